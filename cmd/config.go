@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"gopkg.in/yaml.v3"
+)
+
+// configCommand implements "triksha config show": it prints the effective
+// config after merging every layer, with secrets masked.
+func configCommand(args []string) int {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha config [flags] show")
+		fs.PrintDefaults()
+	}
+	configFlag := fs.String("config", "", "Project config file, YAML or JSON (default ./triksha.yaml if present)")
+	profileFlag := fs.String("profile", "", "Config profile to apply (default $TRIKSHA_PROFILE)")
+	jsonOut := fs.Bool("json", false, "Print the config as JSON instead of YAML")
+	fs.Parse(args)
+	if fs.NArg() != 1 || fs.Arg(0) != "show" {
+		fs.Usage()
+		return 2
+	}
+
+	loader := core.ConfigLoader{ProjectPath: *configFlag, Profile: *profileFlag, Env: os.Environ()}
+	cfg, sources, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	if err := core.ValidateConfig(withPlaceholderTarget(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	data, err := json.MarshalIndent(cfg.Redacted(), "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *jsonOut {
+		fmt.Println(string(data))
+		return 0
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	pruneEmpty(generic)
+	fmt.Printf("# Layers: %s\n", strings.Join(sources, ", "))
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// pruneEmpty drops empty sections such as "cache: {}" from the output.
+func pruneEmpty(m map[string]interface{}) {
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			pruneEmpty(sub)
+			if len(sub) == 0 {
+				delete(m, k)
+			}
+		}
+	}
+}
+
+// withPlaceholderTarget lets a config without targets pass validation,
+// since targets usually come from the command line.
+func withPlaceholderTarget(cfg core.Config) core.Config {
+	if cfg.Target == "" && len(cfg.Targets) == 0 {
+		cfg.Target = "-"
+	}
+	return cfg
+}
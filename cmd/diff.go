@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"github.com/r4j3sh-com/triksha/output"
+)
+
+// diffCommand implements "triksha diff [flags] <old> <new>": it compares
+// the attack surface of two saved scans.
+func diffCommand(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha diff [flags] <old-scan> <new-scan>")
+		fmt.Fprintln(fs.Output(), "Each scan is a scan ID (from the workspace or -state-dir), a scan state file or a JSON report.")
+		fs.PrintDefaults()
+	}
+	stateDir := fs.String("state-dir", core.DefaultStateDir, "Directory for scan state files")
+	dbPath := fs.String("db", core.DefaultDBPath, "Workspace database to look scan IDs up in")
+	wsFlag := fs.String("workspace", "", "Workspace to look scan IDs up in (default the selected one)")
+	jsonOut := fs.String("json", "", "Path to export the diff as JSON")
+	mdOut := fs.String("md", "", "Path to export the diff as Markdown")
+	htmlOut := fs.String("html", "", "Path to export the diff as HTML")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	// Workspace scans take precedence; files and state IDs work without a database
+	load := func(ref string) (core.ScanSnapshot, error) { return core.LoadSnapshot(*stateDir, ref) }
+	if db, ws, err := openWorkspace(*dbPath, *wsFlag); err == nil {
+		load = func(ref string) (core.ScanSnapshot, error) {
+			if snap, err := db.Snapshot(ws, ref); err == nil {
+				return snap, nil
+			}
+			return core.LoadSnapshot(*stateDir, ref)
+		}
+	}
+	old, err := load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	new, err := load(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", fs.Arg(1), err)
+		return 1
+	}
+
+	bus := core.NewBus()
+	bus.Subscribe(core.ConsoleSink(os.Stderr, core.LevelInfo))
+	log := bus.Logger("", "diff")
+	d := core.DiffScans(old, new)
+	if d.TargetsDiffer() {
+		log.Warnf("Scans %s and %s cover different targets", old.ID, new.ID)
+	}
+	d.Publish(log)
+
+	if *jsonOut == "" && *mdOut == "" && *htmlOut == "" {
+		fmt.Print(output.DiffMarkdown(d))
+		return 0
+	}
+	status := 0
+	for _, out := range []struct {
+		path  string
+		write func(core.ScanDiff, string) error
+	}{
+		{*jsonOut, output.WriteDiffJSONReport},
+		{*mdOut, output.WriteDiffMarkdownReport},
+		{*htmlOut, output.WriteDiffHTMLReport},
+	} {
+		if out.path == "" {
+			continue
+		}
+		if err := out.write(d, out.path); err != nil {
+			log.Errorf("Failed to write %s: %v", out.path, err)
+			status = 1
+			continue
+		}
+		log.Infof("Diff written to %s", out.path)
+	}
+	return status
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"github.com/r4j3sh-com/triksha/modules"
+)
+
+// modulesCommand implements "triksha modules [flags] [name...]": without
+// names it lists the catalog, with names it describes those modules.
+func modulesCommand(args []string) int {
+	fs := flag.NewFlagSet("modules", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha modules [flags] [module...]")
+		fs.PrintDefaults()
+	}
+	jsonOut := fs.Bool("json", false, "Print the catalog as JSON")
+	pluginsDir := fs.String("plugins-dir", modules.DefaultPluginDir(), "Directory of external plugin executables")
+	fs.Parse(args)
+
+	bus := core.NewBus()
+	bus.Subscribe(core.ConsoleSink(os.Stderr, core.LevelWarn))
+	engine := newEngine(loadPlugins(*pluginsDir, bus.Logger("", "triksha")))
+
+	catalog := engine.Catalog()
+	if fs.NArg() > 0 {
+		catalog = nil
+		for _, name := range fs.Args() {
+			entry, ok := engine.Describe(name)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Unknown module: %s\n", name)
+				return 1
+			}
+			catalog = append(catalog, entry)
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(catalog); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if fs.NArg() > 0 {
+		for i, entry := range catalog {
+			if i > 0 {
+				fmt.Println()
+			}
+			printModule(entry)
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCATEGORY\tNOISE\tDESCRIPTION")
+	for _, entry := range catalog {
+		desc := entry.Description
+		if entry.Manual {
+			desc += " (manual)"
+		}
+		if len(entry.Missing) > 0 {
+			desc += " (missing: " + strings.Join(entry.Missing, ", ") + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Name, orDash(string(entry.Category)), orDash(string(entry.Noise)), desc)
+	}
+	w.Flush()
+	return 0
+}
+
+// printModule writes the full catalog entry of one module.
+func printModule(entry core.CatalogEntry) {
+	fmt.Printf("%s: %s\n", entry.Name, entry.Description)
+	fmt.Printf("  Category:  %s\n", orDash(string(entry.Category)))
+	fmt.Printf("  Noise:     %s\n", orDash(string(entry.Noise)))
+	fmt.Printf("  Agent:     ")
+	if entry.Manual {
+		fmt.Println("manual only")
+	} else {
+		fmt.Printf("order %d, up to %d run(s)\n", entry.Order, entry.Limit())
+	}
+	if len(entry.Binaries) > 0 {
+		var bins []string
+		for _, bin := range entry.Binaries {
+			if contains(entry.Missing, bin) {
+				bin += " (not found)"
+			}
+			bins = append(bins, bin)
+		}
+		fmt.Printf("  Binaries:  %s\n", strings.Join(bins, ", "))
+	}
+	if len(entry.Consumes) > 0 {
+		fmt.Printf("  Consumes:  %s\n", strings.Join(entry.Consumes, ", "))
+	}
+	if len(entry.Produces) > 0 {
+		fmt.Printf("  Produces:  %s\n", strings.Join(entry.Produces, ", "))
+	}
+	if len(entry.Params) > 0 {
+		fmt.Println("  Params:")
+		for _, spec := range entry.Params {
+			def, _ := json.Marshal(spec.Default)
+			fmt.Printf("    %s (%s, default %s): %s\n", spec.Name, spec.Type, def, spec.Description)
+		}
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"github.com/r4j3sh-com/triksha/modules"
+)
+
+// newRunner sets up the engine, agent, scope and shared clients for a
+// scan with cfg, which must already pass core.ValidateConfig, and the
+// loaded plugins. Checkpoint and Recorder are left for the caller.
+func newRunner(cfg core.Config, graph bool, bus *core.Bus, plugins []*modules.PluginModule, cache *core.Cache) (*core.Runner, error) {
+	log := bus.Logger("", "triksha")
+	defTimeout, moduleTimeouts, _ := cfg.ModuleTimeouts()
+	scope, _ := core.NewScope(cfg.Scope)
+	limits, _ := cfg.Limits()
+	limiter := core.NewLimiter(limits)
+	httpFactory, _ := core.NewHTTPClientFactory(cfg.HTTP, limiter)
+	retry, _ := cfg.Retry.Policies()
+	log.Infof("Rate limits: %s", limits)
+	if cfg.HTTP.Proxy != "" {
+		log.Infof("Routing HTTP traffic through %s", cfg.Redacted().HTTP.Proxy)
+	}
+
+	engine := newEngine(plugins)
+	engine.SetTimeouts(defTimeout, moduleTimeouts)
+	for name, params := range cfg.Params {
+		engine.SetParams(name, params)
+	}
+	for name := range engine.ParamSchemas() {
+		if _, err := engine.ResolveParams(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var moduleNames []string
+	for _, name := range cfg.Modules {
+		if name = strings.TrimSpace(name); name != "" {
+			moduleNames = append(moduleNames, name)
+		}
+	}
+	return &core.Runner{
+		Engine:        engine,
+		NewAgent:      agentFactory(cfg, engine.Catalog(), bus),
+		Modules:       moduleNames,
+		Graph:         graph,
+		Workers:       cfg.Workers,
+		TargetWorkers: cfg.TargetConcurrency,
+		Scope:         scope,
+		Bus:           bus,
+		Limiter:       limiter,
+		HTTP:          httpFactory,
+		Cache:         cache,
+		Retry:         retry,
+		APIKeys:       cfg.ApiKeys,
+		Settings:      cfg.Other,
+		WordlistDir:   cfg.WordlistDir,
+		AllowedParams: cfg.AllowedParams,
+	}, nil
+}
+
+// agentFactory selects the agent for cfg: an LLM agent backed by OpenAI
+// or Ollama when enabled and configured, the simple agent otherwise.
+func agentFactory(cfg core.Config, catalog []core.CatalogEntry, bus *core.Bus) func() core.Agent {
+	log := bus.Logger("", "triksha")
+	newAgent := func() core.Agent { return core.NewAgent(catalog) }
+	if !cfg.LLM.Enabled {
+		log.Infof("Using simple agent (non-AI)")
+		return newAgent
+	}
+	log.Infof("AI agent mode enabled")
+
+	if key := cfg.ApiKeys["openai"]; key != "" {
+		log.Infof("Using OpenAI LLM agent with model: %s", cfg.LLM.OpenAIModel)
+		newAgent = func() core.Agent {
+			client := core.NewOpenAIClient(key, cfg.LLM.OpenAIModel)
+			client.Log = bus.Logger("", "llm")
+			llmAgent := core.NewLLMAgent(client)
+			llmAgent.Catalog = catalog
+			return llmAgent
+		}
+	} else if cfg.LLM.OllamaURL != "" {
+		log.Infof("Using Ollama LLM agent with model: %s", cfg.LLM.OllamaModel)
+		newAgent = func() core.Agent {
+			client := core.NewOllamaClient(cfg.LLM.OllamaURL, cfg.LLM.OllamaModel)
+			client.Log = bus.Logger("", "llm")
+			llmAgent := core.NewLLMAgent(client)
+			llmAgent.Catalog = catalog
+			return llmAgent
+		}
+	} else {
+		log.Warnf("LLM agent requested but no OpenAI key or Ollama URL provided")
+		log.Warnf("Falling back to SimpleAgent")
+	}
+	return newAgent
+}
+
+// openCache opens the lookup cache for mode, at path or the configured
+// location. It returns nil when the cache is off or cannot be opened.
+func openCache(cfg core.Config, mode core.CacheMode, path string, log *core.Logger) *core.Cache {
+	if mode == core.CacheOff {
+		return nil
+	}
+	if path == "" {
+		path = cfg.Cache.Path
+	}
+	if path == "" {
+		path = core.DefaultCachePath
+	}
+	ttls, _ := cfg.Cache.TTLMap()
+	cache, err := core.OpenCache(path, mode, ttls)
+	if err != nil {
+		log.Warnf("Lookup cache disabled: %v", err)
+		return nil
+	}
+	return cache
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"github.com/r4j3sh-com/triksha/modules"
+	"github.com/r4j3sh-com/triksha/server"
+)
+
+// serveCommand implements "triksha serve": it runs scans submitted over
+// the HTTP API until interrupted.
+func serveCommand(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha serve [flags]")
+		fs.PrintDefaults()
+	}
+	configFlag := fs.String("config", "", "Project config file, YAML or JSON (default ./triksha.yaml if present)")
+	profileFlag := fs.String("profile", "", "Config profile applied to every scan (default $TRIKSHA_PROFILE)")
+	listen := fs.String("listen", "", "Address to serve the API on (config server.listen, default "+core.DefaultListen+")")
+	var tokenFlag tokenFlags
+	fs.Var(&tokenFlag, "token", "Accepted API token, in addition to config server.tokens (repeatable)")
+	workers := fs.Int("workers", 0, "Scans run at once (config server.workers, default 2)")
+	queueSize := fs.Int("queue", 0, "Scans that may wait for a worker (config server.queue_size, default 50)")
+	dbPath := fs.String("db", core.DefaultDBPath, "Workspace database to record scans in (empty disables)")
+	wsFlag := fs.String("workspace", "", "Default workspace for scans (default the selected one)")
+	pluginsDir := fs.String("plugins-dir", modules.DefaultPluginDir(), "Directory of external plugin executables")
+	cacheFlag := fs.String("cache", "on", "Lookup cache mode: on, only (no external queries), refresh or off")
+	cachePath := fs.String("cache-path", "", "Lookup cache database (default "+core.DefaultCachePath+")")
+	logLevel := fs.String("log-level", "info", "Console log level: trace, debug, info, warn or error")
+	fs.Parse(args)
+
+	level, err := core.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Log error: %v\n", err)
+		return 1
+	}
+	bus := core.NewBus()
+	bus.Subscribe(core.ConsoleSink(os.Stdout, level))
+	log := bus.Logger("", "triksha")
+
+	loader := core.ConfigLoader{ProjectPath: *configFlag, Profile: *profileFlag, Env: os.Environ()}
+	cfg, sources, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	log.Debugf("Config layers: %s", strings.Join(sources, ", "))
+	if err := core.ValidateConfig(withPlaceholderTarget(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		return 1
+	}
+	tokens := append(append([]string(nil), cfg.Server.Tokens...), tokenFlag...)
+	if len(tokens) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no API tokens; set server.tokens (or TRIKSHA_SERVER__TOKENS) or pass -token")
+		return 1
+	}
+	addr := cfg.Server.Listen
+	if *listen != "" {
+		addr = *listen
+	}
+	if addr == "" {
+		addr = core.DefaultListen
+	}
+
+	cacheMode, err := core.ParseCacheMode(*cacheFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cache error: %v\n", err)
+		return 1
+	}
+	cache := openCache(cfg, cacheMode, *cachePath, log)
+	if cache != nil {
+		defer cache.Close()
+	}
+	var db *core.DB
+	var ws string
+	if *dbPath != "" {
+		if db, ws, err = openWorkspace(*dbPath, *wsFlag); err != nil {
+			log.Errorf("Scans will not be recorded in workspace database %s: %v", *dbPath, err)
+			db = nil
+		} else {
+			log.Infof("Recording scans in %s (default workspace %s)", *dbPath, ws)
+		}
+	}
+	if len(cfg.Notify.Channels) > 0 {
+		notifier, err := core.NewNotifier(cfg.Notify, bus.Logger("", "notify"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+			return 1
+		}
+		bus.Subscribe(notifier.Handle)
+		defer notifier.Close(30 * time.Second)
+	}
+
+	// Plugins are described once here rather than for every request
+	plugins := loadPlugins(*pluginsDir, log)
+	preparer := &scanPreparer{loader: loader, plugins: plugins, cache: cache, configs: map[string]core.Config{"": cfg}}
+	srv := &server.Server{
+		Prepare:   preparer.prepare,
+		Tokens:    tokens,
+		Workers:   cfg.Server.Workers,
+		QueueSize: cfg.Server.QueueSize,
+		DB:        db,
+		Workspace: ws,
+		Catalog:   newEngine(plugins).Catalog(),
+		Bus:       bus,
+	}
+	if *workers > 0 {
+		srv.Workers = *workers
+	}
+	if *queueSize > 0 {
+		srv.QueueSize = *queueSize
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv.Start(ctx)
+	log.Infof("Serving the API on http://%s%s (OpenAPI at %s/openapi.yaml)", addr, server.APIPrefix, server.APIPrefix)
+	if db != nil {
+		log.Infof("Dashboard at http://%s%s", addr, server.DashboardPath)
+	}
+	if err := srv.ListenAndServe(ctx, addr); err != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		return 1
+	}
+	log.Infof("Server stopped")
+	return 0
+}
+
+// scanPreparer sets up submitted scans from what serve loaded at start:
+// the plugins, and each profile's config once it has been read.
+type scanPreparer struct {
+	loader  core.ConfigLoader
+	plugins []*modules.PluginModule
+	cache   *core.Cache
+
+	mu      sync.Mutex
+	configs map[string]core.Config // by requested profile, "" for the server's
+}
+
+// config returns the layered config for profile, reading the layers only
+// the first time it is asked for.
+func (p *scanPreparer) config(profile string) (core.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cfg, ok := p.configs[profile]; ok {
+		return cfg, nil
+	}
+	loader := p.loader
+	loader.Profile = profile
+	cfg, _, err := loader.Load()
+	if err != nil {
+		return core.Config{}, err
+	}
+	p.configs[profile] = cfg
+	return cfg, nil
+}
+
+// prepare builds the config for a submitted scan from the server's
+// layers, the requested profile and the request's overrides.
+func (p *scanPreparer) prepare(req server.ScanRequest, bus *core.Bus) (*server.Prepared, error) {
+	cfg, err := p.config(req.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if req.Target != "" || len(req.Targets) > 0 {
+		cfg.Target, cfg.Targets = req.Target, req.Targets
+	}
+	if len(req.Modules) > 0 {
+		cfg.Modules = req.Modules
+	}
+	switch req.Agent {
+	case "":
+	case "simple":
+		cfg.LLM.Enabled = false
+	case "llm":
+		cfg.LLM.Enabled = true
+	default:
+		return nil, fmt.Errorf("unknown agent %q (want simple or llm)", req.Agent)
+	}
+	if err := core.ValidateConfig(cfg); err != nil {
+		return nil, err
+	}
+	targets, err := cfg.ParseTargets()
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no valid targets")
+	}
+	runner, err := newRunner(cfg, req.Concurrent, bus, p.plugins, p.cache)
+	if err != nil {
+		return nil, err
+	}
+	// Requests may not point modules at files unless the operator allows it
+	for module, params := range req.Params {
+		params, dropped := runner.Engine.UntrustedParams(module, params, cfg.AllowedParams)
+		if len(dropped) > 0 {
+			bus.Logger("", "triksha").Warnf("Dropped path parameter(s) %s of module %s from a scan request; allow them with allowed_params", strings.Join(dropped, ", "), module)
+		}
+		runner.Engine.SetParams(module, params)
+		if _, err := runner.Engine.ResolveParams(module, nil); err != nil {
+			return nil, err
+		}
+		// Copy rather than change the cached config's maps
+		merged := make(map[string]interface{}, len(cfg.Params[module])+len(params))
+		for name, value := range cfg.Params[module] {
+			merged[name] = value
+		}
+		for name, value := range params {
+			merged[name] = value
+		}
+		all := make(map[string]map[string]interface{}, len(cfg.Params)+1)
+		for name, values := range cfg.Params {
+			all[name] = values
+		}
+		all[module] = merged
+		cfg.Params = all
+	}
+	return &server.Prepared{Config: cfg, Targets: targets, Runner: runner}, nil
+}
+
+// tokenFlags collects repeated -token flags.
+type tokenFlags []string
+
+func (t *tokenFlags) String() string { return strings.Join(*t, ",") }
+
+func (t *tokenFlags) Set(v string) error {
+	*t = append(*t, v)
+	return nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
-	"sync"
+	"syscall"
+	"time"
 
 	"github.com/r4j3sh-com/triksha/core"
 	"github.com/r4j3sh-com/triksha/modules"
@@ -13,198 +16,522 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "modules":
+			os.Exit(modulesCommand(os.Args[2:]))
+		case "diff":
+			os.Exit(diffCommand(os.Args[2:]))
+		case "workspace":
+			os.Exit(workspaceCommand(os.Args[2:]))
+		case "scans":
+			os.Exit(scansCommand(os.Args[2:]))
+		case "assets":
+			os.Exit(assetsCommand(os.Args[2:]))
+		case "config":
+			os.Exit(configCommand(os.Args[2:]))
+		case "serve":
+			os.Exit(serveCommand(os.Args[2:]))
+		case "watch":
+			os.Exit(watchCommand(os.Args[2:]))
+		}
+	}
+
 	// CLI flags
-	targetFlag := flag.String("target", "", "Target domain or IP to scan (required)")
-	configFlag := flag.String("config", "", "Path to JSON config file (optional)")
+	targetFlag := flag.String("target", "", "Target domain, host:port, URL, IP or CIDR to scan")
+	targetsFile := flag.String("targets", "", "File with one target per line, or - for stdin")
+	targetWorkers := flag.Int("target-concurrency", 0, "Maximum targets scanned at once (default 1)")
+	configFlag := flag.String("config", "", "Project config file, YAML or JSON (default ./triksha.yaml if present)")
+	profileFlag := flag.String("profile", "", "Config profile to apply (default $TRIKSHA_PROFILE)")
 	modulesFlag := flag.String("modules", "", "Comma-separated list of modules to run (optional)")
 	jsonOut := flag.String("json", "", "Path to export JSON report")
 	mdOut := flag.String("md", "", "Path to export Markdown report")
 	htmlOut := flag.String("html", "", "Path to export HTML report")
-	openaiKey := flag.String("openai-key", "", "OpenAI API key")
-	openaiModel := flag.String("openai-model", "gpt-3.5-turbo", "OpenAI model")
-	ollamaURL := flag.String("ollama-url", "", "Ollama base URL (e.g. http://localhost:11434)")
-	ollamaModel := flag.String("ollama-model", "gemma:2b", "Ollama model name")
-	useLLMAgent := flag.Bool("ai", false, "Use LLM agent for recon orchestration")
-	concurrent := flag.Bool("concurrent", false, "Enable concurrent execution of independent modules")
+	openaiKey := flag.String("openai-key", "", "OpenAI API key (config api_keys.openai)")
+	openaiModel := flag.String("openai-model", "", "OpenAI model (config llm.openai_model)")
+	ollamaURL := flag.String("ollama-url", "", "Ollama base URL, e.g. http://localhost:11434 (config llm.ollama_url)")
+	ollamaModel := flag.String("ollama-model", "", "Ollama model name (config llm.ollama_model)")
+	useLLMAgent := flag.Bool("ai", false, "Use LLM agent for recon orchestration (config llm.enabled)")
+	concurrent := flag.Bool("concurrent", false, "Run modules as a dependency graph, in parallel where possible")
+	workersFlag := flag.Int("workers", 0, "Maximum modules running at once in concurrent mode (default 4)")
+	var paramFlag paramFlags
+	flag.Var(&paramFlag, "param", "Module parameter as module.name=value (repeatable)")
+	timeoutFlag := flag.Duration("timeout", 0, "Default per-module timeout (e.g. 10m, 0 for no limit), overrides config")
+	resumeFlag := flag.String("resume", "", "Resume an interrupted scan by its scan ID")
+	stateDir := flag.String("state-dir", core.DefaultStateDir, "Directory for scan state files")
+	dbPath := flag.String("db", core.DefaultDBPath, "Workspace database to record the scan in (empty disables)")
+	workspaceFlag := flag.String("workspace", "", "Workspace to record the scan in (default the selected one)")
+	logLevel := flag.String("log-level", "info", "Console log level: trace, debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Console log format: text or json")
+	logFile := flag.String("log-file", "", "Also append JSON-lines events to this file (at -log-level)")
+	quiet := flag.Bool("quiet", false, "Only show warnings and errors (same as -log-level warn)")
+	pluginsDir := flag.String("plugins-dir", modules.DefaultPluginDir(), "Directory of external plugin executables")
+	proxyFlag := flag.String("proxy", "", "Upstream HTTP or SOCKS5 proxy for module traffic, e.g. http://127.0.0.1:8080")
+	var headerFlag headerFlags
+	flag.Var(&headerFlag, "header", "Extra HTTP header as \"Name: value\" (repeatable)")
+	userAgent := flag.String("user-agent", "", "HTTP User-Agent (default "+core.DefaultUserAgent+")")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
+	cacheFlag := flag.String("cache", "on", "Lookup cache mode: on, only (no external queries), refresh or off")
+	cachePath := flag.String("cache-path", "", "Lookup cache database (default "+core.DefaultCachePath+")")
+	rateProfile := flag.String("rate-profile", "", "Rate limit profile: "+strings.Join(core.RateProfileNames(), ", ")+" (default "+core.DefaultRateProfile+")")
+	monitorFlag := flag.Bool("monitor", false, "Keep running and rescan on the monitor schedules, reporting changes")
+	var scheduleFlag scheduleFlags
+	flag.Var(&scheduleFlag, "schedule", "Monitor schedule as module=spec, e.g. passive='@every 6h' or agent=@daily (repeatable)")
+	tuiFlag := flag.Bool("tui", false, "Interactive terminal UI: pause, skip or rerun modules and approve agent decisions (held for approval by default with -ai)")
 	flag.Parse()
 
-	var cfg core.Config
+	// Event bus and log sinks
+	level, err := core.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Log error: %v\n", err)
+		os.Exit(1)
+	}
+	if *quiet && level < core.LevelWarn {
+		level = core.LevelWarn
+	}
+	bus := core.NewBus()
+	var console func(core.Event)
+	switch *logFormat {
+	case "text":
+		console = core.ConsoleSink(os.Stdout, level)
+	case "json":
+		console = core.JSONSink(os.Stdout, level)
+	default:
+		fmt.Fprintf(os.Stderr, "Log error: unknown log format %q (want text or json)\n", *logFormat)
+		os.Exit(1)
+	}
+	hideConsole := bus.Subscribe(console)
+	if *logFile != "" {
+		sink, f, err := core.FileSink(*logFile, level)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Log error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		bus.Subscribe(sink)
+	}
+	log := bus.Logger("", "triksha")
+
+	// Layered config: defaults, global file, project file, profile, env
+	loader := core.ConfigLoader{ProjectPath: *configFlag, Profile: *profileFlag, Env: os.Environ()}
+	cfg, sources, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	log.Debugf("Config layers: %s", strings.Join(sources, ", "))
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
 
-	// Prefer config file if provided
-	if *configFlag != "" {
-		var err error
-		cfg, err = core.LoadConfig(*configFlag)
+	if *tuiFlag && *monitorFlag {
+		fmt.Fprintln(os.Stderr, "Error: -tui cannot be combined with -monitor")
+		os.Exit(1)
+	}
+
+	// A resumed scan reuses its saved config, targets and mode; secrets
+	// (API keys, notification channels, HTTP credentials) are never saved,
+	// so they and the LLM settings still come from the layers
+	var checkpoint *core.Checkpoint
+	if *resumeFlag != "" && *monitorFlag {
+		fmt.Fprintln(os.Stderr, "Error: -resume cannot be combined with -monitor")
+		os.Exit(1)
+	}
+	if *resumeFlag != "" {
+		checkpoint, err = core.LoadCheckpoint(*stateDir, *resumeFlag)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Resume error: %v\n", err)
 			os.Exit(1)
 		}
-	} else {
-		// CLI-based config
-		cfg = core.Config{
-			Target:  *targetFlag,
-			Modules: nil,
+		state := checkpoint.State()
+		llm, notify := cfg.LLM, cfg.Notify
+		cfg = state.Config.WithSecretsFrom(cfg)
+		cfg.LLM, cfg.Notify = llm, notify
+		*concurrent = state.Graph
+		log.Infof("Resuming scan %s", checkpoint.ID())
+	}
+
+	// CLI flags override every layer
+	if checkpoint == nil {
+		if set["target"] {
+			cfg.Target = *targetFlag
 		}
-		if *modulesFlag != "" {
+		if set["modules"] {
 			cfg.Modules = strings.Split(*modulesFlag, ",")
 		}
+		if set["rate-profile"] {
+			cfg.RateProfile = *rateProfile
+		}
+		if set["proxy"] {
+			cfg.HTTP.Proxy = *proxyFlag
+		}
+		if set["user-agent"] {
+			cfg.HTTP.UserAgent = *userAgent
+		}
+		if *insecure {
+			cfg.HTTP.Insecure = true
+		}
+		for _, raw := range headerFlag {
+			name, value, ok := strings.Cut(raw, ":")
+			if !ok || strings.TrimSpace(name) == "" {
+				fmt.Fprintf(os.Stderr, "Header error: want \"Name: value\", got %q\n", raw)
+				os.Exit(1)
+			}
+			if cfg.HTTP.Headers == nil {
+				cfg.HTTP.Headers = map[string]string{}
+			}
+			cfg.HTTP.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	if set["openai-key"] {
+		if cfg.ApiKeys == nil {
+			cfg.ApiKeys = map[string]string{}
+		}
+		cfg.ApiKeys["openai"] = *openaiKey
+	}
+	if set["openai-model"] {
+		cfg.LLM.OpenAIModel = *openaiModel
+	}
+	if set["ollama-url"] {
+		cfg.LLM.OllamaURL = *ollamaURL
+	}
+	if set["ollama-model"] {
+		cfg.LLM.OllamaModel = *ollamaModel
+	}
+	if *useLLMAgent {
+		cfg.LLM.Enabled = true
+	}
+	if set["timeout"] {
+		cfg.DefaultTimeout = timeoutFlag.String()
+	}
+	if *workersFlag > 0 {
+		cfg.Workers = *workersFlag
+	}
+	if *targetWorkers > 0 {
+		cfg.TargetConcurrency = *targetWorkers
+	}
+	for _, raw := range paramFlag {
+		module, name, value, err := core.ParseParamFlag(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Param error: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg.Params == nil {
+			cfg.Params = map[string]map[string]interface{}{}
+		}
+		if cfg.Params[module] == nil {
+			cfg.Params[module] = map[string]interface{}{}
+		}
+		cfg.Params[module][name] = value
+	}
+	for _, raw := range scheduleFlag {
+		name, spec, ok := strings.Cut(raw, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			fmt.Fprintf(os.Stderr, "Schedule error: want module=spec, got %q\n", raw)
+			os.Exit(1)
+		}
+		if cfg.Monitor.Schedules == nil {
+			cfg.Monitor.Schedules = map[string]string{}
+		}
+		cfg.Monitor.Schedules[strings.TrimSpace(name)] = strings.TrimSpace(spec)
 	}
 
-	// Validate config
-	if err := core.ValidateConfig(cfg); err != nil {
+	// Validate config; a -targets list can stand in for -target
+	check := cfg
+	if check.Target == "" && (*targetsFile != "" || checkpoint != nil) {
+		check.Target = "-"
+	}
+	if err := core.ValidateConfig(check); err != nil {
 		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	engine := core.NewEngine()
-	engine.RegisterModule(modules.Module) // dummy
-	engine.RegisterModule(modules.Passive)
-	engine.RegisterModule(modules.Subdomain)
-	engine.RegisterModule(modules.Portscan)
-	engine.RegisterModule(modules.Webenum)
-	engine.RegisterModule(modules.Vulnscan)
-	engine.RegisterModule(modules.Report)
+	cacheMode, err := core.ParseCacheMode(*cacheFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cache error: %v\n", err)
+		os.Exit(1)
+	}
+	cache := openCache(cfg, cacheMode, *cachePath, log)
+	if cache != nil {
+		defer cache.Close()
+	}
 
-	ctx := &core.Context{
-		Target: cfg.Target,
-		Store:  make(map[string]interface{}),
+	// Cancel every running module on Ctrl-C or SIGTERM
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner, err := newRunner(cfg, *concurrent, bus, loadPlugins(*pluginsDir, log), cache)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Param error: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Agent selection and initialization
-	var agent core.Agent
-	if *useLLMAgent {
-		fmt.Println("[+] AI agent mode enabled")
-
-		if *openaiKey != "" {
-			fmt.Printf("[+] Using OpenAI LLM agent with model: %s\n", *openaiModel)
-			llm := core.NewOpenAIClient(*openaiKey, *openaiModel)
-			agent = core.NewLLMAgent(llm)
-		} else if *ollamaURL != "" {
-			fmt.Printf("[+] Using Ollama LLM agent with model: %s\n", *ollamaModel)
-			llm := core.NewOllamaClient(*ollamaURL, *ollamaModel)
-			agent = core.NewLLMAgent(llm)
-		} else {
-			fmt.Println("[!] Warning: LLM agent requested but no OpenAI key or Ollama URL provided")
-			fmt.Println("[!] Falling back to SimpleAgent")
-			agent = core.NewAgent()
+	var targets []core.Target
+	if checkpoint != nil {
+		for _, raw := range checkpoint.State().Targets {
+			parsed, err := core.ParseTarget(raw)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Resume error: %v\n", err)
+				os.Exit(1)
+			}
+			targets = append(targets, parsed...)
 		}
-	} else {
-		fmt.Println("[+] Using simple agent (non-AI)")
-		agent = core.NewAgent()
+	} else if targets, err = cfg.ParseTargets(); err != nil {
+		fmt.Fprintf(os.Stderr, "Target error: %v\n", err)
+		os.Exit(1)
+	}
+	if *targetsFile != "" && checkpoint == nil {
+		listed, err := readTargetsFile(*targetsFile, log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Target error: %v\n", err)
+			os.Exit(1)
+		}
+		targets = mergeTargets(targets, listed)
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "Target error: no valid targets")
+		os.Exit(1)
 	}
+	log.Infof("Loaded %d target(s)", len(targets))
 
-	history := []core.Result{}
+	// Push finished scans, serious findings and monitor changes to the
+	// configured webhooks, chats and mailboxes
+	if len(cfg.Notify.Channels) > 0 {
+		notifier, err := core.NewNotifier(cfg.Notify, bus.Logger("", "notify"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+			os.Exit(1)
+		}
+		bus.Subscribe(notifier.Handle)
+		defer notifier.Close(30 * time.Second)
+		log.Infof("Sending notifications to %d channel(s)", len(cfg.Notify.Channels))
+	}
 
-	if *concurrent {
-		fmt.Println("[*] Running independent modules concurrently...")
-		var wg sync.WaitGroup
-		var mu sync.Mutex // To safely append to history
+	// Persist progress after every module so an interrupted scan can resume;
+	// a monitor stores every run in the workspace database instead
+	if checkpoint == nil && !*monitorFlag {
+		checkpoint, err = core.NewCheckpoint(*stateDir, cfg.WithoutSecrets(), targets, *concurrent)
+		if err != nil {
+			log.Warnf("Scan state disabled: %v", err)
+		} else {
+			log.Infof("Scan ID: %s (resume with -resume %s)", checkpoint.ID(), checkpoint.ID())
+		}
+	}
 
-		independentModules := []string{"passive", "subdomain", "portscan"}
-		for _, modName := range independentModules {
-			wg.Add(1)
-			go func(name string) {
-				defer wg.Done()
-				fmt.Printf("[*] Starting concurrent module: %s\n", name)
-				result, err := engine.RunModule(name, ctx.Target, ctx)
+	// Record results and assets in the workspace database as modules finish
+	var recorder *core.ScanRecorder
+	var db *core.DB
+	var ws string
+	if *dbPath != "" {
+		db, ws, err = openWorkspace(*dbPath, *workspaceFlag)
+		if err == nil {
+			if !*monitorFlag {
+				var id string
+				if checkpoint != nil {
+					id = checkpoint.ID()
+				}
+				recorder, err = db.StartScan(ws, id, targets, cfg.Modules)
+			}
+		}
+		if err != nil {
+			log.Errorf("Scan will not be recorded in workspace database %s: %v", *dbPath, err)
+			db = nil
+		} else if recorder != nil {
+			log.Infof("Recording scan %s in workspace %s", recorder.ID(), recorder.Workspace())
+		}
+	}
 
-				mu.Lock()
-				defer mu.Unlock()
+	runner.Checkpoint = checkpoint
+	runner.Recorder = recorder
 
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "[!] Error in module %s: %v\n", name, err)
-				} else {
-					fmt.Printf("[+] Concurrent module %s completed successfully\n", name)
-					history = append(history, result)
-				}
-			}(modName)
+	if *monitorFlag {
+		if *jsonOut != "" || *mdOut != "" || *htmlOut != "" {
+			log.Warnf("Report flags are ignored in monitor mode; use the scans and diff commands on the workspace")
+		}
+		monitor := &core.Monitor{
+			Runner:      runner,
+			Schedules:   cfg.Monitor.Schedules,
+			SkipInitial: cfg.Monitor.SkipInitial,
+			DB:          db,
+			Workspace:   ws,
+		}
+		if db != nil {
+			log.Infof("Recording monitor runs in workspace %s", ws)
+		}
+		if err := monitor.Run(runCtx, targets); err != nil {
+			fmt.Fprintf(os.Stderr, "Monitor error: %v\n", err)
+			os.Exit(1)
 		}
-		wg.Wait()
-		fmt.Println("[*] Concurrent modules finished.")
+		log.Infof("Monitor stopped")
+		return
+	}
 
-		// Now run dependent modules in order
-		fmt.Println("[*] Running dependent modules serially...")
-		dependentModules := []string{"webenum", "vulnscan", "report"}
-		for _, modName := range dependentModules {
-			fmt.Printf("[*] Running module: %s\n", modName)
-			result, err := engine.RunModule(modName, ctx.Target, ctx)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error in module %s: %v\n", modName, err)
-				continue
-			}
-			fmt.Printf("[+] Module %s completed successfully\n", modName)
-			history = append(history, result)
-		}
-		fmt.Println("[+] All modules completed.")
-	} else if *modulesFlag != "" {
-		// Run specific modules serially
-		requestedModules := strings.Split(*modulesFlag, ",")
-		fmt.Printf("[*] Running specific modules: %s\n", strings.Join(requestedModules, ", "))
-
-		for _, modName := range requestedModules {
-			modName = strings.TrimSpace(modName)
-			fmt.Printf("[*] Running module: %s\n", modName)
-			result, err := engine.RunModule(modName, ctx.Target, ctx)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error in module %s: %v\n", modName, err)
-				continue
-			}
-			fmt.Printf("[+] Module %s completed successfully\n", modName)
-			history = append(history, result)
+	var scans []core.TargetScan
+	if *tuiFlag {
+		// The UI takes over the terminal; the console log comes back for
+		// the summary and reports
+		hideConsole()
+		scans, err = runTUI(runCtx, stop, runner, targets, level, scanMode(cfg, runner), cfg.LLM.Enabled)
+		bus.Subscribe(console)
+		if err != nil {
+			log.Errorf("Terminal UI failed: %v", err)
 		}
 	} else {
-		// AI agent-driven workflow
-		for {
-			fmt.Println("\n[*] Asking agent for next action...")
-			action, err := agent.DecideNextAction(ctx, history)
-			if err != nil {
-				if strings.Contains(err.Error(), "all modules completed") {
-					fmt.Println("[+] Recon complete: " + err.Error())
-				} else {
-					fmt.Fprintf(os.Stderr, "[!] Agent error: %v\n", err)
-				}
-				break
-			}
-
-			fmt.Printf("[+] Agent decision: Run module '%s'\n", action.ModuleName)
-			fmt.Printf("[+] Reason: %s\n", action.Reason)
-
-			result, err := engine.RunModule(action.ModuleName, ctx.Target, ctx)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error in module %s: %v\n", action.ModuleName, err)
-				// Ask agent how to handle error
-				recoveryAction, _ := agent.RecoverFromError(ctx, history, err)
-				fmt.Printf("[+] Agent recovery suggestion: %s\n", recoveryAction.Reason)
-				continue
+		scans = runner.Run(runCtx, targets)
+	}
+	failedModules := 0
+	for _, scan := range scans {
+		for name, err := range scan.Errors {
+			if name == "" {
+				log.Errorf("[%s] Scan error: %v", scan.Target, err)
 			}
+			failedModules++
+		}
+	}
+	if failedModules > 0 {
+		log.Warnf("%d module run(s) failed or were skipped", failedModules)
+	}
+	history := core.AllResults(scans)
+	assets := core.AllAssets(scans)
+	if violations := runner.Scope.Violations(); len(violations) > 0 {
+		log.Warnf("%d out-of-scope action(s) were blocked", len(violations))
+	}
 
-			fmt.Printf("[+] Module %s completed successfully\n", action.ModuleName)
-			history = append(history, result)
+	status := core.ScanFinished
+	if runCtx.Err() != nil {
+		status = core.ScanInterrupted
+		log.Warnf("Scan interrupted, exporting partial results")
+		if checkpoint != nil {
+			log.Warnf("Continue later with -resume %s", checkpoint.ID())
 		}
 	}
+	if err := recorder.Finish(status); err != nil {
+		log.Errorf("Failed to record scan in workspace: %v", err)
+	}
 
 	// Export results if requested at the end of the scan
 	if *jsonOut != "" {
-		if err := output.WriteJSONReport(history, *jsonOut); err != nil {
-			fmt.Fprintf(os.Stderr, "[!] Failed to write JSON: %v\n", err)
+		if err := output.WriteJSONReport(history, assets, *jsonOut); err != nil {
+			log.Errorf("Failed to write JSON: %v", err)
 		} else {
-			fmt.Printf("[+] JSON report exported to %s\n", *jsonOut)
+			log.Infof("JSON report exported to %s", *jsonOut)
 		}
 	}
 	if *mdOut != "" {
-		if err := output.WriteMarkdownReport(cfg, history, *mdOut); err != nil {
-			fmt.Fprintf(os.Stderr, "[!] Failed to write Markdown: %v\n", err)
+		if err := output.WriteMarkdownReport(cfg, history, assets, *mdOut); err != nil {
+			log.Errorf("Failed to write Markdown: %v", err)
 		} else {
-			fmt.Printf("[+] Markdown report exported to %s\n", *mdOut)
+			log.Infof("Markdown report exported to %s", *mdOut)
 		}
 	}
 	if *htmlOut != "" {
-		if err := output.WriteHTMLReport(cfg, history, *htmlOut); err != nil {
-			fmt.Fprintf(os.Stderr, "[!] Failed to write HTML: %v\n", err)
+		if err := output.WriteHTMLReport(cfg, history, assets, *htmlOut); err != nil {
+			log.Errorf("Failed to write HTML: %v", err)
 		} else {
-			fmt.Printf("[+] HTML report exported to %s\n", *htmlOut)
+			log.Infof("HTML report exported to %s", *htmlOut)
+		}
+	}
+}
+
+// newEngine registers the built-in modules and plugins, as returned by
+// loadPlugins.
+func newEngine(plugins []*modules.PluginModule) *core.Engine {
+	engine := core.NewEngine()
+	engine.RegisterModule(modules.Module) // dummy
+	engine.RegisterModule(modules.Passive)
+	engine.RegisterModule(modules.Subdomain)
+	engine.RegisterModule(modules.Portscan)
+	engine.RegisterModule(modules.Webenum)
+	engine.RegisterModule(modules.Vulnscan)
+	engine.RegisterModule(modules.Report)
+	for _, p := range plugins {
+		engine.RegisterModule(p)
+	}
+	return engine
+}
+
+// loadPlugins loads the plugins found in pluginsDir, which runs each one
+// to describe itself. Plugins never replace a built-in.
+func loadPlugins(pluginsDir string, log *core.Logger) []*modules.PluginModule {
+	builtins := newEngine(nil)
+	plugins, pluginErrs := modules.LoadPlugins(pluginsDir)
+	for _, err := range pluginErrs {
+		log.Warnf("Skipping plugin: %v", err)
+	}
+	var loaded []*modules.PluginModule
+	for _, p := range plugins {
+		if _, exists := builtins.Module(p.Name()); exists {
+			log.Warnf("Skipping plugin %s: a module named %q is already registered", p.Path, p.Name())
+			continue
+		}
+		loaded = append(loaded, p)
+		log.Infof("Loaded plugin %s from %s", p.Name(), p.Path)
+	}
+	return loaded
+}
+
+// paramFlags collects repeated -param flags.
+type paramFlags []string
+
+func (p *paramFlags) String() string { return strings.Join(*p, ",") }
+
+func (p *paramFlags) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+// headerFlags collects repeated -header flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(v string) error {
+	*h = append(*h, v)
+	return nil
+}
+
+// scheduleFlags collects repeated -schedule flags.
+type scheduleFlags []string
+
+func (s *scheduleFlags) String() string { return strings.Join(*s, ", ") }
+
+func (s *scheduleFlags) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// readTargetsFile loads targets from a file, or from stdin for "-".
+// Invalid lines are reported and skipped.
+func readTargetsFile(path string, log *core.Logger) ([]core.Target, error) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	targets, errs := core.ReadTargets(in)
+	for _, err := range errs {
+		log.Warnf("Skipping target: %v", err)
+	}
+	return targets, nil
+}
+
+// mergeTargets appends extra targets, dropping duplicates.
+func mergeTargets(targets, extra []core.Target) []core.Target {
+	seen := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		seen[t.String()] = true
+	}
+	for _, t := range extra {
+		if !seen[t.String()] {
+			seen[t.String()] = true
+			targets = append(targets, t)
 		}
 	}
+	return targets
 }
 
 func contains(list []string, s string) bool {
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+// runTUI scans targets behind an interactive terminal UI and returns the
+// outcomes once the operator leaves it. cancel stops the scan; approval
+// holds agent decisions for the operator from the start.
+func runTUI(ctx context.Context, cancel func(), runner *core.Runner, targets []core.Target, level core.Level, mode string, approval bool) ([]core.TargetScan, error) {
+	feed := &tuiFeed{}
+	unsubscribe := runner.Bus.Subscribe(feed.add)
+	defer unsubscribe()
+
+	// Graph mode runs modules in parallel and has no place to intervene
+	agent := !runner.Graph && len(runner.Modules) == 0
+	if !runner.Graph {
+		runner.Control = core.NewControl()
+		runner.Control.SetApproval(approval && agent)
+	}
+
+	model := &tuiModel{
+		control:  runner.Control,
+		feed:     feed,
+		cancel:   cancel,
+		level:    level,
+		mode:     mode,
+		agent:    agent,
+		targets:  len(targets),
+		started:  time.Now(),
+		rows:     make(map[string]*tuiRow),
+		kinds:    make(map[string]int),
+		findings: make(map[string]core.Finding),
+	}
+	program := tea.NewProgram(model, tea.WithAltScreen())
+
+	var scans []core.TargetScan
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scans = runner.Run(ctx, targets)
+		program.Send(tuiDone{})
+	}()
+	_, err := program.Run()
+	if err != nil {
+		cancel()
+	}
+	<-done
+	return scans, err
+}
+
+// scanMode describes how runner picks modules, for the UI header.
+func scanMode(cfg core.Config, runner *core.Runner) string {
+	switch {
+	case runner.Graph:
+		return "dependency graph"
+	case len(runner.Modules) > 0:
+		return "modules: " + strings.Join(runner.Modules, ", ")
+	case cfg.LLM.Enabled:
+		return "LLM agent"
+	}
+	return "agent"
+}
+
+// tuiFeed buffers bus events until the UI picks them up, so a slow
+// terminal never holds up the scan.
+type tuiFeed struct {
+	mu     sync.Mutex
+	events []core.Event
+}
+
+func (f *tuiFeed) add(e core.Event) {
+	f.mu.Lock()
+	f.events = append(f.events, e)
+	f.mu.Unlock()
+}
+
+func (f *tuiFeed) drain() []core.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := f.events
+	f.events = nil
+	return events
+}
+
+type (
+	tuiTick struct{}
+	tuiDone struct{}
+)
+
+const (
+	tuiLogLines    = 500
+	tuiRecentAsset = 200
+	tuiRefresh     = 200 * time.Millisecond
+)
+
+// tuiRow is one module on one target in the modules pane.
+type tuiRow struct {
+	target, module string
+	state          string // decided, running, done, failed, skipped
+	started        time.Time
+	duration       string
+	err            string
+	runs           int
+	skipping       bool
+}
+
+type tuiModel struct {
+	control *core.Control
+	feed    *tuiFeed
+	cancel  func()
+	level   core.Level
+	mode    string
+	agent   bool
+	targets int
+	started time.Time
+
+	width, height int
+	order         []*tuiRow
+	rows          map[string]*tuiRow // by target and module
+	selected      int
+	log           []core.Event
+	kinds         map[string]int
+	assets        []string // newest last
+	assetTotal    int
+	findings      map[string]core.Finding // by ID
+	ranked        []core.Finding
+	pending       []core.PendingDecision
+	paused        bool
+	status        string
+	done          bool
+	stopping      bool
+	finished      time.Time
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return tick()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(tuiRefresh, func(time.Time) tea.Msg { return tuiTick{} })
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tuiTick:
+		m.refresh()
+		return m, tick()
+	case tuiDone:
+		m.refresh()
+		m.done, m.finished = true, time.Now()
+		m.pending = nil
+		if m.stopping {
+			return m, tea.Quit
+		}
+		m.status = "Scan finished. Press q to leave and write the reports."
+	case tea.KeyMsg:
+		return m, m.key(msg.String())
+	}
+	return m, nil
+}
+
+// refresh applies the events and control state gathered since the last tick.
+func (m *tuiModel) refresh() {
+	for _, e := range m.feed.drain() {
+		m.apply(e)
+	}
+	if m.control != nil && !m.done {
+		m.pending = m.control.Pending()
+		m.paused = m.control.Paused()
+	}
+}
+
+func (m *tuiModel) apply(e core.Event) {
+	switch e.Type {
+	case core.EventAgentDecision:
+		if name, _ := e.Fields["action"].(string); name != "" {
+			if row := m.row(e.Target, name); row.state == "" {
+				row.state = "decided"
+			}
+		}
+	case core.EventModuleStarted:
+		row := m.row(e.Target, e.Module)
+		row.state, row.started, row.err, row.duration = "running", e.Time, "", ""
+		row.runs++
+	case core.EventModuleFinished:
+		row := m.row(e.Target, e.Module)
+		row.duration, _ = e.Fields["duration"].(string)
+		switch {
+		case e.Error == "":
+			row.state = "done"
+		case row.skipping:
+			row.state = "skipped"
+		default:
+			row.state, row.err = "failed", e.Error
+		}
+		row.skipping = false
+	case core.EventAssetDiscovered:
+		kind, _ := e.Fields["kind"].(string)
+		value, _ := e.Fields["value"].(string)
+		m.kinds[kind]++
+		m.assetTotal++
+		m.assets = appendCapped(m.assets, kind+"  "+value, tuiRecentAsset)
+	case core.EventFindingRaised:
+		if e.Finding != nil {
+			m.findings[e.Finding.ID] = *e.Finding
+			m.ranked = m.ranked[:0]
+			for _, f := range m.findings {
+				m.ranked = append(m.ranked, f)
+			}
+			core.SortFindings(m.ranked)
+		}
+	}
+	if e.Level >= m.level {
+		m.log = appendCapped(m.log, e, tuiLogLines)
+	}
+}
+
+func (m *tuiModel) row(target, module string) *tuiRow {
+	key := target + "\x00" + module
+	row, ok := m.rows[key]
+	if !ok {
+		row = &tuiRow{target: target, module: module}
+		m.rows[key] = row
+		m.order = append(m.order, row)
+	}
+	return row
+}
+
+func appendCapped[T any](list []T, v T, max int) []T {
+	list = append(list, v)
+	if len(list) > max {
+		list = list[len(list)-max:]
+	}
+	return list
+}
+
+// key handles a key press and returns the command to run, if any.
+func (m *tuiModel) key(k string) tea.Cmd {
+	m.status = ""
+	switch k {
+	case "q", "ctrl+c":
+		if m.done || m.stopping {
+			return tea.Quit
+		}
+		m.stopping = true
+		m.status = "Stopping the scan... (q again to leave now)"
+		m.cancel()
+		return nil
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+		}
+		return nil
+	case "down", "j":
+		if m.selected < len(m.order)-1 {
+			m.selected++
+		}
+		return nil
+	}
+	if m.done || m.stopping {
+		return nil
+	}
+	if m.control == nil {
+		switch k {
+		case "p", " ", "s", "r", "y", "enter", "n", "a":
+			m.status = "Pause, skip, rerun and approval are not available in graph mode"
+		}
+		return nil
+	}
+
+	var selected *tuiRow
+	if m.selected < len(m.order) {
+		selected = m.order[m.selected]
+	}
+	switch k {
+	case "p", " ":
+		if m.control.Paused() {
+			m.control.Resume()
+			m.status = "Resumed"
+		} else {
+			m.control.Pause()
+			m.status = "Paused: running modules finish, no new ones start"
+		}
+		m.paused = m.control.Paused()
+	case "s":
+		switch {
+		case selected == nil:
+			m.status = "No module selected"
+		case m.control.Skip(selected.target, selected.module) > 0:
+			selected.skipping = true
+			m.status = fmt.Sprintf("Skipping %s on %s", selected.module, selected.target)
+		default:
+			m.status = fmt.Sprintf("%s is not running on %s", selected.module, selected.target)
+		}
+	case "r":
+		if selected == nil {
+			m.status = "No module selected"
+			break
+		}
+		m.control.Rerun(selected.target, selected.module)
+		m.status = fmt.Sprintf("%s will run again on %s next", selected.module, selected.target)
+	case "y", "enter", "n":
+		approve := k != "n"
+		if len(m.pending) == 0 || !m.control.Decide(m.pending[0].Target, approve) {
+			m.status = "No decision is waiting for approval"
+			break
+		}
+		verb := "Approved"
+		if !approve {
+			verb = "Rejected"
+		}
+		m.status = fmt.Sprintf("%s %s on %s", verb, m.pending[0].Action.ModuleName, m.pending[0].Target)
+		m.pending = m.control.Pending()
+	case "a":
+		if !m.agent {
+			m.status = "Approval only applies to agent decisions"
+			break
+		}
+		on := !m.control.Approval()
+		m.control.SetApproval(on)
+		m.pending = m.control.Pending()
+		if on {
+			m.status = "Agent decisions now wait for approval"
+		} else {
+			m.status = "Agent decisions run without approval"
+		}
+	}
+	return nil
+}
+
+var (
+	tuiTitle    = lipgloss.NewStyle().Bold(true)
+	tuiMuted    = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	tuiSelected = lipgloss.NewStyle().Reverse(true)
+	tuiPrompt   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("0")).Background(lipgloss.Color("214"))
+	tuiBorder   = lipgloss.Color("240")
+
+	tuiStates = map[string]lipgloss.Style{
+		"decided": tuiMuted,
+		"running": lipgloss.NewStyle().Foreground(lipgloss.Color("39")),
+		"done":    lipgloss.NewStyle().Foreground(lipgloss.Color("35")),
+		"failed":  lipgloss.NewStyle().Foreground(lipgloss.Color("196")),
+		"skipped": lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
+	}
+	tuiSeverities = map[core.Severity]lipgloss.Style{
+		core.SeverityCritical: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("135")),
+		core.SeverityHigh:     lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196")),
+		core.SeverityMedium:   lipgloss.NewStyle().Foreground(lipgloss.Color("208")),
+		core.SeverityLow:      lipgloss.NewStyle().Foreground(lipgloss.Color("220")),
+		core.SeverityInfo:     lipgloss.NewStyle().Foreground(lipgloss.Color("39")),
+	}
+	tuiLevels = map[core.Level]lipgloss.Style{
+		core.LevelTrace: tuiMuted,
+		core.LevelDebug: tuiMuted,
+		core.LevelWarn:  lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
+		core.LevelError: lipgloss.NewStyle().Foreground(lipgloss.Color("196")),
+	}
+)
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return "Starting..."
+	}
+	// Header, prompt and help take a line each; the panes share the rest
+	body := m.height - 3
+	top := body * 3 / 5
+	bottom := body - top
+	left := m.width / 2
+	logWidth := m.width * 2 / 3
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		m.header(),
+		lipgloss.JoinHorizontal(lipgloss.Top,
+			pane("Modules", m.moduleLines(top-2), left, top),
+			pane("Findings", m.findingLines(), m.width-left, top)),
+		lipgloss.JoinHorizontal(lipgloss.Top,
+			pane("Log", tail(m.log, bottom-3), logWidth, bottom),
+			pane("Assets", m.assetLines(), m.width-logWidth, bottom)),
+		m.prompt(),
+		tuiMuted.Render(fit("p pause/resume · s skip · r rerun · y/n approve/reject · a approval on/off · ↑↓ select · q quit", m.width)),
+	)
+}
+
+func (m *tuiModel) header() string {
+	state := "RUNNING"
+	switch {
+	case m.done:
+		state = "FINISHED"
+	case m.stopping:
+		state = "STOPPING"
+	case m.paused:
+		state = "PAUSED"
+	}
+	end := time.Now()
+	if m.done {
+		end = m.finished
+	}
+	parts := []string{
+		tuiTitle.Render("Triksha"),
+		fmt.Sprintf("%d target(s)", m.targets),
+		m.mode,
+		end.Sub(m.started).Round(time.Second).String(),
+		tuiTitle.Render(state),
+	}
+	if m.agent && m.control != nil {
+		if m.control.Approval() {
+			parts = append(parts, "approval on")
+		} else {
+			parts = append(parts, "approval off")
+		}
+	}
+	return fit(strings.Join(parts, "  ·  "), m.width)
+}
+
+// prompt shows the decision waiting for approval, or the last status.
+func (m *tuiModel) prompt() string {
+	if len(m.pending) == 0 {
+		return fit(m.status, m.width)
+	}
+	p := m.pending[0]
+	text := fmt.Sprintf(" Agent wants to run %s on %s (%s)  [y] approve  [n] reject ", p.Action.ModuleName, p.Target, p.Action.Reason)
+	if len(m.pending) > 1 {
+		text += fmt.Sprintf("(+%d waiting) ", len(m.pending)-1)
+	}
+	return tuiPrompt.Render(fit(text, m.width))
+}
+
+// moduleLines lists the modules, scrolled so the selection stays in view.
+func (m *tuiModel) moduleLines(height int) []string {
+	if len(m.order) == 0 {
+		return []string{tuiMuted.Render("Waiting for the first module...")}
+	}
+	height-- // the pane title
+	first := 0
+	if m.selected >= height {
+		first = m.selected - height + 1
+	}
+	var lines []string
+	for i := first; i < len(m.order) && len(lines) < height; i++ {
+		row := m.order[i]
+		state := row.state
+		if row.skipping {
+			state = "skipping"
+		}
+		detail := row.duration
+		if row.state == "running" {
+			detail = time.Since(row.started).Round(time.Second).String()
+		}
+		if row.runs > 1 {
+			detail += fmt.Sprintf(" (run %d)", row.runs)
+		}
+		if row.err != "" {
+			detail += "  " + row.err
+		}
+		line := fmt.Sprintf("%-16s %-9s %s", row.module, state, detail)
+		if m.targets > 1 {
+			line = fmt.Sprintf("%-22s %s", row.target, line)
+		}
+		if i == m.selected {
+			lines = append(lines, tuiSelected.Render(line))
+		} else {
+			lines = append(lines, tuiStates[row.state].Render(line))
+		}
+	}
+	return lines
+}
+
+func (m *tuiModel) findingLines() []string {
+	if len(m.ranked) == 0 {
+		return []string{tuiMuted.Render("No findings yet")}
+	}
+	counts := make(map[core.Severity]int)
+	for _, f := range m.ranked {
+		counts[f.Severity]++
+	}
+	var summary []string
+	for _, sev := range core.Severities {
+		if counts[sev] > 0 {
+			summary = append(summary, tuiSeverities[sev].Render(fmt.Sprintf("%s %d", sev, counts[sev])))
+		}
+	}
+	lines := []string{strings.Join(summary, "  ")}
+	for _, f := range m.ranked {
+		style := tuiSeverities[f.Severity]
+		lines = append(lines, style.Render(fmt.Sprintf("%-8s", strings.ToUpper(string(f.Severity))))+" "+f.Title+tuiMuted.Render("  "+f.Asset.String()))
+	}
+	return lines
+}
+
+func (m *tuiModel) assetLines() []string {
+	if m.assetTotal == 0 {
+		return []string{tuiMuted.Render("No assets yet")}
+	}
+	kinds := make([]string, 0, len(m.kinds))
+	for kind := range m.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if m.kinds[kinds[i]] != m.kinds[kinds[j]] {
+			return m.kinds[kinds[i]] > m.kinds[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	var counts []string
+	for _, kind := range kinds {
+		counts = append(counts, fmt.Sprintf("%s %d", kind, m.kinds[kind]))
+	}
+	lines := []string{fmt.Sprintf("%d total: %s", m.assetTotal, strings.Join(counts, ", "))}
+	for i := len(m.assets) - 1; i >= 0; i-- {
+		lines = append(lines, tuiMuted.Render(m.assets[i]))
+	}
+	return lines
+}
+
+// pane draws a bordered box of the given outer size with a title line.
+func pane(title string, lines []string, width, height int) string {
+	inner, rows := width-2, height-2
+	if inner < 1 || rows < 1 {
+		return ""
+	}
+	content := []string{tuiTitle.Render(fit(title, inner))}
+	for _, line := range lines {
+		if len(content) == rows {
+			break
+		}
+		content = append(content, fit(line, inner))
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(tuiBorder).
+		Width(inner).
+		Height(rows).
+		Render(strings.Join(content, "\n"))
+}
+
+// tail renders the last n log events, colored by level.
+func tail(log []core.Event, n int) []string {
+	if n < 0 {
+		n = 0
+	}
+	if len(log) > n {
+		log = log[len(log)-n:]
+	}
+	lines := make([]string, len(log))
+	for i, e := range log {
+		line := e.Time.Format("15:04:05") + " " + core.FormatConsole(e)
+		if style, ok := tuiLevels[e.Level]; ok {
+			line = style.Render(line)
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+// fit cuts s, which may be styled, to one line of width terminal cells.
+func fit(s string, width int) string {
+	return lipgloss.NewStyle().MaxWidth(width).Render(strings.ReplaceAll(s, "\n", " "))
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"github.com/r4j3sh-com/triksha/server"
+)
+
+// watchCommand implements "triksha watch [flags] <scan-id>": it follows a
+// scan running on "triksha serve" over its event stream.
+func watchCommand(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha watch [flags] <scan-id>")
+		fmt.Fprintln(fs.Output(), "Exits 0 when the scan finishes, 1 when it fails or is cancelled.")
+		fs.PrintDefaults()
+	}
+	configFlag := fs.String("config", "", "Project config file, YAML or JSON (default ./triksha.yaml if present)")
+	serverFlag := fs.String("server", "", "Server URL (default http:// + config server.listen)")
+	token := fs.String("token", "", "API token (default the first of config server.tokens)")
+	logLevel := fs.String("log-level", "info", "Lowest level of log messages shown: trace, debug, info, warn or error")
+	types := fs.String("types", "", "Comma-separated event types to show (default all)")
+	jsonOut := fs.Bool("json", false, "Print events as JSON lines")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if _, err := core.ParseLevel(*logLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Log error: %v\n", err)
+		return 1
+	}
+
+	base, apiToken := *serverFlag, *token
+	if base == "" || apiToken == "" {
+		loader := core.ConfigLoader{ProjectPath: *configFlag, Env: os.Environ()}
+		cfg, _, err := loader.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			return 1
+		}
+		if base == "" {
+			addr := cfg.Server.Listen
+			if addr == "" {
+				addr = core.DefaultListen
+			}
+			base = "http://" + addr
+		}
+		if apiToken == "" && len(cfg.Server.Tokens) > 0 {
+			apiToken = cfg.Server.Tokens[0]
+		}
+	}
+	if apiToken == "" {
+		fmt.Fprintln(os.Stderr, "Error: no API token; pass -token or set server.tokens")
+		return 1
+	}
+	q := url.Values{"level": {*logLevel}}
+	if *types != "" {
+		q.Set("types", *types)
+	}
+	streamURL := strings.TrimRight(base, "/") + server.APIPrefix + "/scans/" + url.PathEscape(fs.Arg(0)) + "/stream?" + q.Encode()
+
+	show := core.ConsoleSink(os.Stdout, core.LevelTrace)
+	if *jsonOut {
+		show = core.JSONSink(os.Stdout, core.LevelTrace)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Reconnect from the last event seen until the scan ends
+	last := -1
+	for {
+		end, err := watchStream(ctx, streamURL, apiToken, last, func(e server.StreamEvent) {
+			last = e.Seq
+			if e.Type != core.EventLog && e.Level < core.LevelInfo {
+				e.Level = core.LevelInfo // progress, whatever its log level
+			}
+			show(e.Event)
+		})
+		if end != nil {
+			fmt.Fprintf(os.Stderr, "Scan %s %s\n", fs.Arg(0), end.Status)
+			if end.Error != "" {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", end.Error)
+			}
+			if end.Status != server.JobFinished {
+				return 1
+			}
+			return 0
+		}
+		var fatal *watchError
+		switch {
+		case ctx.Err() != nil:
+			return 1
+		case errors.As(err, &fatal):
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Stream interrupted (%v); reconnecting...\n", err)
+		select {
+		case <-ctx.Done():
+			return 1
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+// watchError is a response from the server that reconnecting won't fix.
+type watchError struct{ msg string }
+
+func (e *watchError) Error() string { return e.msg }
+
+// watchStream reads one connection of a Server-Sent Events stream, passing
+// events to fn, and returns the end message if the stream got that far.
+func watchStream(ctx context.Context, streamURL, token string, last int, fn func(server.StreamEvent)) (*server.StreamEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return nil, &watchError{err.Error()}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "text/event-stream")
+	if last >= 0 {
+		req.Header.Set("Last-Event-ID", strconv.Itoa(last))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+		if body.Error == "" {
+			body.Error = resp.Status
+		}
+		if resp.StatusCode >= 500 {
+			return nil, errors.New(body.Error)
+		}
+		return nil, &watchError{body.Error}
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	var data strings.Builder
+	for sc.Scan() {
+		line := sc.Text()
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue // id, event, retry and comment lines
+		}
+		var e server.StreamEvent
+		err := json.Unmarshal([]byte(data.String()), &e)
+		data.Reset()
+		if err != nil {
+			return nil, fmt.Errorf("invalid event: %v", err)
+		}
+		if e.Type == server.StreamEnd {
+			return &e, nil
+		}
+		fn(e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
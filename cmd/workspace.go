@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+// openWorkspace opens the database at path and resolves ws, falling back
+// to the selected workspace.
+func openWorkspace(path, ws string) (*core.DB, string, error) {
+	db, err := core.OpenDB(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if ws == "" {
+		if ws, err = db.CurrentWorkspace(); err != nil {
+			return nil, "", err
+		}
+	}
+	return db, ws, nil
+}
+
+// workspaceCommand implements "triksha workspace [list|create|use|delete]".
+func workspaceCommand(args []string) int {
+	fs := flag.NewFlagSet("workspace", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha workspace [flags] [list | create <name> [description] | use <name> | delete <name>]")
+		fs.PrintDefaults()
+	}
+	dbPath := fs.String("db", core.DefaultDBPath, "Workspace database")
+	fs.Parse(args)
+
+	db, current, err := openWorkspace(*dbPath, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	action, rest := "list", []string(nil)
+	if fs.NArg() > 0 {
+		action, rest = fs.Arg(0), fs.Args()[1:]
+	}
+	if action != "list" && len(rest) == 0 {
+		fs.Usage()
+		return 2
+	}
+	switch action {
+	case "list":
+		list, err := db.Workspaces()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(list) == 0 {
+			list = []core.Workspace{{Name: core.DefaultWorkspace}}
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tSCANS\tCREATED\tDESCRIPTION")
+		for _, ws := range list {
+			marker := ""
+			if ws.Name == current {
+				marker = "*"
+			}
+			scans, _ := db.Scans(ws.Name)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", marker, ws.Name, len(scans), formatTime(ws.Created), ws.Description)
+		}
+		w.Flush()
+	case "create":
+		if err := db.CreateWorkspace(rest[0], strings.Join(rest[1:], " ")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := db.UseWorkspace(rest[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Created workspace %s and switched to it\n", rest[0])
+	case "use":
+		if err := db.UseWorkspace(rest[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Switched to workspace %s\n", rest[0])
+	case "delete":
+		if err := db.DeleteWorkspace(rest[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Deleted workspace %s\n", rest[0])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown workspace action: %s\n", action)
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
+
+// scansCommand implements "triksha scans [flags] [scan-id...]": without
+// IDs it lists the workspace's scans, with IDs it describes them.
+func scansCommand(args []string) int {
+	fs := flag.NewFlagSet("scans", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha scans [flags] [scan-id...]")
+		fs.PrintDefaults()
+	}
+	dbPath := fs.String("db", core.DefaultDBPath, "Workspace database")
+	wsFlag := fs.String("workspace", "", "Workspace to read (default the selected one)")
+	jsonOut := fs.Bool("json", false, "Print scans as JSON")
+	fs.Parse(args)
+
+	db, ws, err := openWorkspace(*dbPath, *wsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var scans []core.ScanRecord
+	if fs.NArg() == 0 {
+		scans, err = db.Scans(ws)
+	}
+	for _, id := range fs.Args() {
+		var rec core.ScanRecord
+		if rec, err = db.Scan(ws, id); err != nil {
+			break
+		}
+		scans = append(scans, rec)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *jsonOut {
+		if scans == nil {
+			scans = []core.ScanRecord{}
+		}
+		return printJSON(scans)
+	}
+	if fs.NArg() > 0 {
+		for i, rec := range scans {
+			if i > 0 {
+				fmt.Println()
+			}
+			printScan(rec)
+		}
+		return 0
+	}
+	if len(scans) == 0 {
+		fmt.Printf("No scans in workspace %s\n", ws)
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tSTATUS\tTARGETS\tASSETS\tFINDINGS")
+	for _, rec := range scans {
+		assets := 0
+		for _, n := range rec.Assets {
+			assets += n
+		}
+		findings := 0
+		for _, n := range rec.FindingCounts {
+			findings += n
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\n", rec.ID, formatTime(rec.Started), rec.Status, strings.Join(rec.Targets, ","), assets, findings)
+	}
+	w.Flush()
+	return 0
+}
+
+// printScan writes the full record of one scan.
+func printScan(rec core.ScanRecord) {
+	fmt.Printf("Scan %s (%s)\n", rec.ID, rec.Status)
+	fmt.Printf("  Started:   %s\n", formatTime(rec.Started))
+	if !rec.Finished.IsZero() {
+		fmt.Printf("  Finished:  %s (%s)\n", formatTime(rec.Finished), rec.Finished.Sub(rec.Started).Round(time.Second))
+	}
+	fmt.Printf("  Targets:   %s\n", strings.Join(rec.Targets, ", "))
+	if len(rec.Modules) > 0 {
+		fmt.Printf("  Modules:   %s\n", strings.Join(rec.Modules, ", "))
+	}
+	fmt.Printf("  Results:   %d\n", rec.Results)
+	var assets []string
+	for _, kind := range core.NodeKinds {
+		if n := rec.Assets[kind]; n > 0 {
+			assets = append(assets, fmt.Sprintf("%d %s", n, kind))
+		}
+	}
+	fmt.Printf("  Assets:    %s\n", orDash(strings.Join(assets, ", ")))
+	var findings []string
+	for _, sev := range core.Severities {
+		if n := rec.FindingCounts[sev]; n > 0 {
+			findings = append(findings, fmt.Sprintf("%d %s", n, sev))
+		}
+	}
+	fmt.Printf("  Findings:  %s\n", orDash(strings.Join(findings, ", ")))
+}
+
+// assetsCommand implements "triksha assets [flags] [match]": it queries
+// the assets recorded across a workspace's scans.
+func assetsCommand(args []string) int {
+	fs := flag.NewFlagSet("assets", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: triksha assets [flags] [match]")
+		fs.PrintDefaults()
+	}
+	dbPath := fs.String("db", core.DefaultDBPath, "Workspace database")
+	wsFlag := fs.String("workspace", "", "Workspace to read (default the selected one)")
+	kinds := fs.String("kind", "", "Comma-separated asset kinds, e.g. subdomain,service")
+	scan := fs.String("scan", "", "Only assets reported by this scan ID")
+	jsonOut := fs.Bool("json", false, "Print assets as JSON")
+	fs.Parse(args)
+
+	q := core.AssetQuery{Scan: *scan, Match: strings.Join(fs.Args(), " ")}
+	for _, kind := range strings.Split(*kinds, ",") {
+		if kind = strings.TrimSpace(kind); kind == "" {
+			continue
+		}
+		if !containsKind(core.NodeKinds, core.NodeKind(kind)) {
+			fmt.Fprintf(os.Stderr, "Unknown asset kind: %s\n", kind)
+			return 2
+		}
+		q.Kinds = append(q.Kinds, core.NodeKind(kind))
+	}
+
+	db, ws, err := openWorkspace(*dbPath, *wsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	assets, err := db.Assets(ws, q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *jsonOut {
+		if assets == nil {
+			assets = []core.AssetRecord{}
+		}
+		return printJSON(assets)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tVALUE\tSCANS\tFIRST SEEN\tLAST SEEN\tDETAILS")
+	for _, a := range assets {
+		var details []string
+		for _, k := range sortedAttrKeys(a.Attrs) {
+			details = append(details, k+"="+a.Attrs[k])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", a.Kind, a.Value, len(a.Scans), formatTime(a.FirstSeen), formatTime(a.LastSeen), strings.Join(details, " "))
+	}
+	w.Flush()
+	return 0
+}
+
+func printJSON(v interface{}) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func containsKind(kinds []core.NodeKind, kind core.NodeKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedAttrKeys(attrs map[string]string) []string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -12,20 +12,15 @@ const (
 	DefaultMaxExecutions = 1 // Default max executions
 )
 
-// Add a map of module-specific execution limits
-var ModuleExecutionLimits = map[string]int{
-	"passive":   1, // Passive recon only needs to run once
-	"subdomain": 2, // Subdomain enumeration benefits from multiple runs
-	"portscan":  1, // Port scanning only needs one thorough run
-	"webenum":   2, // Web enumeration might need multiple runs with different wordlists
-	"vulnscan":  1, // Vulnerability scanning only needs one thorough run
-	"report":    1, // Report generation only needs to run once
-}
-
 // LLMAgent uses an LLM for decision making
 type LLMAgent struct {
 	LLMClient        LLMClient
 	ModuleExecutions map[string]int
+	// Catalog lists the modules the LLM may pick, with their descriptions,
+	// execution limits and parameters (e.g. a bigger webenum wordlist).
+	Catalog []CatalogEntry
+	// rejected holds the decisions the operator turned down, for the prompt.
+	rejected []Action
 }
 
 // Action describes what the agent recommends next.
@@ -43,8 +38,19 @@ type Agent interface {
 	RecoverFromError(ctx *Context, history []Result, err error) (Action, error)
 }
 
-// SimpleAgent is a placeholder/dummy agent (no LLM yet).
-type SimpleAgent struct{}
+// ReviewedAgent is implemented by agents that want to hear when the
+// operator rejects one of their decisions.
+type ReviewedAgent interface {
+	Rejected(action Action)
+}
+
+// SimpleAgent runs each catalog module once, in catalog order.
+type SimpleAgent struct {
+	Modules []string
+	// tried holds the modules already picked, so one that failed, was
+	// skipped or was rejected is not picked again.
+	tried map[string]bool
+}
 
 func NewLLMAgent(client LLMClient) *LLMAgent {
 	return &LLMAgent{
@@ -53,22 +59,74 @@ func NewLLMAgent(client LLMClient) *LLMAgent {
 	}
 }
 
-// NewAgent returns a basic (non-AI) agent for now.
-func NewAgent() Agent {
-	return &SimpleAgent{}
+// ExecutionCounts returns a copy of the per-module run counters.
+func (a *LLMAgent) ExecutionCounts() map[string]int {
+	counts := make(map[string]int, len(a.ModuleExecutions))
+	for name, n := range a.ModuleExecutions {
+		counts[name] = n
+	}
+	return counts
+}
+
+// RestoreExecutionCounts reloads counters saved by a checkpoint.
+func (a *LLMAgent) RestoreExecutionCounts(counts map[string]int) {
+	a.ModuleExecutions = make(map[string]int, len(counts))
+	for name, n := range counts {
+		a.ModuleExecutions[name] = n
+	}
+}
+
+// Rejected gives back the run counted for a rejected decision and keeps
+// it for the prompt, so the LLM knows the operator turned it down.
+func (a *LLMAgent) Rejected(action Action) {
+	if a.ModuleExecutions[action.ModuleName] > 0 {
+		a.ModuleExecutions[action.ModuleName]--
+	}
+	a.rejected = append(a.rejected, action)
+}
+
+// NewAgent returns a basic (non-AI) agent that walks the catalog's
+// agent modules in order.
+func NewAgent(catalog []CatalogEntry) Agent {
+	agent := &SimpleAgent{}
+	for _, entry := range AgentModules(catalog) {
+		agent.Modules = append(agent.Modules, entry.Name)
+	}
+	return agent
+}
+
+// moduleNames lists the catalog modules the agent may pick, in order.
+func (a *LLMAgent) moduleNames() []string {
+	var names []string
+	for _, entry := range AgentModules(a.Catalog) {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+// limit returns how often the agent may run a module.
+func (a *LLMAgent) limit(name string) int {
+	for _, entry := range a.Catalog {
+		if entry.Name == name {
+			return entry.Limit()
+		}
+	}
+	return DefaultMaxExecutions
 }
 
 // DecideNextAction recommends the next module in a fixed order.
 // Later, this will use LLM/AI for smarter decisions.
 func (a *SimpleAgent) DecideNextAction(ctx *Context, history []Result) (Action, error) {
-	// List of modules in preferred order
-	modules := []string{"passive", "subdomain", "portscan", "webenum", "vulnscan", "report"}
 	seen := map[string]bool{}
 	for _, r := range history {
 		seen[r.ModuleName] = true
 	}
-	for _, name := range modules {
-		if !seen[name] {
+	for _, name := range a.Modules {
+		if !seen[name] && !a.tried[name] {
+			if a.tried == nil {
+				a.tried = make(map[string]bool)
+			}
+			a.tried[name] = true
 			return Action{
 				ModuleName: name,
 				Params:     map[string]interface{}{},
@@ -91,15 +149,12 @@ func (a *SimpleAgent) RecoverFromError(ctx *Context, history []Result, err error
 // Update the DecideNextAction method to use the module-specific limits
 func (a *LLMAgent) DecideNextAction(ctx *Context, history []Result) (Action, error) {
 	// Check if we've completed all modules or reached execution limits
-	allModules := []string{"passive", "subdomain", "portscan", "webenum", "vulnscan", "report"}
+	allModules := a.moduleNames()
 	executedAll := true
 
 	for _, module := range allModules {
 		count := a.ModuleExecutions[module]
-		limit := ModuleExecutionLimits[module]
-		if limit == 0 {
-			limit = DefaultMaxExecutions
-		}
+		limit := a.limit(module)
 
 		if count < limit {
 			executedAll = false
@@ -146,10 +201,7 @@ func (a *LLMAgent) DecideNextAction(ctx *Context, history []Result) (Action, err
 	var moduleStatus strings.Builder
 	for _, module := range allModules {
 		count := a.ModuleExecutions[module]
-		limit := ModuleExecutionLimits[module]
-		if limit == 0 {
-			limit = DefaultMaxExecutions
-		}
+		limit := a.limit(module)
 
 		status := "available"
 		if count >= limit {
@@ -171,19 +223,17 @@ func (a *LLMAgent) DecideNextAction(ctx *Context, history []Result) (Action, err
 TARGET: %s
 
 AVAILABLE MODULES:
-- passive: Performs passive reconnaissance (WHOIS, DNS, certificates)
-- subdomain: Enumerates subdomains using various techniques
-- portscan: Scans for open ports and services
-- webenum: Enumerates web technologies and directories
-- vulnscan: Scans for vulnerabilities
-- report: Generates final report
-
+%s
+MODULE PARAMETERS (optional, omitted ones use defaults):
+%s
 MODULE EXECUTION STATUS:
 %s
-
+DISCOVERED ASSETS:
+%s
 RECON HISTORY:
 %s
-
+REJECTED BY THE OPERATOR:
+%s
 Based on the above information, what module should run next? 
 
 INSTRUCTIONS:
@@ -191,8 +241,10 @@ INSTRUCTIONS:
 2. Decide which module would be most logical to run next
 3. DO NOT select a module that has reached its maximum execution count
 4. If a module failed previously, consider retrying it
-5. Provide a brief reason for your decision
-6. Format your response EXACTLY as valid JSON:
+5. Do not repeat a decision the operator rejected unless new results justify it
+6. Set "params" only with parameters listed above for the chosen module
+7. Provide a brief reason for your decision
+8. Format your response EXACTLY as valid JSON:
 
 {
   "module": "module_name",
@@ -206,32 +258,30 @@ If all modules have been completed or no further action is needed, respond with:
   "params": {},
   "reason": "all reconnaissance completed"
 }
-`, ctx.Target, moduleStatus.String(), string(historyJson))
+`, ctx.Target, describeModules(AgentModules(a.Catalog)), describeParams(AgentModules(a.Catalog)), moduleStatus.String(), describeAssets(ctx.Assets), string(historyJson), describeRejected(a.rejected))
 
 	// Rest of the method remains the same...
-	fmt.Println("[DEBUG] Sending prompt to LLM...")
+	log := ctx.Logger("agent")
+	log.Debugf("Sending prompt to LLM...")
 	answer, err := a.LLMClient.Chat(prompt)
 	if err != nil {
-		fmt.Printf("[ERROR] LLM error: %v\n", err)
+		log.Errorf("LLM error: %v", err)
 		return Action{}, err
 	}
 
-	fmt.Printf("[DEBUG] Raw LLM response: %s\n", answer)
+	log.Tracef("Raw LLM response: %s", answer)
 
 	// Extract JSON from the response
 	cleanedJSON := extractJSONagent(answer)
 	if cleanedJSON == "" {
-		fmt.Printf("[ERROR] No valid JSON found in LLM response\n")
+		log.Errorf("No valid JSON found in LLM response")
 
 		// Fallback to a simple module selection if JSON extraction fails
-		fmt.Println("[INFO] Falling back to simple module selection")
+		log.Infof("Falling back to simple module selection")
 
 		// Simple module selection logic with execution limits
 		for _, name := range allModules {
-			limit := ModuleExecutionLimits[name]
-			if limit == 0 {
-				limit = DefaultMaxExecutions
-			}
+			limit := a.limit(name)
 
 			if a.ModuleExecutions[name] < limit {
 				return Action{
@@ -245,7 +295,7 @@ If all modules have been completed or no further action is needed, respond with:
 		return Action{}, fmt.Errorf("all modules completed (fallback)")
 	}
 
-	fmt.Printf("[DEBUG] Extracted JSON: %s\n", cleanedJSON)
+	log.Debugf("Extracted JSON: %s", cleanedJSON)
 
 	// Try to parse the JSON response
 	var parsed struct {
@@ -255,17 +305,14 @@ If all modules have been completed or no further action is needed, respond with:
 	}
 
 	if err := json.Unmarshal([]byte(cleanedJSON), &parsed); err != nil {
-		fmt.Printf("[ERROR] JSON parse error: %v\n", err)
+		log.Errorf("JSON parse error: %v", err)
 
 		// Fallback to a simple module selection if JSON parsing fails
-		fmt.Println("[INFO] Falling back to simple module selection")
+		log.Infof("Falling back to simple module selection")
 
 		// Simple module selection logic with execution limits
 		for _, name := range allModules {
-			limit := ModuleExecutionLimits[name]
-			if limit == 0 {
-				limit = DefaultMaxExecutions
-			}
+			limit := a.limit(name)
 
 			if a.ModuleExecutions[name] < limit {
 				return Action{
@@ -284,20 +331,14 @@ If all modules have been completed or no further action is needed, respond with:
 	}
 
 	// Check if the selected module has reached its execution limit
-	limit := ModuleExecutionLimits[parsed.Module]
-	if limit == 0 {
-		limit = DefaultMaxExecutions
-	}
+	limit := a.limit(parsed.Module)
 
 	if a.ModuleExecutions[parsed.Module] >= limit {
-		fmt.Printf("[WARNING] LLM selected %s which has reached its execution limit\n", parsed.Module)
+		log.Warnf("LLM selected %s which has reached its execution limit", parsed.Module)
 
 		// Find an alternative module that hasn't reached its limit
 		for _, name := range allModules {
-			moduleLimit := ModuleExecutionLimits[name]
-			if moduleLimit == 0 {
-				moduleLimit = DefaultMaxExecutions
-			}
+			moduleLimit := a.limit(name)
 
 			if a.ModuleExecutions[name] < moduleLimit {
 				parsed.Module = name
@@ -313,7 +354,7 @@ If all modules have been completed or no further action is needed, respond with:
 
 	// Update the execution count for the selected module
 	a.ModuleExecutions[parsed.Module]++
-	fmt.Printf("[INFO] Module %s execution count: %d/%d\n",
+	log.Debugf("Module %s execution count: %d/%d",
 		parsed.Module,
 		a.ModuleExecutions[parsed.Module],
 		limit)
@@ -325,17 +366,16 @@ If all modules have been completed or no further action is needed, respond with:
 	}, nil
 }
 
+// RecoverFromError asks the LLM how to recover from err. A suggestion is
+// not counted as a run of its module; DecideNextAction counts the runs.
 func (a *LLMAgent) RecoverFromError(ctx *Context, history []Result, err error) (Action, error) {
 	// Build module execution status for the prompt
-	allModules := []string{"passive", "subdomain", "portscan", "webenum", "vulnscan", "report"}
+	allModules := a.moduleNames()
 
 	var moduleStatus strings.Builder
 	for _, module := range allModules {
 		count := a.ModuleExecutions[module]
-		limit := ModuleExecutionLimits[module]
-		if limit == 0 {
-			limit = DefaultMaxExecutions
-		}
+		limit := a.limit(module)
 
 		status := "available"
 		if count >= limit {
@@ -387,10 +427,11 @@ INSTRUCTIONS:
 `, ctx.Target, err.Error(), errorModule, moduleStatus.String(), string(historyJson))
 
 	// Send to LLM
-	fmt.Println("[DEBUG] Sending error recovery prompt to LLM...")
+	log := ctx.Logger("agent")
+	log.Debugf("Sending error recovery prompt to LLM...")
 	answer, err := a.LLMClient.Chat(prompt)
 	if err != nil {
-		fmt.Printf("[ERROR] LLM error during recovery: %v\n", err)
+		log.Errorf("LLM error during recovery: %v", err)
 		// Fall back to simple skip
 		return Action{
 			ModuleName: "",
@@ -399,12 +440,12 @@ INSTRUCTIONS:
 		}, nil
 	}
 
-	fmt.Printf("[DEBUG] Raw LLM recovery response: %s\n", answer)
+	log.Tracef("Raw LLM recovery response: %s", answer)
 
 	// Extract JSON from the response
 	cleanedJSON := extractJSONagent(answer)
 	if cleanedJSON == "" {
-		fmt.Printf("[ERROR] No valid JSON found in LLM response\n")
+		log.Errorf("No valid JSON found in LLM response")
 		return Action{
 			ModuleName: "",
 			Params:     nil,
@@ -420,7 +461,7 @@ INSTRUCTIONS:
 	}
 
 	if err := json.Unmarshal([]byte(cleanedJSON), &parsed); err != nil {
-		fmt.Printf("[ERROR] JSON parse error in recovery: %v\n", err)
+		log.Errorf("JSON parse error in recovery: %v", err)
 		// Fall back to simple skip
 		return Action{
 			ModuleName: "",
@@ -441,10 +482,7 @@ INSTRUCTIONS:
 		}
 
 		// Check if we've reached the retry limit
-		limit := ModuleExecutionLimits[errorModule]
-		if limit == 0 {
-			limit = DefaultMaxExecutions
-		}
+		limit := a.limit(errorModule)
 
 		if a.ModuleExecutions[errorModule] >= limit {
 			return Action{
@@ -454,9 +492,6 @@ INSTRUCTIONS:
 			}, nil
 		}
 
-		// Update execution count for the retry
-		a.ModuleExecutions[errorModule]++
-
 		return Action{
 			ModuleName: errorModule,
 			Params:     nil,
@@ -473,10 +508,7 @@ INSTRUCTIONS:
 		}
 
 		// Check if the alternative module has reached its limit
-		limit := ModuleExecutionLimits[parsed.Module]
-		if limit == 0 {
-			limit = DefaultMaxExecutions
-		}
+		limit := a.limit(parsed.Module)
 
 		if a.ModuleExecutions[parsed.Module] >= limit {
 			return Action{
@@ -486,9 +518,6 @@ INSTRUCTIONS:
 			}, nil
 		}
 
-		// Update execution count for the alternative module
-		a.ModuleExecutions[parsed.Module]++
-
 		return Action{
 			ModuleName: parsed.Module,
 			Params:     nil,
@@ -498,15 +527,9 @@ INSTRUCTIONS:
 	default: // "skip" or any other response
 		// Find the next logical module to run
 		for _, name := range allModules {
-			limit := ModuleExecutionLimits[name]
-			if limit == 0 {
-				limit = DefaultMaxExecutions
-			}
+			limit := a.limit(name)
 
 			if a.ModuleExecutions[name] < limit && name != errorModule {
-				// Update execution count for the next module
-				a.ModuleExecutions[name]++
-
 				return Action{
 					ModuleName: name,
 					Params:     nil,
@@ -523,6 +546,122 @@ INSTRUCTIONS:
 	}
 }
 
+// describeModules renders catalog entries for the LLM prompt.
+func describeModules(catalog []CatalogEntry) string {
+	var sb strings.Builder
+	for _, entry := range catalog {
+		sb.WriteString(fmt.Sprintf("- %s: %s", entry.Name, entry.Description))
+		if entry.Category != "" {
+			sb.WriteString(fmt.Sprintf(" [%s, noise: %s]", entry.Category, entry.Noise))
+		}
+		if len(entry.Produces) > 0 {
+			sb.WriteString(" (produces: " + strings.Join(entry.Produces, ", ") + ")")
+		}
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		return "none\n"
+	}
+	return sb.String()
+}
+
+// describeParams renders module parameter schemas for the LLM prompt.
+func describeParams(catalog []CatalogEntry) string {
+	var sb strings.Builder
+	for _, entry := range catalog {
+		if len(entry.Params) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("- %s:\n", entry.Name))
+		for _, spec := range entry.Params {
+			def, _ := json.Marshal(spec.Default)
+			sb.WriteString(fmt.Sprintf("    - %s (%s, default %s): %s\n", spec.Name, spec.Type, def, spec.Description))
+		}
+	}
+	if sb.Len() == 0 {
+		return "none\n"
+	}
+	return sb.String()
+}
+
+// describeRejected lists the operator's rejected decisions for the prompt.
+func describeRejected(actions []Action) string {
+	var sb strings.Builder
+	for _, action := range actions {
+		sb.WriteString(fmt.Sprintf("- %s", action.ModuleName))
+		if len(action.Params) > 0 {
+			params, _ := json.Marshal(action.Params)
+			sb.WriteString(fmt.Sprintf(" with params %s", params))
+		}
+		if action.Reason != "" {
+			sb.WriteString(fmt.Sprintf(" (proposed because: %s)", action.Reason))
+		}
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		return "none\n"
+	}
+	return sb.String()
+}
+
+// maxPromptAssets caps how many assets of one kind the prompt lists.
+const maxPromptAssets = 20
+
+// describeAssets summarizes the asset graph for the LLM prompt, one line
+// per kind with its relations (e.g. "a.example.com -> 192.0.2.1").
+func describeAssets(g *AssetGraph) string {
+	var sb strings.Builder
+	for _, kind := range NodeKinds {
+		nodes := g.Nodes(kind)
+		if len(nodes) == 0 {
+			continue
+		}
+		var items []string
+		for i, n := range nodes {
+			if i == maxPromptAssets {
+				items = append(items, fmt.Sprintf("... and %d more", len(nodes)-i))
+				break
+			}
+			items = append(items, describeNode(g, n))
+		}
+		sb.WriteString(fmt.Sprintf("- %s (%d): %s\n", kind, len(nodes), strings.Join(items, "; ")))
+	}
+	if sb.Len() == 0 {
+		return "none yet\n"
+	}
+	return sb.String()
+}
+
+// describeNode renders one asset with its most useful relation.
+func describeNode(g *AssetGraph, n Node) string {
+	var related []Node
+	switch n.Kind {
+	case NodeDomain, NodeSubdomain:
+		related = g.Out(n.ID, EdgeResolvesTo)
+	case NodeService:
+		if service := n.Attrs["service"]; service != "" {
+			return n.Value + " " + service
+		}
+	case NodeTechnology:
+		related = g.In(n.ID, EdgeRuns)
+	case NodeFinding:
+		related = g.Out(n.ID, EdgeAffects)
+		return fmt.Sprintf("[%s] %s on %s", strings.ToUpper(n.Attrs["severity"]), n.Attrs["title"], joinValues(related))
+	}
+	if len(related) == 0 {
+		return n.Value
+	}
+	return n.Value + " -> " + joinValues(related)
+}
+
+func joinValues(nodes []Node) string {
+	values := make([]string, len(nodes))
+	for i, n := range nodes {
+		values[i] = n.Value
+	}
+	return strings.Join(values, ", ")
+}
+
 // extractJSON extracts valid JSON from a potentially messy LLM response
 func extractJSONagent(text string) string {
 	// If the text is already valid JSON, return it
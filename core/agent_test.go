@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// cannedLLM answers every prompt with the same reply.
+type cannedLLM string
+
+func (c cannedLLM) Chat(string) (string, error) { return string(c), nil }
+
+func (c cannedLLM) ChatWithTimeout(context.Context, string, time.Duration) (string, error) {
+	return string(c), nil
+}
+
+func TestRecoverFromErrorKeepsCounts(t *testing.T) {
+	tests := []struct {
+		reply      string
+		wantModule string
+	}{
+		{`{"action": "retry", "reason": "timeout"}`, "subdomain"},
+		{`{"action": "alternative", "module": "passive", "reason": "try passive"}`, "passive"},
+		{`{"action": "skip", "reason": "give up"}`, "passive"},
+	}
+	catalog := []CatalogEntry{{Name: "passive"}, {Name: "subdomain", ModuleInfo: ModuleInfo{MaxRuns: 2}}}
+	for _, tt := range tests {
+		agent := NewLLMAgent(cannedLLM(tt.reply))
+		agent.Catalog = catalog
+		agent.RestoreExecutionCounts(map[string]int{"subdomain": 1})
+		history := []Result{{ModuleName: "subdomain"}}
+		action, err := agent.RecoverFromError(NewContext("example.com"), history, context.DeadlineExceeded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if action.ModuleName != tt.wantModule {
+			t.Errorf("%s: suggested %q, want %q", tt.reply, action.ModuleName, tt.wantModule)
+		}
+		// The runner only logs the suggestion, so it must not count as a run
+		if got := agent.ExecutionCounts(); !reflect.DeepEqual(got, map[string]int{"subdomain": 1}) {
+			t.Errorf("%s: execution counts = %v, want them unchanged", tt.reply, got)
+		}
+	}
+}
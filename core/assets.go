@@ -0,0 +1,560 @@
+package core
+
+import (
+	"encoding/json"
+	"net"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NodeKind classifies an asset in the graph.
+type NodeKind string
+
+const (
+	NodeDomain      NodeKind = "domain" // the scanned domain
+	NodeSubdomain   NodeKind = "subdomain"
+	NodeIP          NodeKind = "ip"
+	NodeNetblock    NodeKind = "netblock" // CIDR range
+	NodeService     NodeKind = "service"  // open port, "host:port"
+	NodeURL         NodeKind = "url"
+	NodeTechnology  NodeKind = "technology"
+	NodeCertificate NodeKind = "certificate" // keyed by SHA-256 fingerprint
+	NodeFinding     NodeKind = "finding"     // keyed by Finding.ID
+)
+
+// NodeKinds lists every node kind, roughly from the widest asset down.
+var NodeKinds = []NodeKind{NodeDomain, NodeSubdomain, NodeNetblock, NodeIP, NodeService, NodeURL, NodeTechnology, NodeCertificate, NodeFinding}
+
+// EdgeKind names the relation an edge expresses, read "from kind to".
+type EdgeKind string
+
+const (
+	EdgeResolvesTo  EdgeKind = "resolves_to"  // domain/subdomain -> ip
+	EdgeSubdomainOf EdgeKind = "subdomain_of" // subdomain -> domain
+	EdgeContains    EdgeKind = "contains"     // netblock -> ip
+	EdgeHosts       EdgeKind = "hosts"        // domain/subdomain/ip -> service
+	EdgeServes      EdgeKind = "serves"       // service -> url
+	EdgeRuns        EdgeKind = "runs"         // url -> technology
+	EdgeSecures     EdgeKind = "secures"      // certificate -> domain/subdomain/ip
+	EdgeAffects     EdgeKind = "affects"      // finding -> any asset
+)
+
+// Provenance records which module (and data source) reported a node or
+// edge, and when it was first and last seen.
+type Provenance struct {
+	Module string    `json:"module,omitempty"`
+	Source string    `json:"source,omitempty"`
+	First  time.Time `json:"first_seen"`
+	Last   time.Time `json:"last_seen"`
+}
+
+// Node is one asset. Attrs holds kind-specific details such as a
+// service's banner or a URL's status code.
+type Node struct {
+	ID      string            `json:"id"`
+	Kind    NodeKind          `json:"kind"`
+	Value   string            `json:"value"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+	Sources []Provenance      `json:"sources"`
+}
+
+// Edge is a directed relation between two nodes.
+type Edge struct {
+	From    string       `json:"from"`
+	Kind    EdgeKind     `json:"kind"`
+	To      string       `json:"to"`
+	Sources []Provenance `json:"sources"`
+}
+
+// AssetGraph links the assets found during a scan. It is safe for
+// concurrent use; a nil *AssetGraph reads as empty and ignores writes.
+type AssetGraph struct {
+	mu    sync.RWMutex
+	nodes map[string]*Node
+	edges map[string]*Edge // keyed by from|kind|to
+}
+
+// NewAssetGraph returns an empty graph.
+func NewAssetGraph() *AssetGraph {
+	return &AssetGraph{nodes: make(map[string]*Node), edges: make(map[string]*Edge)}
+}
+
+// NodeID returns the ID of the node of kind with value. Host names and
+// technologies are case-insensitive; IPs use their canonical form.
+func NodeID(kind NodeKind, value string) string {
+	return string(kind) + ":" + normalizeValue(kind, value)
+}
+
+// displayValue is the value a node keeps: normalized, except that
+// technologies keep the spelling they were first reported with.
+func displayValue(kind NodeKind, value string) string {
+	if kind == NodeTechnology {
+		return strings.TrimSpace(value)
+	}
+	return normalizeValue(kind, value)
+}
+
+func normalizeValue(kind NodeKind, value string) string {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case NodeDomain, NodeSubdomain:
+		return strings.ToLower(strings.TrimSuffix(value, "."))
+	case NodeIP:
+		if addr, err := netip.ParseAddr(value); err == nil {
+			return addr.String()
+		}
+	case NodeNetblock:
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			return prefix.Masked().String()
+		}
+	case NodeService, NodeTechnology, NodeCertificate:
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+func edgeKey(from string, kind EdgeKind, to string) string {
+	return from + "|" + string(kind) + "|" + to
+}
+
+// AddNode adds or updates a node and reports whether it is new. Attrs are
+// merged into the existing ones; prov is added to its sources.
+func (g *AssetGraph) AddNode(kind NodeKind, value string, attrs map[string]string, prov Provenance) (id string, added bool) {
+	id = NodeID(kind, value)
+	if g == nil {
+		return id, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n, ok := g.nodes[id]
+	if !ok {
+		n = &Node{ID: id, Kind: kind, Value: displayValue(kind, value)}
+		g.nodes[id] = n
+	}
+	for k, v := range attrs {
+		if v == "" {
+			continue
+		}
+		if n.Attrs == nil {
+			n.Attrs = make(map[string]string, len(attrs))
+		}
+		n.Attrs[k] = v
+	}
+	n.Sources = mergeProvenance(n.Sources, prov)
+	return id, !ok
+}
+
+// AddEdge links two existing nodes and reports whether the edge is new.
+// Edges to unknown nodes are dropped.
+func (g *AssetGraph) AddEdge(from string, kind EdgeKind, to string, prov Provenance) bool {
+	if g == nil || from == to {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.nodes[from] == nil || g.nodes[to] == nil {
+		return false
+	}
+	key := edgeKey(from, kind, to)
+	e, ok := g.edges[key]
+	if !ok {
+		e = &Edge{From: from, Kind: kind, To: to}
+		g.edges[key] = e
+	}
+	e.Sources = mergeProvenance(e.Sources, prov)
+	return !ok
+}
+
+// mergeProvenance adds p to sources, widening the seen window of an
+// existing entry for the same module and source.
+func mergeProvenance(sources []Provenance, p Provenance) []Provenance {
+	if p.First.IsZero() {
+		p.First = time.Now()
+	}
+	if p.Last.IsZero() {
+		p.Last = p.First
+	}
+	for i, s := range sources {
+		if s.Module == p.Module && s.Source == p.Source {
+			if p.First.Before(s.First) {
+				sources[i].First = p.First
+			}
+			if p.Last.After(s.Last) {
+				sources[i].Last = p.Last
+			}
+			return sources
+		}
+	}
+	return append(sources, p)
+}
+
+// Node returns a copy of the node with id.
+func (g *AssetGraph) Node(id string) (Node, bool) {
+	if g == nil {
+		return Node{}, false
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	n, ok := g.nodes[id]
+	if !ok {
+		return Node{}, false
+	}
+	return n.clone(), true
+}
+
+// Nodes returns the nodes of the given kinds (all nodes when none are
+// given), sorted by kind and value.
+func (g *AssetGraph) Nodes(kinds ...NodeKind) []Node {
+	if g == nil {
+		return nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var out []Node
+	for _, n := range g.nodes {
+		if len(kinds) == 0 || containsKind(kinds, n.Kind) {
+			out = append(out, n.clone())
+		}
+	}
+	sortNodes(out)
+	return out
+}
+
+// Edges returns every edge, sorted by source node, kind and target.
+func (g *AssetGraph) Edges() []Edge {
+	if g == nil {
+		return nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	out := make([]Edge, 0, len(g.edges))
+	for _, e := range g.edges {
+		c := *e
+		c.Sources = append([]Provenance(nil), e.Sources...)
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return edgeKey(out[i].From, out[i].Kind, out[i].To) < edgeKey(out[j].From, out[j].Kind, out[j].To)
+	})
+	return out
+}
+
+// Out returns the nodes id points to over kind edges ("" for any kind),
+// e.g. Out(subdomain, EdgeResolvesTo) lists its IPs.
+func (g *AssetGraph) Out(id string, kind EdgeKind) []Node {
+	return g.neighbors(id, kind, true)
+}
+
+// In returns the nodes pointing to id over kind edges ("" for any kind),
+// e.g. In(ip, EdgeResolvesTo) lists the names that resolve to it.
+func (g *AssetGraph) In(id string, kind EdgeKind) []Node {
+	return g.neighbors(id, kind, false)
+}
+
+func (g *AssetGraph) neighbors(id string, kind EdgeKind, out bool) []Node {
+	if g == nil {
+		return nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var nodes []Node
+	for _, e := range g.edges {
+		if kind != "" && e.Kind != kind {
+			continue
+		}
+		from, to := e.From, e.To
+		if !out {
+			from, to = to, from
+		}
+		if from == id {
+			nodes = append(nodes, g.nodes[to].clone())
+		}
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// Counts tallies nodes per kind.
+func (g *AssetGraph) Counts() map[NodeKind]int {
+	counts := make(map[NodeKind]int)
+	if g == nil {
+		return counts
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, n := range g.nodes {
+		counts[n.Kind]++
+	}
+	return counts
+}
+
+// Merge copies other's nodes and edges into g, keeping both provenances.
+func (g *AssetGraph) Merge(other *AssetGraph) {
+	if g == nil || other == nil || g == other {
+		return
+	}
+	for _, n := range other.Nodes() {
+		for _, p := range n.Sources {
+			g.AddNode(n.Kind, n.Value, n.Attrs, p)
+		}
+	}
+	for _, e := range other.Edges() {
+		for _, p := range e.Sources {
+			g.AddEdge(e.From, e.Kind, e.To, p)
+		}
+	}
+}
+
+// Without returns a copy of g that drops what only the named modules
+// reported: their provenance is removed, and nodes and edges left without
+// any source disappear along with the edges that touch them.
+func (g *AssetGraph) Without(modules ...string) *AssetGraph {
+	out := NewAssetGraph()
+	keep := func(sources []Provenance) []Provenance {
+		var kept []Provenance
+		for _, p := range sources {
+			if !containsString(modules, p.Module) {
+				kept = append(kept, p)
+			}
+		}
+		return kept
+	}
+	for _, n := range g.Nodes() {
+		if n.Sources = keep(n.Sources); len(n.Sources) > 0 {
+			out.nodes[n.ID] = &n
+		}
+	}
+	for _, e := range g.Edges() {
+		_, from := out.nodes[e.From]
+		_, to := out.nodes[e.To]
+		if e.Sources = keep(e.Sources); len(e.Sources) > 0 && from && to {
+			out.edges[edgeKey(e.From, e.Kind, e.To)] = &e
+		}
+	}
+	return out
+}
+
+// assetGraphJSON is the serialized form of an AssetGraph.
+type assetGraphJSON struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// MarshalJSON encodes the graph as sorted node and edge lists.
+func (g *AssetGraph) MarshalJSON() ([]byte, error) {
+	out := assetGraphJSON{Nodes: g.Nodes(), Edges: g.Edges()}
+	if out.Nodes == nil {
+		out.Nodes = []Node{}
+	}
+	if out.Edges == nil {
+		out.Edges = []Edge{}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON replaces the graph's contents.
+func (g *AssetGraph) UnmarshalJSON(data []byte) error {
+	var in assetGraphJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	nodes := make(map[string]*Node, len(in.Nodes))
+	for i := range in.Nodes {
+		n := in.Nodes[i]
+		nodes[n.ID] = &n
+	}
+	edges := make(map[string]*Edge, len(in.Edges))
+	for i := range in.Edges {
+		e := in.Edges[i]
+		edges[edgeKey(e.From, e.Kind, e.To)] = &e
+	}
+	g.mu.Lock()
+	g.nodes, g.edges = nodes, edges
+	g.mu.Unlock()
+	return nil
+}
+
+func (n *Node) clone() Node {
+	c := *n
+	if n.Attrs != nil {
+		c.Attrs = make(map[string]string, len(n.Attrs))
+		for k, v := range n.Attrs {
+			c.Attrs[k] = v
+		}
+	}
+	c.Sources = append([]Provenance(nil), n.Sources...)
+	return c
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Kind != nodes[j].Kind {
+			return kindRank(nodes[i].Kind) < kindRank(nodes[j].Kind)
+		}
+		return nodes[i].Value < nodes[j].Value
+	})
+}
+
+func kindRank(kind NodeKind) int {
+	for i, k := range NodeKinds {
+		if k == kind {
+			return i
+		}
+	}
+	return len(NodeKinds)
+}
+
+func containsKind(kinds []NodeKind, kind NodeKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// AssetWriter adds assets to a scan's graph on behalf of one module,
+// stamping provenance and announcing new nodes on the event bus.
+type AssetWriter struct {
+	graph  *AssetGraph
+	log    *Logger
+	module string
+	source string
+	apex   string // the scan target's host; other names are subdomains
+}
+
+// AssetWriter returns a writer for module's discoveries.
+func (c *Context) AssetWriter(module string) *AssetWriter {
+	if c == nil {
+		return &AssetWriter{module: module}
+	}
+	w := &AssetWriter{graph: c.Assets, log: c.Logger(module), module: module}
+	if targets, err := ParseTarget(c.Target); err == nil && len(targets) == 1 {
+		w.apex = targets[0].Host
+	}
+	return w
+}
+
+// From returns a copy of w that credits source (e.g. "crtsh").
+func (w *AssetWriter) From(source string) *AssetWriter {
+	c := *w
+	c.source = source
+	return &c
+}
+
+// Add records a node and returns its ID.
+func (w *AssetWriter) Add(kind NodeKind, value string, attrs map[string]string) string {
+	now := time.Now()
+	id, added := w.graph.AddNode(kind, value, attrs, Provenance{Module: w.module, Source: w.source, First: now, Last: now})
+	if added && kind != NodeFinding {
+		w.log.Asset(string(kind), displayValue(kind, value))
+	}
+	return id
+}
+
+// Link records an edge between two recorded nodes.
+func (w *AssetWriter) Link(from string, kind EdgeKind, to string) {
+	now := time.Now()
+	w.graph.AddEdge(from, kind, to, Provenance{Module: w.module, Source: w.source, First: now, Last: now})
+}
+
+// Host records a host name or IP. The scan target's own name is the
+// domain; other names are subdomains, linked to it when they sit below it.
+func (w *AssetWriter) Host(host string, attrs map[string]string) string {
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(strings.TrimSpace(host), "[]"), "."))
+	if _, err := netip.ParseAddr(host); err == nil {
+		return w.Add(NodeIP, host, attrs)
+	}
+	if host == w.apex {
+		return w.Add(NodeDomain, host, attrs)
+	}
+	id := w.Add(NodeSubdomain, host, attrs)
+	if w.apex != "" && strings.HasSuffix(host, "."+w.apex) {
+		// Only credit the domain when this is the first we hear of it
+		domain := NodeID(NodeDomain, w.apex)
+		if _, ok := w.graph.Node(domain); !ok {
+			domain = w.Add(NodeDomain, w.apex, nil)
+		}
+		w.Link(id, EdgeSubdomainOf, domain)
+	}
+	return id
+}
+
+// Resolves records that host resolves to each of ips.
+func (w *AssetWriter) Resolves(host string, ips []string) string {
+	id := w.Host(host, nil)
+	for _, ip := range ips {
+		if _, err := netip.ParseAddr(strings.TrimSpace(ip)); err != nil {
+			continue
+		}
+		w.Link(id, EdgeResolvesTo, w.Add(NodeIP, ip, nil))
+	}
+	return id
+}
+
+// Service records an open port on host.
+func (w *AssetWriter) Service(host string, port int, attrs map[string]string) string {
+	hostID := w.Host(host, nil)
+	id := w.Add(NodeService, net.JoinHostPort(normalizeValue(NodeSubdomain, host), strconv.Itoa(port)), withAttr(attrs, "port", strconv.Itoa(port)))
+	w.Link(hostID, EdgeHosts, id)
+	return id
+}
+
+// URL records a web resource along with the service serving it.
+func (w *AssetWriter) URL(raw string, attrs map[string]string) string {
+	id := w.Add(NodeURL, raw, attrs)
+	if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+		port, _ := strconv.Atoi(u.Port())
+		if port == 0 {
+			port = 80
+			if u.Scheme == "https" {
+				port = 443
+			}
+		}
+		w.Link(w.Service(u.Hostname(), port, nil), EdgeServes, id)
+	}
+	return id
+}
+
+// Technology records that the web resource urlID runs tech.
+func (w *AssetWriter) Technology(urlID, tech string, attrs map[string]string) string {
+	id := w.Add(NodeTechnology, tech, attrs)
+	w.Link(urlID, EdgeRuns, id)
+	return id
+}
+
+// Finding records a finding and the asset it affects.
+func (w *AssetWriter) Finding(f Finding) string {
+	id := w.Add(NodeFinding, f.ID, map[string]string{
+		"title":    f.Title,
+		"severity": string(f.Severity),
+		"module":   f.Module,
+	})
+	var asset string
+	switch {
+	case f.Asset.URL != "":
+		asset = w.URL(f.Asset.URL, nil)
+	case f.Asset.Port != 0 && f.Asset.Host != "":
+		asset = w.Service(f.Asset.Host, f.Asset.Port, nil)
+	case f.Asset.Host != "":
+		asset = w.Host(f.Asset.Host, nil)
+	default:
+		return id
+	}
+	w.Link(id, EdgeAffects, asset)
+	return id
+}
+
+func withAttr(attrs map[string]string, k, v string) map[string]string {
+	out := make(map[string]string, len(attrs)+1)
+	for key, val := range attrs {
+		out[key] = val
+	}
+	out[k] = v
+	return out
+}
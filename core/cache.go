@@ -0,0 +1,213 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultCachePath holds the third-party lookup cache.
+const DefaultCachePath = ".triksha/cache.db"
+
+// DefaultCacheTTL applies to sources without a configured TTL.
+const DefaultCacheTTL = 24 * time.Hour
+
+// DefaultCacheTTLs are the built-in per-source lifetimes.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"whois":        7 * 24 * time.Hour,
+	"crtsh":        24 * time.Hour,
+	"hackertarget": 24 * time.Hour,
+	"subfinder":    24 * time.Hour,
+}
+
+// ErrCacheMiss is returned in cache-only mode when nothing fresh is cached.
+var ErrCacheMiss = errors.New("not in cache")
+
+// CacheMode selects how lookups use the cache.
+type CacheMode string
+
+const (
+	CacheOn      CacheMode = "on"      // serve fresh entries, fetch and store the rest
+	CacheOnly    CacheMode = "only"    // never query sources; misses fail with ErrCacheMiss
+	CacheRefresh CacheMode = "refresh" // always query sources and overwrite entries
+	CacheOff     CacheMode = "off"     // bypass the cache entirely
+)
+
+// ParseCacheMode accepts on, only, refresh and off.
+func ParseCacheMode(s string) (CacheMode, error) {
+	switch mode := CacheMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case CacheOn, CacheOnly, CacheRefresh, CacheOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown cache mode %q (want on, only, refresh or off)", s)
+}
+
+// CacheConfig configures the lookup cache.
+type CacheConfig struct {
+	Path string `json:"path,omitempty"`
+	// TTLs overrides DefaultCacheTTLs per source, e.g. {"crtsh": "12h"}.
+	TTLs map[string]string `json:"ttls,omitempty"`
+}
+
+// TTLMap parses TTLs on top of DefaultCacheTTLs.
+func (c CacheConfig) TTLMap() (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs)+len(c.TTLs))
+	for source, ttl := range DefaultCacheTTLs {
+		ttls[source] = ttl
+	}
+	for source, raw := range c.TTLs {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache ttl for %s: %v", source, err)
+		}
+		ttls[source] = d
+	}
+	return ttls, nil
+}
+
+// Cache persists third-party lookups (WHOIS, crt.sh, ...) keyed by source
+// and query, so repeat scans and agent retries reuse them. A nil *Cache
+// always fetches.
+type Cache struct {
+	db   *bolt.DB
+	mode CacheMode
+	ttls map[string]time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// cacheEntry is the stored form of one lookup.
+type cacheEntry struct {
+	Stored time.Time       `json:"stored"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// OpenCache opens (or creates) the cache database at path.
+func OpenCache(path string, mode CacheMode, ttls map[string]time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open cache %s: %v", path, err)
+	}
+	return &Cache{db: db, mode: mode, ttls: ttls, locks: make(map[string]*sync.Mutex)}, nil
+}
+
+// Close releases the database.
+func (c *Cache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// Mode returns the cache mode; a nil cache is off.
+func (c *Cache) Mode() CacheMode {
+	if c == nil {
+		return CacheOff
+	}
+	return c.mode
+}
+
+func (c *Cache) ttl(source string) time.Duration {
+	if ttl, ok := c.ttls[source]; ok {
+		return ttl
+	}
+	return DefaultCacheTTL
+}
+
+// Get decodes the entry for source and query into v. It reports false if
+// there is none or it is older than the source's TTL.
+func (c *Cache) Get(source, query string, v interface{}) (bool, error) {
+	var entry cacheEntry
+	found := false
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(source))
+		if b == nil {
+			return nil
+		}
+		raw := b.Get(cacheKey(query))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(raw, &entry)
+	})
+	if err != nil || !found || time.Since(entry.Stored) > c.ttl(source) {
+		return false, err
+	}
+	return true, json.Unmarshal(entry.Value, v)
+}
+
+// Put stores v for source and query.
+func (c *Cache) Put(source, query string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(cacheEntry{Stored: time.Now(), Value: value})
+	if err != nil {
+		return err
+	}
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(source))
+		if err != nil {
+			return err
+		}
+		return b.Put(cacheKey(query), raw)
+	})
+}
+
+// lock serializes lookups of one key, so parallel modules asking for the
+// same domain query the source only once.
+func (c *Cache) lock(source, query string) func() {
+	key := source + "\x00" + string(cacheKey(query))
+	c.mu.Lock()
+	l, ok := c.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		c.locks[key] = l
+	}
+	c.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+func cacheKey(query string) []byte {
+	return []byte(strings.ToLower(strings.TrimSpace(query)))
+}
+
+// Cached returns the cached value for source and query, or calls fetch and
+// stores its result, following the cache's mode. Failed fetches are not
+// cached.
+func Cached[T any](c *Cache, source, query string, fetch func() (T, error)) (T, error) {
+	if c.Mode() == CacheOff {
+		return fetch()
+	}
+	defer c.lock(source, query)()
+
+	var value T
+	if c.mode != CacheRefresh {
+		if ok, err := c.Get(source, query, &value); err == nil && ok {
+			return value, nil
+		}
+		if c.mode == CacheOnly {
+			return value, fmt.Errorf("%s %s: %w", source, query, ErrCacheMiss)
+		}
+	}
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	c.Put(source, query, value)
+	return value, nil
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"os/exec"
+	"sort"
+)
+
+// Category groups modules by how they interact with the target.
+type Category string
+
+const (
+	CategoryPassive Category = "passive" // third-party sources only, no traffic to the target
+	CategoryActive  Category = "active"  // sends traffic to the target
+	CategoryReport  Category = "report"  // summarizes earlier results
+)
+
+// Noise estimates how visible a module is to the target's monitoring.
+type Noise string
+
+const (
+	NoiseNone   Noise = "none"
+	NoiseLow    Noise = "low"
+	NoiseMedium Noise = "medium"
+	NoiseHigh   Noise = "high"
+)
+
+// DefaultModuleOrder places modules without an explicit order after the
+// built-in recon steps and before the report.
+const DefaultModuleOrder = 60
+
+// ModuleInfo is the catalog metadata a module publishes about itself.
+type ModuleInfo struct {
+	Description string   `json:"description"`
+	Category    Category `json:"category,omitempty"`
+	Noise       Noise    `json:"noise,omitempty"`
+	Binaries    []string `json:"binaries,omitempty"` // external tools the module runs when present
+	Order       int      `json:"order"`              // position in the default agent order, lowest first
+	MaxRuns     int      `json:"max_runs,omitempty"` // agent execution limit, 0 means DefaultMaxExecutions
+	Manual      bool     `json:"manual,omitempty"`   // only runs when named explicitly, never picked by agents
+}
+
+// Describer is implemented by modules that publish catalog metadata.
+type Describer interface {
+	Info() ModuleInfo
+}
+
+// CatalogEntry is everything known about one registered module.
+type CatalogEntry struct {
+	Name string `json:"name"`
+	ModuleInfo
+	Params   []ParamSpec `json:"params,omitempty"`
+	Consumes []string    `json:"consumes,omitempty"`
+	Produces []string    `json:"produces,omitempty"`
+	Missing  []string    `json:"missing_binaries,omitempty"` // Binaries not found in PATH
+}
+
+// Limit returns the agent execution limit for the module.
+func (c CatalogEntry) Limit() int {
+	if c.MaxRuns > 0 {
+		return c.MaxRuns
+	}
+	return DefaultMaxExecutions
+}
+
+// Catalog describes every registered module in default agent order
+// (Order, then name).
+func (e *Engine) Catalog() []CatalogEntry {
+	entries := make([]CatalogEntry, 0, len(e.modules))
+	for name := range e.modules {
+		entry, _ := e.Describe(name)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Order != entries[j].Order {
+			return entries[i].Order < entries[j].Order
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Describe returns the catalog entry of the module called name.
+func (e *Engine) Describe(name string) (CatalogEntry, bool) {
+	mod, ok := e.modules[name]
+	if !ok {
+		return CatalogEntry{}, false
+	}
+	entry := CatalogEntry{Name: name}
+	if d, ok := mod.(Describer); ok {
+		entry.ModuleInfo = d.Info()
+	}
+	if entry.Order == 0 {
+		entry.Order = DefaultModuleOrder
+	}
+	if p, ok := mod.(Parameterized); ok {
+		entry.Params = p.Params()
+	}
+	if dep, ok := mod.(Dependent); ok {
+		entry.Consumes = dep.Consumes()
+		entry.Produces = dep.Produces()
+	}
+	for _, bin := range entry.Binaries {
+		if _, err := exec.LookPath(bin); err != nil {
+			entry.Missing = append(entry.Missing, bin)
+		}
+	}
+	return entry, true
+}
+
+// AgentModules returns the catalog entries agents may pick, in order.
+func AgentModules(catalog []CatalogEntry) []CatalogEntry {
+	var out []CatalogEntry
+	for _, entry := range catalog {
+		if !entry.Manual {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
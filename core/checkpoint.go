@@ -0,0 +1,173 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultStateDir is where scan state files are kept.
+const DefaultStateDir = ".triksha/scans"
+
+// StatefulAgent is implemented by agents whose decisions depend on counters
+// that must survive a resume (e.g. LLMAgent's ModuleExecutions).
+type StatefulAgent interface {
+	ExecutionCounts() map[string]int
+	RestoreExecutionCounts(counts map[string]int)
+}
+
+// ScanState is the on-disk form of a scan in progress.
+type ScanState struct {
+	ID      string                  `json:"id"`
+	Started time.Time               `json:"started"`
+	Updated time.Time               `json:"updated"`
+	Config  Config                  `json:"config"`
+	Graph   bool                    `json:"graph,omitempty"`
+	Targets []string                `json:"targets"`
+	Scans   map[string]*TargetState `json:"scans"`
+}
+
+// TargetState is the saved progress for one target.
+type TargetState struct {
+	Results    []Result       `json:"results"`
+	Store      *Store         `json:"store"`
+	Assets     *AssetGraph    `json:"assets,omitempty"`
+	Executions map[string]int `json:"executions,omitempty"`
+	Done       bool           `json:"done"`
+}
+
+// Checkpoint persists a ScanState after every completed step.
+type Checkpoint struct {
+	path  string
+	mu    sync.Mutex
+	state ScanState
+}
+
+// NewCheckpoint starts a new scan state file in dir.
+func NewCheckpoint(dir string, cfg Config, targets []Target, graph bool) (*Checkpoint, error) {
+	// State files hold the engagement's config, so only the owner may read them
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	id, err := NewScanID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c := &Checkpoint{
+		path: filepath.Join(dir, id+".json"),
+		state: ScanState{
+			ID:      id,
+			Started: now,
+			Updated: now,
+			Config:  cfg,
+			Graph:   graph,
+			Scans:   make(map[string]*TargetState),
+		},
+	}
+	for _, t := range targets {
+		c.state.Targets = append(c.state.Targets, t.String())
+	}
+	return c, c.write()
+}
+
+// LoadCheckpoint reopens the state file of an earlier scan.
+func LoadCheckpoint(dir, id string) (*Checkpoint, error) {
+	path := filepath.Join(dir, filepath.Base(id)+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s not found: %v", id, err)
+	}
+	c := &Checkpoint{path: path}
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("corrupt scan state %s: %v", path, err)
+	}
+	if c.state.Scans == nil {
+		c.state.Scans = make(map[string]*TargetState)
+	}
+	return c, nil
+}
+
+// ID returns the scan ID used with -resume.
+func (c *Checkpoint) ID() string { return c.state.ID }
+
+// Path returns the state file location.
+func (c *Checkpoint) Path() string { return c.path }
+
+// State returns the saved scan configuration and targets.
+func (c *Checkpoint) State() ScanState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Restore loads a target's saved progress into rctx and returns the saved
+// results and agent counters. ok is false when nothing was saved.
+func (c *Checkpoint) Restore(target string, rctx *Context) (ts TargetState, ok bool) {
+	if c == nil {
+		return TargetState{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	saved, ok := c.state.Scans[target]
+	if !ok {
+		return TargetState{}, false
+	}
+	if saved.Store != nil {
+		rctx.Store = saved.Store
+	}
+	if saved.Assets != nil {
+		rctx.Assets = saved.Assets
+	}
+	return *saved, true
+}
+
+// Save records a target's progress and rewrites the state file.
+func (c *Checkpoint) Save(target string, rctx *Context, results []Result, executions map[string]int, done bool) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Scans[target] = &TargetState{
+		Results:    append([]Result(nil), results...),
+		Store:      rctx.Store,
+		Assets:     rctx.Assets,
+		Executions: executions,
+		Done:       done,
+	}
+	c.state.Updated = time.Now()
+	return c.write()
+}
+
+// write replaces the state file atomically so a crash never leaves it
+// half-written. Callers hold c.mu (or own c exclusively).
+func (c *Checkpoint) write() error {
+	data, err := json.MarshalIndent(&c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a tmp file left by an older version
+	if err := os.Chmod(tmp, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// NewScanID returns a time-ordered ID such as "20250101-120000-a1b2c3".
+func NewScanID() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b), nil
+}
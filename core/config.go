@@ -1,38 +1,214 @@
 package core
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
+	"time"
 )
 
 // Config represents user or system config.
 type Config struct {
 	Target  string                 `json:"target"`
-	Modules []string               `json:"modules"` // If empty, run all in default order.
-	ApiKeys map[string]string      `json:"api_keys,omitempty"`
-	Other   map[string]interface{} `json:"other,omitempty"`
+	Targets []string               `json:"targets,omitempty"`  // Extra targets: domains, hosts, host:port, URLs, IPs or CIDRs.
+	Modules []string               `json:"modules"`            // If empty, run all in default order.
+	ApiKeys map[string]string      `json:"api_keys,omitempty"` // By service, e.g. "openai"; modules read them via Context.APIKey.
+	Other   map[string]interface{} `json:"other,omitempty"`    // Free-form settings handed to plugins.
+
+	// Profile names the profile the config was built with.
+	Profile string `json:"profile,omitempty"`
+	// LLM configures the AI agent.
+	LLM LLMConfig `json:"llm,omitempty"`
+	// ModuleConfig groups settings per module name; they are folded into
+	// Timeouts, Params and Retry when the config is loaded.
+	ModuleConfig map[string]ModuleConfig `json:"module_config,omitempty"`
+
+	// DefaultTimeout bounds every module run (e.g. "10m"). "0" disables it.
+	DefaultTimeout string `json:"default_timeout,omitempty"`
+	// Timeouts overrides DefaultTimeout per module name.
+	Timeouts map[string]string `json:"timeouts,omitempty"`
+	// Workers caps how many modules the scheduler runs at once.
+	Workers int `json:"workers,omitempty"`
+	// TargetConcurrency caps how many targets are scanned at once.
+	TargetConcurrency int `json:"target_concurrency,omitempty"`
+	// Params sets module parameters, keyed by module then parameter name.
+	Params map[string]map[string]interface{} `json:"params,omitempty"`
+	// AllowedParams lists path parameters, as "module.name", that the LLM
+	// agent and API requests may set; others are dropped.
+	AllowedParams []string `json:"allowed_params,omitempty"`
+	// WordlistDir is where path parameters resolve (default "wordlists").
+	WordlistDir string `json:"wordlist_dir,omitempty"`
+	// Scope holds the rules of engagement; empty allows every asset.
+	Scope ScopeConfig `json:"scope,omitempty"`
+	// RateProfile names a preset from RateProfiles (default "normal").
+	RateProfile string `json:"rate_profile,omitempty"`
+	// RateLimits overrides single profile values; a negative value turns
+	// that limit off.
+	RateLimits RateLimits `json:"rate_limits,omitempty"`
+	// HTTP configures proxy, headers, TLS and redirects for all modules.
+	HTTP HTTPConfig `json:"http,omitempty"`
+	// Cache configures the third-party lookup cache.
+	Cache CacheConfig `json:"cache,omitempty"`
+	// Retry configures backoff for module runs and data sources.
+	Retry RetryConfig `json:"retry,omitempty"`
+	// Monitor schedules rescans in monitor mode.
+	Monitor MonitorConfig `json:"monitor,omitempty"`
+	// Notify sends scan events and findings to webhooks, chat and email.
+	Notify NotifyConfig `json:"notify,omitempty"`
+	// Server configures the API started by "triksha serve".
+	Server ServerConfig `json:"server,omitempty"`
+}
+
+// LLMConfig selects and configures the LLM behind the AI agent. The
+// OpenAI key is read from ApiKeys["openai"].
+type LLMConfig struct {
+	Enabled     bool   `json:"enabled,omitempty"`
+	OpenAIModel string `json:"openai_model,omitempty"`
+	OllamaURL   string `json:"ollama_url,omitempty"`
+	OllamaModel string `json:"ollama_model,omitempty"`
+}
+
+// ModuleConfig is the per-module section of a config file.
+type ModuleConfig struct {
+	Timeout  string                 `json:"timeout,omitempty"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+	Retry    *RetrySpec             `json:"retry,omitempty"`
+	Schedule string                 `json:"schedule,omitempty"` // see MonitorConfig.Schedules
+}
+
+// MonitorConfig configures monitor mode.
+type MonitorConfig struct {
+	// Schedules maps module names to a cron expression or descriptor
+	// (see ParseSchedule), e.g. {"passive": "@every 6h"}. The name
+	// MonitorAgent schedules a full agent-driven scan.
+	Schedules map[string]string `json:"schedules,omitempty"`
+	// SkipInitial waits for the first scheduled time instead of running
+	// every module once at startup.
+	SkipInitial bool `json:"skip_initial,omitempty"`
+}
+
+// ServerConfig configures the API server.
+type ServerConfig struct {
+	// Listen is the address to serve on (default DefaultListen).
+	Listen string `json:"listen,omitempty"`
+	// Tokens are the accepted API tokens, sent as "Authorization: Bearer
+	// <token>". The server refuses to start without one.
+	Tokens []string `json:"tokens,omitempty"`
+	// Workers caps how many submitted scans run at once (default 2).
+	Workers int `json:"workers,omitempty"`
+	// QueueSize caps how many scans may wait for a worker (default 50).
+	QueueSize int `json:"queue_size,omitempty"`
 }
 
-// LoadConfig loads config from a JSON file.
+// DefaultListen is the API server's default address.
+const DefaultListen = "127.0.0.1:8686"
+
+// LoadConfig loads config from a single YAML or JSON file, without the
+// other layers. Profiles in the file are ignored.
 func LoadConfig(path string) (Config, error) {
-	file, err := os.Open(path)
+	m, err := readConfigMap(path)
 	if err != nil {
 		return Config{}, err
 	}
-	defer file.Close()
-	var cfg Config
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&cfg); err != nil {
-		return Config{}, err
-	}
-	return cfg, nil
+	delete(m, "profiles")
+	return decodeConfig(m)
 }
 
 // ValidateConfig checks config for basic errors.
 func ValidateConfig(cfg Config) error {
-	if cfg.Target == "" {
+	if cfg.Target == "" && len(cfg.Targets) == 0 {
 		return fmt.Errorf("target is required")
 	}
+	if _, _, err := cfg.ModuleTimeouts(); err != nil {
+		return err
+	}
+	if _, err := NewScope(cfg.Scope); err != nil {
+		return err
+	}
+	if _, err := cfg.Limits(); err != nil {
+		return err
+	}
+	if _, err := NewHTTPClientFactory(cfg.HTTP, nil); err != nil {
+		return err
+	}
+	if _, err := cfg.Cache.TTLMap(); err != nil {
+		return err
+	}
+	if _, err := cfg.Retry.Policies(); err != nil {
+		return err
+	}
+	for name, spec := range cfg.Monitor.Schedules {
+		if _, err := ParseSchedule(spec); err != nil {
+			return fmt.Errorf("monitor schedule for %s: %v", name, err)
+		}
+	}
+	if _, err := cfg.Notify.channels(); err != nil {
+		return err
+	}
 	return nil
 }
+
+// Limits resolves RateProfile and applies the RateLimits overrides.
+func (c Config) Limits() (RateLimits, error) {
+	name := c.RateProfile
+	if name == "" {
+		name = DefaultRateProfile
+	}
+	limits, ok := RateProfiles[name]
+	if !ok {
+		return RateLimits{}, fmt.Errorf("unknown rate_profile %q (want %s)", name, strings.Join(RateProfileNames(), ", "))
+	}
+	override := func(dst *float64, v float64) {
+		if v > 0 {
+			*dst = v
+		} else if v < 0 {
+			*dst = 0
+		}
+	}
+	o := c.RateLimits
+	override(&limits.PerHost, o.PerHost)
+	override(&limits.Global, o.Global)
+	override(&limits.HTTP, o.HTTP)
+	override(&limits.DNS, o.DNS)
+	if o.Connections > 0 {
+		limits.Connections = o.Connections
+	} else if o.Connections < 0 {
+		limits.Connections = 0
+	}
+	return limits, nil
+}
+
+// ModuleTimeouts parses the configured module deadlines.
+// The default falls back to DefaultModuleTimeout when unset.
+func (c Config) ModuleTimeouts() (time.Duration, map[string]time.Duration, error) {
+	def := DefaultModuleTimeout
+	if c.DefaultTimeout != "" {
+		d, err := time.ParseDuration(c.DefaultTimeout)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid default_timeout %q: %v", c.DefaultTimeout, err)
+		}
+		def = d
+	}
+	perModule := make(map[string]time.Duration, len(c.Timeouts))
+	for name, raw := range c.Timeouts {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid timeout for module %s: %v", name, err)
+		}
+		perModule[name] = d
+	}
+	return def, perModule, nil
+}
+
+// ParseTargets normalizes Target and Targets into a deduplicated list.
+func (c Config) ParseTargets() ([]Target, error) {
+	var raw []string
+	if c.Target != "" {
+		raw = append(raw, c.Target)
+	}
+	raw = append(raw, c.Targets...)
+	targets, errs := ReadTargets(strings.NewReader(strings.Join(raw, "\n")))
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return targets, nil
+}
@@ -0,0 +1,506 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts every environment setting. Nested keys are joined with
+// "__", e.g. TRIKSHA_HTTP__PROXY or TRIKSHA_API_KEYS__SHODAN.
+const EnvPrefix = "TRIKSHA_"
+
+// ProjectConfigFiles are looked up in the working directory when no
+// config file is given.
+var ProjectConfigFiles = []string{"triksha.yaml", "triksha.yml", "triksha.json"}
+
+// GlobalConfigPath returns the user-wide config file, e.g.
+// ~/.config/triksha/config.yaml. It is empty if no such file exists.
+func GlobalConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range []string{"config.yaml", "config.yml", "config.json"} {
+		path := filepath.Join(dir, "triksha", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// DefaultConfig holds the built-in settings every layer starts from.
+func DefaultConfig() Config {
+	return Config{
+		RateProfile: DefaultRateProfile,
+		LLM: LLMConfig{
+			OpenAIModel: "gpt-3.5-turbo",
+			OllamaModel: "gemma:2b",
+		},
+	}
+}
+
+// ConfigLoader merges the config layers: built-in defaults, the global
+// file, the project file, the selected profile, then TRIKSHA_* variables.
+// CLI flags are applied by the caller on top of the result.
+type ConfigLoader struct {
+	// GlobalPath overrides GlobalConfigPath; "-" skips the global file.
+	GlobalPath string
+	// ProjectPath is the -config file; empty looks for ProjectConfigFiles
+	// (or TRIKSHA_CONFIG).
+	ProjectPath string
+	// Profile selects a profile, overriding TRIKSHA_PROFILE and the
+	// files' "profile" key.
+	Profile string
+	// Env is the environment, as from os.Environ.
+	Env []string
+}
+
+// Load returns the merged config and the layers it was built from, in
+// the order they were applied.
+func (l ConfigLoader) Load() (Config, []string, error) {
+	env := make(map[string]string)
+	for _, kv := range l.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, EnvPrefix) {
+			env[k] = v
+		}
+	}
+
+	merged, err := configMap(DefaultConfig())
+	if err != nil {
+		return Config{}, nil, err
+	}
+	sources := []string{"defaults"}
+	profiles := make(map[string]interface{})
+	apply := func(path string) error {
+		layer, err := readConfigMap(path)
+		if err != nil {
+			return err
+		}
+		if p, ok := layer["profiles"].(map[string]interface{}); ok {
+			mergeMaps(profiles, p)
+		}
+		delete(layer, "profiles")
+		mergeMaps(merged, layer)
+		sources = append(sources, path)
+		return nil
+	}
+
+	global := l.GlobalPath
+	if global == "" {
+		global = GlobalConfigPath()
+	}
+	if global != "" && global != "-" {
+		if err := apply(global); err != nil {
+			return Config{}, nil, err
+		}
+	}
+	project := l.ProjectPath
+	if project == "" {
+		project = env[EnvPrefix+"CONFIG"]
+	}
+	if project == "" {
+		for _, name := range ProjectConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				project = name
+				break
+			}
+		}
+	}
+	if project != "" {
+		if err := apply(project); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	profile := l.Profile
+	if profile == "" {
+		profile = env[EnvPrefix+"PROFILE"]
+	}
+	if profile == "" {
+		profile, _ = merged["profile"].(string)
+	}
+	if profile != "" {
+		overlay, ok := profiles[profile].(map[string]interface{})
+		if !ok {
+			return Config{}, nil, fmt.Errorf("unknown profile %q (have %s)", profile, strings.Join(sortedNames(profiles), ", "))
+		}
+		mergeMaps(merged, overlay)
+		merged["profile"] = profile
+		sources = append(sources, "profile "+profile)
+	}
+
+	var envKeys []string
+	for k := range env {
+		switch k {
+		case EnvPrefix + "CONFIG", EnvPrefix + "PROFILE":
+			continue
+		}
+		envKeys = append(envKeys, k)
+	}
+	sort.Strings(envKeys)
+	for _, k := range envKeys {
+		path := strings.Split(strings.ToLower(strings.TrimPrefix(k, EnvPrefix)), "__")
+		value, err := envValue(path, env[k])
+		if err != nil {
+			return Config{}, nil, fmt.Errorf("%s: %v", k, err)
+		}
+		setPath(merged, path, value)
+	}
+	if len(envKeys) > 0 {
+		sources = append(sources, "environment")
+	}
+
+	cfg, err := decodeConfig(merged)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, sources, nil
+}
+
+// readConfigMap parses a YAML or JSON config file into a generic map.
+func readConfigMap(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	default:
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// decodeConfig turns a merged map into a Config, rejecting unknown keys,
+// and folds the per-module sections into the flat settings.
+func decodeConfig(m map[string]interface{}) (Config, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid config: %v", err)
+	}
+	cfg.foldModuleConfig()
+	return cfg, nil
+}
+
+// foldModuleConfig copies each module_config section into Timeouts, Params,
+// Retry.Modules and Monitor.Schedules, where the rest of the scan reads them.
+func (c *Config) foldModuleConfig() {
+	for name, mc := range c.ModuleConfig {
+		if mc.Timeout != "" {
+			if c.Timeouts == nil {
+				c.Timeouts = make(map[string]string)
+			}
+			c.Timeouts[name] = mc.Timeout
+		}
+		if len(mc.Params) > 0 {
+			if c.Params == nil {
+				c.Params = make(map[string]map[string]interface{})
+			}
+			params := make(map[string]interface{}, len(c.Params[name])+len(mc.Params))
+			for k, v := range c.Params[name] {
+				params[k] = v
+			}
+			for k, v := range mc.Params {
+				params[k] = v
+			}
+			c.Params[name] = params
+		}
+		if mc.Retry != nil {
+			if c.Retry.Modules == nil {
+				c.Retry.Modules = make(map[string]RetrySpec)
+			}
+			c.Retry.Modules[name] = *mc.Retry
+		}
+		if mc.Schedule != "" {
+			if c.Monitor.Schedules == nil {
+				c.Monitor.Schedules = make(map[string]string)
+			}
+			c.Monitor.Schedules[name] = mc.Schedule
+		}
+	}
+}
+
+// configMap converts a Config into the generic form layers merge into.
+func configMap(cfg Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	return m, json.Unmarshal(data, &m)
+}
+
+// mergeMaps merges src into dst: nested maps merge key by key, any other
+// value (including lists) replaces what was there.
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if sv, ok := v.(map[string]interface{}); ok {
+			if dv, ok := dst[k].(map[string]interface{}); ok {
+				mergeMaps(dv, sv)
+				continue
+			}
+			copied := make(map[string]interface{}, len(sv))
+			mergeMaps(copied, sv)
+			v = copied
+		}
+		dst[k] = v
+	}
+}
+
+func setPath(m map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[key] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
+
+// envValue converts an environment value to the type of the setting at
+// path: lists are comma-separated, objects are YAML or JSON.
+func envValue(path []string, raw string) (interface{}, error) {
+	t, ok := settingType(reflect.TypeOf(Config{}), path)
+	if !ok {
+		return nil, fmt.Errorf("unknown setting %s", strings.Join(path, "."))
+	}
+	return parseSetting(t, raw)
+}
+
+func parseSetting(t reflect.Type, raw string) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	case reflect.Slice:
+		var list []interface{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			v, err := parseSetting(t.Elem(), item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(raw), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// settingType finds the Go type of the setting at path, following JSON
+// field names through structs and keys through maps.
+func settingType(t reflect.Type, path []string) (reflect.Type, bool) {
+	for _, key := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := jsonField(t, key)
+			if !ok {
+				return nil, false
+			}
+			t = f.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, false
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, true
+}
+
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		if tag == name || (tag == "" && strings.EqualFold(f.Name, name)) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func sortedNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Redacted returns a copy of c that is safe to print: API keys,
+// credential-bearing headers and cookies, proxy and SMTP passwords,
+// notification webhook URLs and API server tokens are masked.
+func (c Config) Redacted() Config {
+	c.ApiKeys = maskValues(c.ApiKeys, nil)
+	c.HTTP.Headers = maskValues(c.HTTP.Headers, isSecretHeader)
+	c.HTTP.Cookies = maskValues(c.HTTP.Cookies, nil)
+	c.HTTP.Proxy = maskURLPassword(c.HTTP.Proxy)
+	channels := make([]NotifyChannel, len(c.Notify.Channels))
+	for i, ch := range c.Notify.Channels {
+		ch.URL = MaskSecret(ch.URL)
+		ch.Headers = maskValues(ch.Headers, isSecretHeader)
+		ch.Password = MaskSecret(ch.Password)
+		channels[i] = ch
+	}
+	if c.Notify.Channels != nil {
+		c.Notify.Channels = channels
+	}
+	if c.Server.Tokens != nil {
+		tokens := make([]string, len(c.Server.Tokens))
+		for i, t := range c.Server.Tokens {
+			tokens[i] = MaskSecret(t)
+		}
+		c.Server.Tokens = tokens
+	}
+	return c
+}
+
+// WithoutSecrets returns a copy of c without API keys, notification
+// channels, server tokens, credential-bearing headers, cookies or the
+// proxy password, for saving in scan state files.
+func (c Config) WithoutSecrets() Config {
+	c.ApiKeys = nil
+	c.Notify.Channels = nil
+	c.Server.Tokens = nil
+	if c.HTTP.Headers != nil {
+		headers := make(map[string]string, len(c.HTTP.Headers))
+		for k, v := range c.HTTP.Headers {
+			if !isSecretHeader(k) {
+				headers[k] = v
+			}
+		}
+		c.HTTP.Headers = headers
+	}
+	c.HTTP.Cookies = nil
+	c.HTTP.Proxy = stripURLPassword(c.HTTP.Proxy)
+	return c
+}
+
+// WithSecretsFrom puts back what WithoutSecrets removed, taken from
+// layers, e.g. when a saved scan is resumed.
+func (c Config) WithSecretsFrom(layers Config) Config {
+	c.ApiKeys = layers.ApiKeys
+	c.Notify.Channels = layers.Notify.Channels
+	c.Server.Tokens = layers.Server.Tokens
+	for k, v := range layers.HTTP.Headers {
+		if isSecretHeader(k) {
+			if c.HTTP.Headers == nil {
+				c.HTTP.Headers = make(map[string]string)
+			}
+			c.HTTP.Headers[k] = v
+		}
+	}
+	c.HTTP.Cookies = layers.HTTP.Cookies
+	if stripURLPassword(layers.HTTP.Proxy) == c.HTTP.Proxy {
+		c.HTTP.Proxy = layers.HTTP.Proxy
+	}
+	return c
+}
+
+func maskValues(m map[string]string, secret func(string) bool) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if secret == nil || secret(k) {
+			v = MaskSecret(v)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func isSecretHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"authorization", "cookie", "token", "key", "secret", "password"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskURLPassword(raw string) string {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return raw
+	}
+	userinfo, host, ok := strings.Cut(rest, "@")
+	if !ok {
+		return raw
+	}
+	if user, _, ok := strings.Cut(userinfo, ":"); ok {
+		return scheme + "://" + user + ":" + MaskSecret("x") + "@" + host
+	}
+	return raw
+}
+
+// stripURLPassword drops the password from a URL's userinfo.
+func stripURLPassword(raw string) string {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return raw
+	}
+	userinfo, host, ok := strings.Cut(rest, "@")
+	if !ok {
+		return raw
+	}
+	user, _, _ := strings.Cut(userinfo, ":")
+	return scheme + "://" + user + "@" + host
+}
+
+// MaskSecret hides a secret, keeping the last four characters of long
+// values so they can still be told apart.
+func MaskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) <= 12 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Control lets an operator steer a running scan: pause the agent, skip the
+// module running on a target, queue a module to run again and hold agent
+// decisions for approval. The runner honors it between modules in agent
+// and sequential mode; a nil *Control never intervenes.
+type Control struct {
+	mu       sync.Mutex
+	paused   bool
+	resumed  chan struct{} // closed by Resume
+	approval bool
+	pending  map[string]*pendingDecision  // by target
+	running  map[string]map[string]func() // target -> module -> cancel
+	reruns   map[string][]string          // by target; "" queues for every target
+	skipped  map[string]map[string]bool   // target -> module, until it ends
+}
+
+// PendingDecision is an agent decision waiting for the operator.
+type PendingDecision struct {
+	Target string
+	Action Action
+	Since  time.Time
+}
+
+type pendingDecision struct {
+	PendingDecision
+	verdict chan bool
+}
+
+// NewControl returns a control that lets the scan run freely.
+func NewControl() *Control {
+	return &Control{
+		pending: make(map[string]*pendingDecision),
+		running: make(map[string]map[string]func()),
+		reruns:  make(map[string][]string),
+		skipped: make(map[string]map[string]bool),
+	}
+}
+
+// Pause stops the agent from starting modules; running ones finish.
+func (c *Control) Pause() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume lets a paused scan go on.
+func (c *Control) Resume() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+}
+
+// Paused reports whether the scan is paused.
+func (c *Control) Paused() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// SetApproval turns holding agent decisions for approval on or off.
+// Turning it off approves the decisions waiting.
+func (c *Control) SetApproval(on bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.approval = on
+	if !on {
+		for target, p := range c.pending {
+			p.verdict <- true
+			delete(c.pending, target)
+		}
+	}
+}
+
+// Approval reports whether agent decisions wait for approval.
+func (c *Control) Approval() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.approval
+}
+
+// Pending lists the decisions waiting for approval, oldest first.
+func (c *Control) Pending() []PendingDecision {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]PendingDecision, 0, len(c.pending))
+	for _, p := range c.pending {
+		list = append(list, p.PendingDecision)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Since.Before(list[j].Since) })
+	return list
+}
+
+// Decide approves or rejects the decision waiting on target, or the oldest
+// one when target is empty. It reports false if none was waiting.
+func (c *Control) Decide(target string, approve bool) bool {
+	if target == "" {
+		pending := c.Pending()
+		if len(pending) == 0 {
+			return false
+		}
+		target = pending[0].Target
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[target]
+	if !ok {
+		return false
+	}
+	p.verdict <- approve
+	delete(c.pending, target)
+	return true
+}
+
+// Skip stops module on target (any target when empty) if it is running,
+// and reports how many runs it stopped. The agent moves on to its next
+// decision.
+func (c *Control) Skip(target, module string) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for t, modules := range c.running {
+		if target != "" && t != target {
+			continue
+		}
+		if cancel, ok := modules[module]; ok {
+			if c.skipped[t] == nil {
+				c.skipped[t] = make(map[string]bool)
+			}
+			c.skipped[t][module] = true
+			cancel()
+			n++
+		}
+	}
+	return n
+}
+
+// Rerun queues module to run again on target (every target when empty)
+// before the agent's next decision.
+func (c *Control) Rerun(target, module string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reruns[target] = append(c.reruns[target], module)
+}
+
+// wait blocks while the scan is paused.
+func (c *Control) wait(ctx context.Context) error {
+	if c == nil {
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	paused, resumed := c.paused, c.resumed
+	c.mu.Unlock()
+	if !paused {
+		return ctx.Err()
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// nextRerun pops the next module queued to run again on target.
+func (c *Control) nextRerun(target string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range []string{target, ""} {
+		if queue := c.reruns[key]; len(queue) > 0 {
+			c.reruns[key] = queue[1:]
+			return queue[0], true
+		}
+	}
+	return "", false
+}
+
+// approve holds action for the operator when approval is on. It reports
+// whether the action may run.
+func (c *Control) approve(ctx context.Context, target string, action Action) (bool, error) {
+	if c == nil {
+		return true, ctx.Err()
+	}
+	c.mu.Lock()
+	if !c.approval {
+		c.mu.Unlock()
+		return true, ctx.Err()
+	}
+	p := &pendingDecision{
+		PendingDecision: PendingDecision{Target: target, Action: action, Since: time.Now()},
+		verdict:         make(chan bool, 1),
+	}
+	c.pending[target] = p
+	c.mu.Unlock()
+	select {
+	case ok := <-p.verdict:
+		return ok, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		if c.pending[target] == p {
+			delete(c.pending, target)
+		}
+		c.mu.Unlock()
+		return false, ctx.Err()
+	}
+}
+
+// track derives the context module runs in on target, so Skip can stop
+// it. Call the returned function when the module returns; it reports
+// whether the run was skipped.
+func (c *Control) track(ctx context.Context, target, module string) (context.Context, func() bool) {
+	if c == nil {
+		return ctx, func() bool { return false }
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	if c.running[target] == nil {
+		c.running[target] = make(map[string]func())
+	}
+	c.running[target][module] = cancel
+	c.mu.Unlock()
+	return ctx, func() bool {
+		cancel()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.running[target], module)
+		skipped := c.skipped[target][module]
+		delete(c.skipped[target], module)
+		return skipped
+	}
+}
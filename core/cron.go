@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a recurring job runs next.
+type Schedule interface {
+	// Next returns the first run time strictly after t.
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule accepts a five-field cron expression ("minute hour
+// day-of-month month day-of-week", e.g. "0 */6 * * *"), one of @hourly,
+// @daily (@midnight), @weekly, @monthly and @yearly (@annually), or
+// "@every <duration>" such as "@every 6h".
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be at least 1m", spec)
+		}
+		return every(d), nil
+	}
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: want 5 cron fields or a descriptor such as @daily", spec)
+	}
+	var c cron
+	var err error
+	bounds := []struct {
+		dst      *uint64
+		min, max int
+		name     string
+	}{
+		{&c.minute, 0, 59, "minute"},
+		{&c.hour, 0, 23, "hour"},
+		{&c.dom, 1, 31, "day of month"},
+		{&c.month, 1, 12, "month"},
+		{&c.dow, 0, 7, "day of week"},
+	}
+	for i, b := range bounds {
+		if *b.dst, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %s: %v", spec, b.name, err)
+		}
+	}
+	// Sunday is both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+// every runs at a fixed interval.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+// cron holds one bit per allowed value of each field.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+func (c cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted, either
+// may match.
+func (c cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// parseCronField parses a comma-separated list of "*", "a", "a-b", each
+// optionally with a "/step".
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepStr)
+			}
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("bad value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("bad value %q", to)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
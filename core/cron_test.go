@@ -0,0 +1,111 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     uint64
+		wantErr  bool
+	}{
+		{field: "*", min: 0, max: 5, want: bits(0, 1, 2, 3, 4, 5)},
+		{field: "7", min: 0, max: 59, want: bits(7)},
+		{field: "1-3", min: 0, max: 59, want: bits(1, 2, 3)},
+		{field: "1,5,9", min: 0, max: 59, want: bits(1, 5, 9)},
+		{field: "*/20", min: 0, max: 59, want: bits(0, 20, 40)},
+		{field: "10/20", min: 0, max: 59, want: bits(10, 30, 50)},
+		{field: "5-10/2", min: 0, max: 59, want: bits(5, 7, 9)},
+		{field: "*/5", min: 1, max: 12, want: bits(1, 6, 11)},
+		{field: "60", min: 0, max: 59, wantErr: true},
+		{field: "0", min: 1, max: 31, wantErr: true},
+		{field: "5-1", min: 0, max: 59, wantErr: true},
+		{field: "*/0", min: 0, max: 59, wantErr: true},
+		{field: "*/x", min: 0, max: 59, wantErr: true},
+		{field: "a", min: 0, max: 59, wantErr: true},
+		{field: "1-b", min: 0, max: 59, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCronField(tt.field, tt.min, tt.max)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCronField(%q) error = %v, wantErr %v", tt.field, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCronField(%q) = %b, want %b", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2026-01-01 10:07", "2026-01-01 10:15"},
+		{"*/15 * * * *", "2026-01-01 10:15", "2026-01-01 10:30"},
+		{"0 */6 * * *", "2026-01-01 07:30", "2026-01-01 12:00"},
+		{"@hourly", "2026-01-01 07:30", "2026-01-01 08:00"},
+		{"@daily", "2026-01-01 23:59", "2026-01-02 00:00"},
+		{"@monthly", "2026-01-15 12:00", "2026-02-01 00:00"},
+		{"@yearly", "2026-03-01 00:00", "2027-01-01 00:00"},
+		// 2026-01-01 is a Thursday
+		{"0 9 * * 1", "2026-01-01 00:00", "2026-01-05 09:00"},
+		{"@weekly", "2026-01-01 00:00", "2026-01-04 00:00"},
+		{"0 0 * * 7", "2026-01-01 00:00", "2026-01-04 00:00"},
+		// Day of month or day of week when both are restricted
+		{"0 0 1,15 * 1", "2026-01-02 00:00", "2026-01-05 00:00"},
+		{"0 0 1,15 * 1", "2026-01-13 00:00", "2026-01-15 00:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		{"@every 6h", "2026-01-01 10:07", "2026-01-01 16:07"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q Next(%s) = %s, want %s", tt.spec, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"@every 30s",
+		"@every soon",
+		"@fortnightly",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScanSnapshot is the outcome of a finished scan, as loaded for diffing.
+type ScanSnapshot struct {
+	ID      string      `json:"id"`
+	Time    time.Time   `json:"time"`
+	Targets []string    `json:"targets"`
+	Results []Result    `json:"-"`
+	Assets  *AssetGraph `json:"-"`
+}
+
+// LoadSnapshot loads a saved scan by scan ID (from stateDir), or from a
+// scan state file or JSON report at ref.
+func LoadSnapshot(stateDir, ref string) (ScanSnapshot, error) {
+	if _, err := os.Stat(ref); err != nil {
+		c, err := LoadCheckpoint(stateDir, ref)
+		if err != nil {
+			return ScanSnapshot{}, err
+		}
+		return c.Snapshot(), nil
+	}
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return ScanSnapshot{}, err
+	}
+	var probe struct {
+		Scans json.RawMessage `json:"scans"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return ScanSnapshot{}, fmt.Errorf("%s: %v", ref, err)
+	}
+	if probe.Scans != nil {
+		c := &Checkpoint{path: ref}
+		if err := json.Unmarshal(data, &c.state); err != nil {
+			return ScanSnapshot{}, fmt.Errorf("corrupt scan state %s: %v", ref, err)
+		}
+		return c.Snapshot(), nil
+	}
+
+	// A JSON report written with -json
+	var report struct {
+		Assets  *AssetGraph `json:"assets"`
+		Results []Result    `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return ScanSnapshot{}, fmt.Errorf("%s: %v", ref, err)
+	}
+	if report.Assets == nil {
+		return ScanSnapshot{}, fmt.Errorf("%s has no asset graph to compare", ref)
+	}
+	info, _ := os.Stat(ref)
+	snap := ScanSnapshot{ID: ref, Time: info.ModTime(), Results: report.Results, Assets: report.Assets}
+	seen := map[string]bool{}
+	for _, r := range report.Results {
+		if r.Target != "" && !seen[r.Target] {
+			seen[r.Target] = true
+			snap.Targets = append(snap.Targets, r.Target)
+		}
+	}
+	return snap, nil
+}
+
+// Snapshot merges the saved progress of every target.
+func (c *Checkpoint) Snapshot() ScanSnapshot {
+	state := c.State()
+	snap := ScanSnapshot{ID: state.ID, Time: state.Updated, Targets: state.Targets, Assets: NewAssetGraph()}
+	for _, target := range state.Targets {
+		ts, ok := state.Scans[target]
+		if !ok {
+			continue
+		}
+		snap.Results = append(snap.Results, ts.Results...)
+		snap.Assets.Merge(ts.Assets)
+	}
+	return snap
+}
+
+// SetChange lists values that appeared or disappeared between two scans.
+type SetChange struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Empty reports whether nothing changed.
+func (c SetChange) Empty() bool { return len(c.Added) == 0 && len(c.Removed) == 0 }
+
+// AttrChange is a detail of an asset present in both scans that changed,
+// e.g. a service banner after an upgrade.
+type AttrChange struct {
+	Asset string `json:"asset"`
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ScanDiff is the change set between an older and a newer scan.
+type ScanDiff struct {
+	Old          ScanSnapshot `json:"old"`
+	New          ScanSnapshot `json:"new"`
+	Subdomains   SetChange    `json:"subdomains"`
+	IPs          SetChange    `json:"ips"`
+	Ports        SetChange    `json:"ports"` // host:port
+	Services     []AttrChange `json:"services,omitempty"`
+	Technologies SetChange    `json:"technologies"`
+	URLs         SetChange    `json:"urls"`
+	NewFindings  []Finding    `json:"new_findings,omitempty"`
+	Resolved     []Finding    `json:"resolved_findings,omitempty"`
+}
+
+// serviceFields are the service attributes compared between scans.
+var serviceFields = []string{"service", "banner"}
+
+// DiffScans compares two scans of the same target(s).
+func DiffScans(old, new ScanSnapshot) ScanDiff {
+	d := ScanDiff{Old: old, New: new}
+	d.Subdomains = diffNodes(old.Assets, new.Assets, NodeDomain, NodeSubdomain)
+	d.IPs = diffNodes(old.Assets, new.Assets, NodeIP)
+	d.Ports = diffNodes(old.Assets, new.Assets, NodeService)
+	d.Technologies = diffNodes(old.Assets, new.Assets, NodeTechnology)
+	d.URLs = diffNodes(old.Assets, new.Assets, NodeURL)
+
+	for _, svc := range new.Assets.Nodes(NodeService) {
+		before, ok := old.Assets.Node(svc.ID)
+		if !ok {
+			continue
+		}
+		for _, field := range serviceFields {
+			if o, n := before.Attrs[field], svc.Attrs[field]; o != n && n != "" {
+				d.Services = append(d.Services, AttrChange{Asset: svc.Value, Field: field, Old: o, New: n})
+			}
+		}
+	}
+
+	oldFindings := findingsByID(old.Results)
+	newFindings := findingsByID(new.Results)
+	for id, f := range newFindings {
+		if _, ok := oldFindings[id]; !ok {
+			d.NewFindings = append(d.NewFindings, f)
+		}
+	}
+	for id, f := range oldFindings {
+		if _, ok := newFindings[id]; !ok {
+			d.Resolved = append(d.Resolved, f)
+		}
+	}
+	SortFindings(d.NewFindings)
+	SortFindings(d.Resolved)
+	return d
+}
+
+func diffNodes(old, new *AssetGraph, kinds ...NodeKind) SetChange {
+	var c SetChange
+	for _, n := range new.Nodes(kinds...) {
+		if _, ok := old.Node(n.ID); !ok {
+			c.Added = append(c.Added, n.Value)
+		}
+	}
+	for _, n := range old.Nodes(kinds...) {
+		if _, ok := new.Node(n.ID); !ok {
+			c.Removed = append(c.Removed, n.Value)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Removed)
+	return c
+}
+
+func findingsByID(results []Result) map[string]Finding {
+	out := make(map[string]Finding)
+	for _, f := range CollectFindings(results) {
+		out[f.ID] = f
+	}
+	return out
+}
+
+// Empty reports whether the scans show the same attack surface.
+func (d ScanDiff) Empty() bool {
+	return d.Subdomains.Empty() && d.IPs.Empty() && d.Ports.Empty() && len(d.Services) == 0 &&
+		d.Technologies.Empty() && d.URLs.Empty() && len(d.NewFindings) == 0 && len(d.Resolved) == 0
+}
+
+// Summary renders the size of each change, e.g. "+2/-1 subdomains,
+// 1 new finding".
+func (d ScanDiff) Summary() string {
+	var parts []string
+	for _, s := range []struct {
+		name string
+		c    SetChange
+	}{
+		{"subdomains", d.Subdomains}, {"IPs", d.IPs}, {"ports", d.Ports},
+		{"technologies", d.Technologies}, {"URLs", d.URLs},
+	} {
+		if !s.c.Empty() {
+			parts = append(parts, fmt.Sprintf("+%d/-%d %s", len(s.c.Added), len(s.c.Removed), s.name))
+		}
+	}
+	if len(d.Services) > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed service(s)", len(d.Services)))
+	}
+	if len(d.NewFindings) > 0 {
+		parts = append(parts, fmt.Sprintf("%d new finding(s)", len(d.NewFindings)))
+	}
+	if len(d.Resolved) > 0 {
+		parts = append(parts, fmt.Sprintf("%d resolved finding(s)", len(d.Resolved)))
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// TargetsDiffer reports whether the scans cover different targets.
+func (d ScanDiff) TargetsDiffer() bool {
+	return !sameStrings(d.Old.Targets, d.New.Targets)
+}
+
+// Publish announces the change set on the bus for notifiers.
+func (d ScanDiff) Publish(log *Logger) {
+	log.Publish(Event{
+		Type:    EventScanDiff,
+		Level:   LevelInfo,
+		Message: fmt.Sprintf("Changes since scan %s: %s", d.Old.ID, d.Summary()),
+		Fields:  map[string]interface{}{"diff": d},
+	})
+}
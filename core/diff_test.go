@@ -0,0 +1,90 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func graphOf(t *testing.T, nodes ...Node) *AssetGraph {
+	t.Helper()
+	g := NewAssetGraph()
+	for _, n := range nodes {
+		g.AddNode(n.Kind, n.Value, n.Attrs, Provenance{Module: "test"})
+	}
+	return g
+}
+
+func resultWith(titles ...string) []Result {
+	r := Result{ModuleName: "vulnscan"}
+	for _, title := range titles {
+		r.AddFinding(Finding{Title: title, Severity: SeverityHigh, Asset: Asset{Host: "example.com"}})
+	}
+	return []Result{r}
+}
+
+func TestDiffScans(t *testing.T) {
+	old := ScanSnapshot{
+		Assets: graphOf(t,
+			Node{Kind: NodeDomain, Value: "example.com"},
+			Node{Kind: NodeSubdomain, Value: "old.example.com"},
+			Node{Kind: NodeSubdomain, Value: "www.example.com"},
+			Node{Kind: NodeIP, Value: "192.0.2.1"},
+			Node{Kind: NodeService, Value: "www.example.com:443", Attrs: map[string]string{"service": "https", "banner": "nginx/1.20"}},
+			Node{Kind: NodeService, Value: "www.example.com:22", Attrs: map[string]string{"service": "ssh"}},
+		),
+		Results: resultWith("Old bug", "Lasting bug"),
+	}
+	new := ScanSnapshot{
+		Assets: graphOf(t,
+			Node{Kind: NodeDomain, Value: "example.com"},
+			Node{Kind: NodeSubdomain, Value: "www.example.com"},
+			Node{Kind: NodeSubdomain, Value: "api.example.com"},
+			Node{Kind: NodeIP, Value: "192.0.2.1"},
+			Node{Kind: NodeService, Value: "www.example.com:443", Attrs: map[string]string{"service": "https", "banner": "nginx/1.25"}},
+			Node{Kind: NodeService, Value: "www.example.com:22", Attrs: map[string]string{"service": "ssh"}},
+			Node{Kind: NodeService, Value: "api.example.com:8443"},
+			Node{Kind: NodeTechnology, Value: "nginx"},
+		),
+		Results: resultWith("Lasting bug", "New bug"),
+	}
+
+	d := DiffScans(old, new)
+	checks := []struct {
+		name      string
+		got, want SetChange
+	}{
+		{"subdomains", d.Subdomains, SetChange{Added: []string{"api.example.com"}, Removed: []string{"old.example.com"}}},
+		{"ips", d.IPs, SetChange{}},
+		{"ports", d.Ports, SetChange{Added: []string{"api.example.com:8443"}}},
+		{"technologies", d.Technologies, SetChange{Added: []string{"nginx"}}},
+		{"urls", d.URLs, SetChange{}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %+v, want %+v", c.name, c.got, c.want)
+		}
+	}
+	wantServices := []AttrChange{{Asset: "www.example.com:443", Field: "banner", Old: "nginx/1.20", New: "nginx/1.25"}}
+	if !reflect.DeepEqual(d.Services, wantServices) {
+		t.Errorf("services = %+v, want %+v", d.Services, wantServices)
+	}
+	if len(d.NewFindings) != 1 || d.NewFindings[0].Title != "New bug" {
+		t.Errorf("new findings = %+v, want New bug", d.NewFindings)
+	}
+	if len(d.Resolved) != 1 || d.Resolved[0].Title != "Old bug" {
+		t.Errorf("resolved findings = %+v, want Old bug", d.Resolved)
+	}
+	if d.Empty() {
+		t.Error("Empty() = true for scans that differ")
+	}
+}
+
+func TestDiffScansUnchanged(t *testing.T) {
+	snap := ScanSnapshot{
+		Assets:  graphOf(t, Node{Kind: NodeSubdomain, Value: "www.example.com"}, Node{Kind: NodeURL, Value: "https://www.example.com/"}),
+		Results: resultWith("Lasting bug"),
+	}
+	if d := DiffScans(snap, snap); !d.Empty() {
+		t.Errorf("DiffScans of a scan with itself is not empty: %s", d.Summary())
+	}
+}
@@ -1,36 +1,124 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultModuleTimeout bounds a single module run when no timeout is configured.
+const DefaultModuleTimeout = 15 * time.Minute
+
+// ModuleGrace is how long a module may take to return once its run is
+// cancelled or times out.
+const ModuleGrace = 10 * time.Second
+
+// ErrModuleStuck is returned (wrapped) when a module kept running past
+// ModuleGrace. Such runs are never retried, since the first copy may still
+// write to the scan's data.
+var ErrModuleStuck = errors.New("still running")
+
 // Module is the interface that all modules must implement.
+// Run must return promptly once ctx is cancelled or its deadline expires.
+// params holds the validated values for the module's ParamSpecs, if any.
 type Module interface {
 	Name() string
-	Run(target string, ctx *Context) (Result, error)
+	Run(ctx context.Context, target string, params Params, rctx *Context) (Result, error)
 }
 
 // Result is a placeholder for module output.
 type Result struct {
 	ModuleName string
+	Target     string
 	Data       map[string]interface{}
+	Findings   []Finding
+	// Retries counts retried attempts by data source, and by module name
+	// for reruns of the whole module.
+	Retries map[string]int `json:",omitempty"`
 }
 
 // Context holds context for recon (can be extended).
 type Context struct {
 	Target string
-	Store  map[string]interface{}
+	Store  *Store
+	// Assets links everything discovered about the target; modules add
+	// to it through AssetWriter.
+	Assets *AssetGraph
+	Scope  *Scope // nil allows everything
+	Bus    *Bus   // nil drops events
+	// Limiter throttles HTTP, DNS and dial traffic; nil is unlimited.
+	Limiter *Limiter
+	// HTTP builds the modules' HTTP clients; nil uses defaults.
+	HTTP *HTTPClientFactory
+	// Cache stores third-party lookups; nil always queries the source.
+	Cache *Cache
+	// Retry holds the module and data source retry policies; nil uses
+	// the defaults.
+	Retry *RetryPolicies
+	// APIKeys holds service credentials from the config's api_keys.
+	APIKeys map[string]string
+	// Settings holds the config's free-form "other" section.
+	Settings map[string]interface{}
+	// WordlistDir is where path parameters resolve; empty means
+	// DefaultWordlistDir.
+	WordlistDir string
+
+	retryMu sync.Mutex
+	retries map[string]map[string]int // module -> source -> retries
+}
+
+// NewContext returns a Context with an empty Store.
+func NewContext(target string) *Context {
+	return &Context{
+		Target: target,
+		Store:  NewStore(),
+		Assets: NewAssetGraph(),
+	}
+}
+
+// DefaultWordlistDir holds the files path parameters such as wordlists name.
+const DefaultWordlistDir = "wordlists"
+
+// Wordlist resolves a path parameter under the wordlist directory; an
+// empty name stays empty.
+func (c *Context) Wordlist(name string) string {
+	if name == "" {
+		return ""
+	}
+	dir := DefaultWordlistDir
+	if c != nil && c.WordlistDir != "" {
+		dir = c.WordlistDir
+	}
+	return filepath.Join(dir, name)
+}
+
+// APIKey returns the configured credential for service, or "".
+func (c *Context) APIKey(service string) string {
+	if c == nil {
+		return ""
+	}
+	return c.APIKeys[service]
 }
 
 // Engine manages modules and runs recon workflows.
 type Engine struct {
-	modules map[string]Module
+	modules        map[string]Module
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration
+	params         map[string]map[string]interface{}
 }
 
 // NewEngine initializes an Engine instance.
 func NewEngine() *Engine {
 	return &Engine{
-		modules: make(map[string]Module),
+		modules:        make(map[string]Module),
+		defaultTimeout: DefaultModuleTimeout,
+		timeouts:       make(map[string]time.Duration),
+		params:         make(map[string]map[string]interface{}),
 	}
 }
 
@@ -39,19 +127,185 @@ func (e *Engine) RegisterModule(m Module) {
 	e.modules[m.Name()] = m
 }
 
-// ListModules prints available modules.
-func (e *Engine) ListModules() {
-	fmt.Println("Available modules:")
-	for name := range e.modules {
-		fmt.Println("-", name)
+// Module returns the registered module called name.
+func (e *Engine) Module(name string) (Module, bool) {
+	mod, ok := e.modules[name]
+	return mod, ok
+}
+
+// SetTimeouts configures the default and per-module run deadlines.
+// A zero duration disables the deadline for that module.
+func (e *Engine) SetTimeouts(def time.Duration, perModule map[string]time.Duration) {
+	e.defaultTimeout = def
+	e.timeouts = make(map[string]time.Duration, len(perModule))
+	for name, d := range perModule {
+		e.timeouts[name] = d
+	}
+}
+
+// ModuleTimeout returns the deadline applied to a module run.
+func (e *Engine) ModuleTimeout(name string) time.Duration {
+	if d, ok := e.timeouts[name]; ok {
+		return d
+	}
+	return e.defaultTimeout
+}
+
+// SetParams sets base parameter values for a module (from config or CLI).
+// They override the module defaults; per-call values override them.
+func (e *Engine) SetParams(name string, params map[string]interface{}) {
+	merged := make(map[string]interface{}, len(params))
+	for k, v := range e.params[name] {
+		merged[k] = v
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+	e.params[name] = merged
+}
+
+// ParamSchemas returns the parameter specs of every parameterized module.
+func (e *Engine) ParamSchemas() map[string][]ParamSpec {
+	schemas := make(map[string][]ParamSpec)
+	for name, mod := range e.modules {
+		if p, ok := mod.(Parameterized); ok {
+			schemas[name] = p.Params()
+		}
 	}
+	return schemas
 }
 
-// RunModule executes a module by name.
-func (e *Engine) RunModule(name string, target string, ctx *Context) (Result, error) {
+// UntrustedParams drops path parameters the operator did not allow from
+// params the LLM agent or an API request set for module.
+func (e *Engine) UntrustedParams(module string, params map[string]interface{}, allowed []string) (map[string]interface{}, []string) {
+	var specs []ParamSpec
+	if p, ok := e.modules[module].(Parameterized); ok {
+		specs = p.Params()
+	}
+	return UntrustedParams(specs, module, params, allowed)
+}
+
+// ResolveParams validates a module's parameters: defaults, then the values
+// set with SetParams, then the per-call values.
+func (e *Engine) ResolveParams(name string, call map[string]interface{}) (Params, error) {
+	mod, exists := e.modules[name]
+	if !exists {
+		return nil, fmt.Errorf("module not found: %s", name)
+	}
+	var specs []ParamSpec
+	if p, ok := mod.(Parameterized); ok {
+		specs = p.Params()
+	}
+	params, err := ResolveParams(specs, e.params[name], call)
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %v", name, err)
+	}
+	return params, nil
+}
+
+// RunModule executes a module by name, enforcing its configured deadline.
+// params are per-call overrides (e.g. from the agent) and may be nil.
+// Once ctx is done, the module gets ModuleGrace to return before RunModule
+// gives up on it, so the caller is never blocked for long and a retry
+// never runs beside an earlier attempt.
+func (e *Engine) RunModule(ctx context.Context, name string, target string, params map[string]interface{}, rctx *Context) (Result, error) {
 	mod, exists := e.modules[name]
 	if !exists {
 		return Result{}, fmt.Errorf("module not found: %s", name)
 	}
-	return mod.Run(target, ctx)
+	resolved, err := e.ResolveParams(name, params)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, fmt.Errorf("module %s not started: %w", name, err)
+	}
+	// Central rules-of-engagement gate: nothing runs against an
+	// out-of-scope target, whoever picked the module.
+	if err := rctx.Enforce(name, target); err != nil {
+		return Result{}, fmt.Errorf("module %s not started: %w", name, err)
+	}
+
+	log := rctx.Logger(name)
+	log.Publish(Event{Type: EventModuleStarted, Level: LevelInfo, Message: "Running module: " + name})
+	start := time.Now()
+	rctx.takeRetries(name) // left over from an earlier run of the module
+	policy := rctx.retryPolicies().Module(name)
+	var result Result
+	retries, err := policy.Do(ctx, func() error {
+		var err error
+		result, err = e.runModule(ctx, mod, target, resolved, rctx)
+		return err
+	}, func(n int, wait time.Duration, err error) {
+		log.Warnf("Retrying module %s in %s (attempt %d/%d): %v", name, wait.Round(time.Millisecond), n+1, policy.Attempts, err)
+	})
+	rctx.addRetries(name, name, retries)
+	counts := rctx.takeRetries(name)
+
+	finished := Event{
+		Type:   EventModuleFinished,
+		Level:  LevelInfo,
+		Fields: map[string]interface{}{"duration": time.Since(start).Round(time.Millisecond).String()},
+	}
+	if len(counts) > 0 {
+		finished.Fields["retries"] = counts
+	}
+	if err != nil {
+		finished.Level = LevelError
+		finished.Error = err.Error()
+		finished.Message = fmt.Sprintf("Error in module %s: %v", name, err)
+		log.Publish(finished)
+		return result, err
+	}
+	result.Retries = counts
+	finished.Fields["findings"] = len(result.Findings)
+	finished.Message = fmt.Sprintf("Module %s completed in %s", name, finished.Fields["duration"])
+	log.Publish(finished)
+	assets := rctx.AssetWriter(name)
+	for i := range result.Findings {
+		f := result.Findings[i]
+		assets.Finding(f)
+		log.Publish(Event{
+			Type:    EventFindingRaised,
+			Level:   LevelInfo,
+			Message: fmt.Sprintf("[%s] %s (%s)", strings.ToUpper(string(f.Severity)), f.Title, f.Asset),
+			Finding: &f,
+		})
+	}
+	return result, nil
+}
+
+// runModule runs mod in its own goroutine under the module's deadline, so
+// a module that ignores ctx cannot block the caller much past it.
+func (e *Engine) runModule(ctx context.Context, mod Module, target string, params Params, rctx *Context) (Result, error) {
+	name := mod.Name()
+	if timeout := e.ModuleTimeout(name); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	type outcome struct {
+		result Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := mod.Run(ctx, target, params, rctx)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		if out.err != nil && ctx.Err() != nil {
+			return out.result, fmt.Errorf("module %s: %w", name, ctx.Err())
+		}
+		return out.result, out.err
+	case <-ctx.Done():
+	}
+	select {
+	case <-done:
+		return Result{}, fmt.Errorf("module %s: %w", name, ctx.Err())
+	case <-time.After(ModuleGrace):
+		return Result{}, fmt.Errorf("module %s: %w (%w after %s)", name, ctx.Err(), ErrModuleStuck, ModuleGrace)
+	}
 }
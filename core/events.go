@@ -0,0 +1,193 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level orders log events by importance.
+type Level int
+
+const (
+	LevelTrace Level = iota // LLM prompts and raw responses
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"trace", "debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelTrace || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// MarshalJSON encodes the level by name.
+func (l Level) MarshalJSON() ([]byte, error) { return json.Marshal(l.String()) }
+
+// UnmarshalJSON decodes a level name.
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// ParseLevel accepts trace, debug, info, warn (or warning) and error.
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "warning" {
+		s = "warn"
+	}
+	for i, name := range levelNames {
+		if name == s {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (want %s)", s, strings.Join(levelNames, ", "))
+}
+
+// EventType names what happened during a scan.
+type EventType string
+
+const (
+	EventScanStarted     EventType = "scan_started"
+	EventScanFinished    EventType = "scan_finished"
+	EventTargetStarted   EventType = "target_started"
+	EventTargetFinished  EventType = "target_finished"
+	EventModuleStarted   EventType = "module_started"
+	EventModuleFinished  EventType = "module_finished"
+	EventAssetDiscovered EventType = "asset_discovered"
+	EventFindingRaised   EventType = "finding_raised"
+	EventAgentDecision   EventType = "agent_decision"
+	EventScanDiff        EventType = "scan_diff" // Fields["diff"] holds the ScanDiff
+	EventLog             EventType = "log"
+)
+
+// Event is one entry on the scan event bus. Fields carries event-specific
+// data, e.g. "duration" for EventModuleFinished or "kind"/"value" for
+// EventAssetDiscovered.
+type Event struct {
+	Time    time.Time              `json:"time"`
+	Type    EventType              `json:"type"`
+	Level   Level                  `json:"level"`
+	Target  string                 `json:"target,omitempty"`
+	Module  string                 `json:"module,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	Finding *Finding               `json:"finding,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Bus fans scan events out to subscribers (log sinks, the server, ...).
+// Delivery is synchronous and in publish order; a nil *Bus drops events.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]func(Event)
+}
+
+// NewBus returns an empty event bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]func(Event))}
+}
+
+// Subscribe registers fn for every later event and returns a function that
+// removes it again.
+func (b *Bus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+	}
+}
+
+// Publish stamps e with the current time (if unset) and delivers it.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subs {
+		fn(e)
+	}
+}
+
+// Logger publishes log and asset events for one target and module.
+// A nil *Logger, or one without a bus, discards everything.
+type Logger struct {
+	bus    *Bus
+	target string
+	module string
+}
+
+// Logger returns a logger that tags events with target and module.
+func (b *Bus) Logger(target, module string) *Logger {
+	return &Logger{bus: b, target: target, module: module}
+}
+
+// Logger returns a logger for module bound to this scan's target and bus.
+func (c *Context) Logger(module string) *Logger {
+	if c == nil {
+		return nil
+	}
+	return c.Bus.Logger(c.Target, module)
+}
+
+func (l *Logger) Tracef(format string, args ...interface{}) { l.logf(LevelTrace, format, args...) }
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...interface{})  { l.logf(LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{})  { l.logf(LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
+
+// Asset announces a discovered asset, e.g. Asset("subdomain", "www.example.com").
+func (l *Logger) Asset(kind, value string) {
+	l.Publish(Event{
+		Type:    EventAssetDiscovered,
+		Level:   LevelDebug,
+		Message: fmt.Sprintf("Discovered %s %s", kind, value),
+		Fields:  map[string]interface{}{"kind": kind, "value": value},
+	})
+}
+
+// Publish sends e with the logger's target and module filled in.
+func (l *Logger) Publish(e Event) {
+	if l == nil || l.bus == nil {
+		return
+	}
+	if e.Target == "" {
+		e.Target = l.target
+	}
+	if e.Module == "" {
+		e.Module = l.module
+	}
+	l.bus.Publish(e)
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if l == nil || l.bus == nil {
+		return
+	}
+	l.Publish(Event{Type: EventLog, Level: level, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity ranks how serious a finding is.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities lists every severity, most serious first.
+var Severities = []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo}
+
+// Rank orders severities; higher is more serious. Unknown values rank lowest.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityCritical:
+		return 4
+	case SeverityHigh:
+		return 3
+	case SeverityMedium:
+		return 2
+	case SeverityLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity converts a case-insensitive name into a Severity.
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Severities {
+		if sev == known {
+			return sev, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity: %s", s)
+}
+
+// Confidence expresses how sure a module is that a finding is real.
+type Confidence string
+
+const (
+	ConfidenceTentative Confidence = "tentative"
+	ConfidenceFirm      Confidence = "firm"
+	ConfidenceCertain   Confidence = "certain"
+)
+
+// Asset identifies what a finding affects. Unused fields stay empty.
+type Asset struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// String renders the most specific form of the asset.
+func (a Asset) String() string {
+	switch {
+	case a.URL != "":
+		return a.URL
+	case a.Port != 0:
+		return fmt.Sprintf("%s:%d", a.Host, a.Port)
+	default:
+		return a.Host
+	}
+}
+
+// Finding is a single security-relevant observation raised by a module.
+type Finding struct {
+	ID          string     `json:"id"`
+	Module      string     `json:"module"`
+	Title       string     `json:"title"`
+	Severity    Severity   `json:"severity"`
+	Confidence  Confidence `json:"confidence"`
+	Asset       Asset      `json:"asset"`
+	Evidence    string     `json:"evidence,omitempty"`   // request/response snippet or banner
+	References  []string   `json:"references,omitempty"` // CVE/CWE IDs or URLs
+	Remediation string     `json:"remediation,omitempty"`
+}
+
+// FindingID derives a stable ID from what was found and where, so the same
+// issue keeps its ID across runs.
+func FindingID(module, title string, asset Asset) string {
+	sum := sha1.Sum([]byte(module + "|" + title + "|" + asset.String()))
+	return module + "-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// AddFinding attaches a finding to the result, filling in the module name,
+// ID and defaults when missing.
+func (r *Result) AddFinding(f Finding) {
+	if f.Module == "" {
+		f.Module = r.ModuleName
+	}
+	if f.Severity == "" {
+		f.Severity = SeverityInfo
+	}
+	if f.Confidence == "" {
+		f.Confidence = ConfidenceTentative
+	}
+	if f.ID == "" {
+		f.ID = FindingID(f.Module, f.Title, f.Asset)
+	}
+	r.Findings = append(r.Findings, f)
+}
+
+// CollectFindings gathers the findings of all results, sorted by severity.
+func CollectFindings(results []Result) []Finding {
+	var all []Finding
+	for _, r := range results {
+		all = append(all, r.Findings...)
+	}
+	SortFindings(all)
+	return all
+}
+
+// SortFindings orders findings by severity (most serious first), then by
+// title and asset for a stable listing.
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool { return findingLess(findings[i], findings[j]) })
+}
+
+func findingLess(a, b Finding) bool {
+	if a.Severity.Rank() != b.Severity.Rank() {
+		return a.Severity.Rank() > b.Severity.Rank()
+	}
+	if a.Title != b.Title {
+		return a.Title < b.Title
+	}
+	return a.Asset.String() < b.Asset.String()
+}
+
+// CountBySeverity tallies findings per severity, including zero counts.
+func CountBySeverity(findings []Finding) map[Severity]int {
+	counts := make(map[Severity]int, len(Severities))
+	for _, sev := range Severities {
+		counts[sev] = 0
+	}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	return counts
+}
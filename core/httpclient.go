@@ -0,0 +1,279 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent identifies Triksha when no user_agent is configured.
+const DefaultUserAgent = "Triksha/1.0"
+
+// DefaultMaxRedirects is followed when max_redirects is unset.
+const DefaultMaxRedirects = 10
+
+// HTTPConfig configures every HTTP client modules get from the Context.
+type HTTPConfig struct {
+	// Proxy routes traffic upstream, e.g. "http://127.0.0.1:8080" (Burp)
+	// or "socks5://127.0.0.1:1080".
+	Proxy     string            `json:"proxy,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Cookies   map[string]string `json:"cookies,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	// Insecure skips TLS certificate verification.
+	Insecure bool `json:"insecure,omitempty"`
+	// CACert, ClientCert and ClientKey are PEM file paths.
+	CACert     string `json:"ca_cert,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// MaxRedirects caps followed redirects; negative disables following.
+	MaxRedirects int `json:"max_redirects,omitempty"`
+	// HostTimeouts overrides the request timeout per host; keys may be
+	// globs such as "*.example.com".
+	HostTimeouts map[string]string `json:"host_timeouts,omitempty"`
+}
+
+// HTTPClientFactory builds HTTP clients from an HTTPConfig. Clients share
+// one transport so connections are reused across modules.
+type HTTPClientFactory struct {
+	cfg          HTTPConfig
+	base         *http.Transport
+	source       *http.Transport // proxy and connection limit only
+	hostTimeouts map[string]time.Duration
+}
+
+// NewHTTPClientFactory validates cfg and prepares the shared transport.
+// Connections count against lim's connection limit.
+func NewHTTPClientFactory(cfg HTTPConfig, lim *Limiter) (*HTTPClientFactory, error) {
+	f := &HTTPClientFactory{cfg: cfg, hostTimeouts: make(map[string]time.Duration, len(cfg.HostTimeouts))}
+	for host, raw := range cfg.HostTimeouts {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http host timeout for %s: %v", host, err)
+		}
+		if _, err := path.Match(host, ""); err != nil {
+			return nil, fmt.Errorf("invalid http host pattern %q: %v", host, err)
+		}
+		f.hostTimeouts[strings.ToLower(host)] = d
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	source := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid http proxy %q", cfg.Proxy)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported http proxy scheme %q (want http, https or socks5)", proxy.Scheme)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+		source.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("http ca_cert: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("http ca_cert: no certificates in %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("http client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	t.TLSClientConfig = tlsConfig
+
+	if lim != nil {
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return lim.dial(ctx, &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}, network, addr)
+		}
+		source.DialContext = t.DialContext
+	}
+	f.base, f.source = t, source
+	return f, nil
+}
+
+// Config returns the settings, e.g. to pass them on to external tools.
+func (f *HTTPClientFactory) Config() HTTPConfig {
+	if f == nil {
+		return HTTPConfig{}
+	}
+	return f.cfg
+}
+
+// UserAgent returns the configured User-Agent, or DefaultUserAgent.
+func (f *HTTPClientFactory) UserAgent() string {
+	if f == nil || f.cfg.UserAgent == "" {
+		return DefaultUserAgent
+	}
+	return f.cfg.UserAgent
+}
+
+// Transport returns the configured transport (proxy, TLS, headers,
+// cookies and timeouts) with timeout as the default request timeout.
+func (f *HTTPClientFactory) Transport(timeout time.Duration) http.RoundTripper {
+	return &configuredTransport{factory: f, timeout: timeout}
+}
+
+// SourceTransport returns a transport for third-party data sources such
+// as crt.sh. It keeps the proxy and connection limit but leaves out the
+// configured headers, cookies, User-Agent and TLS settings, unless
+// inScope reports the request's host as part of the engagement.
+func (f *HTTPClientFactory) SourceTransport(timeout time.Duration, inScope func(host string) bool) http.RoundTripper {
+	return &sourceTransport{configured: &configuredTransport{factory: f, timeout: timeout}, inScope: inScope}
+}
+
+// Client wraps the configured transport with wrap (e.g. scope or rate
+// limiting) and applies the redirect policy.
+func (f *HTTPClientFactory) Client(timeout time.Duration, wrap func(http.RoundTripper) http.RoundTripper) *http.Client {
+	return f.client(f.Transport(timeout), wrap)
+}
+
+func (f *HTTPClientFactory) client(rt http.RoundTripper, wrap func(http.RoundTripper) http.RoundTripper) *http.Client {
+	if wrap != nil {
+		rt = wrap(rt)
+	}
+	max := f.cfg.MaxRedirects
+	if max == 0 {
+		max = DefaultMaxRedirects
+	}
+	return &http.Client{
+		Transport: rt,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if max < 0 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= max {
+				return fmt.Errorf("stopped after %d redirects", max)
+			}
+			return nil
+		},
+	}
+}
+
+// timeoutFor returns the request timeout for host.
+func (f *HTTPClientFactory) timeoutFor(host string, def time.Duration) time.Duration {
+	host = strings.ToLower(host)
+	if d, ok := f.hostTimeouts[host]; ok {
+		return d
+	}
+	for pattern, d := range f.hostTimeouts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return d
+		}
+	}
+	return def
+}
+
+type configuredTransport struct {
+	factory *HTTPClientFactory
+	timeout time.Duration
+}
+
+func (t *configuredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f := t.factory
+	req = req.Clone(req.Context())
+	for k, v := range f.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if f.cfg.UserAgent != "" || req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", f.UserAgent())
+	}
+	for name, value := range f.cfg.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	return roundTrip(f.base, req, f.timeoutFor(req.URL.Hostname(), t.timeout))
+}
+
+type sourceTransport struct {
+	configured *configuredTransport
+	inScope    func(host string) bool
+}
+
+func (t *sourceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.inScope != nil && t.inScope(req.URL.Hostname()) {
+		return t.configured.RoundTrip(req)
+	}
+	f := t.configured.factory
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+	return roundTrip(f.source, req, f.timeoutFor(req.URL.Hostname(), t.configured.timeout))
+}
+
+// roundTrip sends req over base with timeout, if positive, covering the
+// whole exchange.
+func roundTrip(base http.RoundTripper, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The deadline covers reading the body too
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// httpFactory returns the scan's factory, or a default one.
+func (c *Context) httpFactory() *HTTPClientFactory {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	f, _ := NewHTTPClientFactory(HTTPConfig{}, c.Limiter)
+	return f
+}
+
+// HTTPClient returns a client for module's requests to the target. Every
+// request, redirects included, is scope checked and rate limited.
+func (c *Context) HTTPClient(module string, timeout time.Duration) *http.Client {
+	return c.httpFactory().Client(timeout, func(rt http.RoundTripper) http.RoundTripper {
+		return c.ScopedTransport(module, rt)
+	})
+}
+
+// SourceClient returns a client for third-party data sources such as
+// crt.sh. It is rate limited but not scope checked, and only sends the
+// engagement's headers, cookies and User-Agent to hosts a scope include
+// rule matches.
+func (c *Context) SourceClient(timeout time.Duration) *http.Client {
+	f := c.httpFactory()
+	inScope := func(host string) bool { return c.Scope.Restricted() && c.Scope.CheckHost(host) == nil }
+	return f.client(f.SourceTransport(timeout, inScope), c.Limiter.Transport)
+}
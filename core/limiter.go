@@ -0,0 +1,290 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimits caps the traffic a scan generates. Rates are per second; zero
+// (or less) leaves that limit off.
+type RateLimits struct {
+	PerHost     float64 `json:"per_host,omitempty"`    // requests to one host
+	Global      float64 `json:"global,omitempty"`      // requests across all hosts
+	HTTP        float64 `json:"http,omitempty"`        // HTTP requests
+	DNS         float64 `json:"dns,omitempty"`         // DNS queries
+	Connections int     `json:"connections,omitempty"` // concurrent open connections
+}
+
+// DefaultRateProfile is used when the config names no profile.
+const DefaultRateProfile = "normal"
+
+// RateProfiles are the named limit presets selectable with -rate-profile or
+// the config's rate_profile.
+var RateProfiles = map[string]RateLimits{
+	"polite":     {PerHost: 2, Global: 10, HTTP: 10, DNS: 20, Connections: 10},
+	"normal":     {PerHost: 20, Global: 100, HTTP: 100, DNS: 200, Connections: 50},
+	"aggressive": {PerHost: 100, Global: 500, HTTP: 500, DNS: 1000, Connections: 200},
+	"unlimited":  {},
+}
+
+// RateProfileNames lists the profile names, sorted.
+func RateProfileNames() []string {
+	names := make([]string, 0, len(RateProfiles))
+	for name := range RateProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Budget names the kind of traffic a Wait is for.
+type Budget int
+
+const (
+	BudgetTCP  Budget = iota // raw connections, e.g. port probes and WHOIS
+	BudgetHTTP               // HTTP requests
+	BudgetDNS                // DNS queries
+)
+
+// Limiter enforces RateLimits across every module of a scan. A nil
+// *Limiter allows everything.
+type Limiter struct {
+	limits    RateLimits
+	global    *rate.Limiter
+	http      *rate.Limiter
+	dns       *rate.Limiter
+	conns     chan struct{}
+	transport *http.Transport
+
+	mu    sync.Mutex
+	hosts map[string]*rate.Limiter
+}
+
+// NewLimiter returns a limiter enforcing limits.
+func NewLimiter(limits RateLimits) *Limiter {
+	l := &Limiter{
+		limits: limits,
+		global: newRate(limits.Global),
+		http:   newRate(limits.HTTP),
+		dns:    newRate(limits.DNS),
+		hosts:  make(map[string]*rate.Limiter),
+	}
+	if limits.Connections > 0 {
+		l.conns = make(chan struct{}, limits.Connections)
+	}
+	// Shared so connections are reused across modules
+	l.transport = http.DefaultTransport.(*http.Transport).Clone()
+	l.transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return l.dial(ctx, &net.Dialer{}, network, addr)
+	}
+	return l
+}
+
+// newRate returns nil (no limit) for non-positive rates.
+func newRate(perSec float64) *rate.Limiter {
+	if perSec <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSec), int(math.Max(1, math.Ceil(perSec))))
+}
+
+// Limits returns the configured limits.
+func (l *Limiter) Limits() RateLimits {
+	if l == nil {
+		return RateLimits{}
+	}
+	return l.limits
+}
+
+// Wait blocks until one request of the given budget to host may proceed.
+// The global limit always applies, the per-host limit only when host is set.
+func (l *Limiter) Wait(ctx context.Context, budget Budget, host string) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	waits := []*rate.Limiter{l.global}
+	switch budget {
+	case BudgetHTTP:
+		waits = append(waits, l.http)
+	case BudgetDNS:
+		waits = append(waits, l.dns)
+	}
+	if host != "" && l.limits.PerHost > 0 {
+		waits = append(waits, l.host(host))
+	}
+	for _, lim := range waits {
+		if lim == nil {
+			continue
+		}
+		if err := lim.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) host(host string) *rate.Limiter {
+	host = strings.ToLower(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim, ok := l.hosts[host]
+	if !ok {
+		lim = newRate(l.limits.PerHost)
+		l.hosts[host] = lim
+	}
+	return lim
+}
+
+// DialContext waits for the TCP budget of addr's host, then dials it while
+// holding a connection slot until the returned conn is closed.
+func (l *Limiter) DialContext(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	if l == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if err := l.Wait(ctx, BudgetTCP, host); err != nil {
+		return nil, err
+	}
+	return l.dial(ctx, dialer, network, addr)
+}
+
+// dial takes a connection slot for the lifetime of the connection.
+func (l *Limiter) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	if l.conns == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	select {
+	case l.conns <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		<-l.conns
+		return nil, err
+	}
+	return &slotConn{Conn: conn, release: func() { <-l.conns }}, nil
+}
+
+// slotConn frees its connection slot on the first Close.
+type slotConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *slotConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+// Transport wraps base so every request waits for the HTTP budget of its
+// host. A nil base uses a shared transport whose connections count against
+// the connection limit.
+func (l *Limiter) Transport(base http.RoundTripper) http.RoundTripper {
+	if l == nil {
+		if base == nil {
+			return http.DefaultTransport
+		}
+		return base
+	}
+	if base == nil {
+		base = l.transport
+	}
+	return &limitedTransport{limiter: l, base: base}
+}
+
+type limitedTransport struct {
+	limiter *Limiter
+	base    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), BudgetHTTP, req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Resolver returns a resolver whose queries wait for the DNS budget.
+func (l *Limiter) Resolver() *net.Resolver {
+	if l == nil {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if err := l.Wait(ctx, BudgetDNS, ""); err != nil {
+				return nil, err
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// Rate caps a requested rate for an external tool (naabu, nmap, httpx) at
+// the limits that apply to it: the budget's and the global rate, plus the
+// per-host rate when the tool targets a single host.
+func (l *Limiter) Rate(requested int, budget Budget, singleHost bool) int {
+	if l == nil {
+		return requested
+	}
+	caps := []float64{l.limits.Global}
+	switch budget {
+	case BudgetHTTP:
+		caps = append(caps, l.limits.HTTP)
+	case BudgetDNS:
+		caps = append(caps, l.limits.DNS)
+	}
+	if singleHost {
+		caps = append(caps, l.limits.PerHost)
+	}
+	for _, c := range caps {
+		if c > 0 && (requested <= 0 || int(c) < requested) {
+			requested = int(math.Max(1, c))
+		}
+	}
+	return requested
+}
+
+// Concurrency caps a requested worker count at the connection limit.
+func (l *Limiter) Concurrency(requested int) int {
+	if l == nil || l.limits.Connections <= 0 {
+		return requested
+	}
+	if requested <= 0 || l.limits.Connections < requested {
+		return l.limits.Connections
+	}
+	return requested
+}
+
+// String summarizes the limits for logs.
+func (r RateLimits) String() string {
+	format := func(v float64) string {
+		if v <= 0 {
+			return "off"
+		}
+		return fmt.Sprintf("%g/s", v)
+	}
+	conns := "off"
+	if r.Connections > 0 {
+		conns = fmt.Sprint(r.Connections)
+	}
+	return fmt.Sprintf("per-host %s, global %s, http %s, dns %s, connections %s",
+		format(r.PerHost), format(r.Global), format(r.HTTP), format(r.DNS), conns)
+}
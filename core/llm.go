@@ -23,6 +23,8 @@ type LLMClient interface {
 type OpenAIClient struct {
 	client *openai.Client
 	model  string
+	// Log receives trace-level LLM traffic; nil discards it.
+	Log *Logger
 }
 
 // ChatWithTimeout implements LLMClient interface for OpenAIClient
@@ -62,7 +64,7 @@ func (c *OpenAIClient) ChatWithTimeout(ctx context.Context, prompt string, timeo
 		}
 
 		responseText := strings.TrimSpace(resp.Choices[0].Message.Content)
-		fmt.Printf("[DEBUG] OpenAI response: %s\n", responseText)
+		c.Log.Tracef("OpenAI response: %s", responseText)
 		resultCh <- result{response: responseText, err: nil}
 	}()
 
@@ -85,7 +87,7 @@ func NewOpenAIClient(apiKey, model string) *OpenAIClient {
 }
 
 func (c *OpenAIClient) Chat(prompt string) (string, error) {
-	fmt.Printf("[DEBUG] OpenAI prompt: %s\n", prompt)
+	c.Log.Tracef("OpenAI prompt: %s", prompt)
 
 	req := openai.ChatCompletionRequest{
 		Model: c.model,
@@ -110,7 +112,7 @@ func (c *OpenAIClient) Chat(prompt string) (string, error) {
 	}
 
 	result := strings.TrimSpace(resp.Choices[0].Message.Content)
-	fmt.Printf("[DEBUG] OpenAI response: %s\n", result)
+	c.Log.Tracef("OpenAI response: %s", result)
 	return result, nil
 }
 
@@ -118,6 +120,8 @@ func (c *OpenAIClient) Chat(prompt string) (string, error) {
 type OllamaClient struct {
 	Endpoint string
 	Model    string
+	// Log receives trace-level LLM traffic; nil discards it.
+	Log *Logger
 }
 
 func NewOllamaClient(endpoint, model string) *OllamaClient {
@@ -132,7 +136,7 @@ func NewOllamaClient(endpoint, model string) *OllamaClient {
 // Updated Ollamaclient method:
 
 func (c *OllamaClient) Chat(prompt string) (string, error) {
-	fmt.Printf("[DEBUG] Ollama prompt: %s\n", prompt)
+	c.Log.Tracef("Ollama prompt: %s", prompt)
 
 	// For Ollama, we'll use the completions endpoint which is more reliable for JSON responses
 	type Req struct {
@@ -158,7 +162,7 @@ func (c *OllamaClient) Chat(prompt string) (string, error) {
 		return "", fmt.Errorf("error marshaling request: %v", err)
 	}
 
-	fmt.Printf("[DEBUG] Sending to Ollama endpoint: %s\n", c.Endpoint+"/api/generate")
+	c.Log.Tracef("Sending to Ollama endpoint: %s", c.Endpoint+"/api/generate")
 
 	client := &http.Client{Timeout: 60 * time.Second}
 	resp, err := client.Post(c.Endpoint+"/api/generate", "application/json", bytes.NewBuffer(data))
@@ -173,7 +177,7 @@ func (c *OllamaClient) Chat(prompt string) (string, error) {
 		return "", fmt.Errorf("error reading response body: %v", err)
 	}
 
-	fmt.Printf("[DEBUG] Ollama raw response: %s\n", string(body))
+	c.Log.Tracef("Ollama raw response: %s", string(body))
 
 	// Parse the response - Ollama returns a single JSON object for non-streaming requests
 	var res struct {
@@ -190,12 +194,12 @@ func (c *OllamaClient) Chat(prompt string) (string, error) {
 	}
 
 	result := strings.TrimSpace(res.Response)
-	fmt.Printf("[DEBUG] Ollama parsed response: %s\n", result)
+	c.Log.Tracef("Ollama parsed response: %s", result)
 
 	// Try to extract JSON from the response
 	jsonStr := extractJSON(result)
 	if jsonStr != "" {
-		fmt.Printf("[DEBUG] Extracted JSON: %s\n", jsonStr)
+		c.Log.Tracef("Extracted JSON: %s", jsonStr)
 		return jsonStr, nil
 	}
 
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ConsoleSink writes events at or above min as human-readable lines,
+// e.g. "[portscan] Scanning ports for: example.com".
+func ConsoleSink(w io.Writer, min Level) func(Event) {
+	var mu sync.Mutex
+	return func(e Event) {
+		if e.Level < min {
+			return
+		}
+		line := FormatConsole(e)
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(w, line)
+	}
+}
+
+// JSONSink writes events at or above min as JSON lines.
+func JSONSink(w io.Writer, min Level) func(Event) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e Event) {
+		if e.Level < min {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(e)
+	}
+}
+
+// FileSink appends events at or above min to path as JSON lines. Close the
+// returned file when the scan ends.
+func FileSink(path string, min Level) (func(Event), io.Closer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return JSONSink(f, min), f, nil
+}
+
+// FormatConsole renders e as a ConsoleSink line.
+func FormatConsole(e Event) string {
+	var sb strings.Builder
+	switch {
+	case e.Level == LevelError:
+		sb.WriteString("[ERROR] ")
+	case e.Level == LevelWarn:
+		sb.WriteString("[!] ")
+	case e.Level == LevelTrace:
+		sb.WriteString("[TRACE] ")
+	case e.Level == LevelDebug:
+		sb.WriteString("[DEBUG] ")
+	case e.Type == EventLog && e.Module != "":
+	case e.Type == EventModuleFinished || e.Type == EventFindingRaised || e.Type == EventAgentDecision || e.Type == EventTargetFinished || e.Type == EventScanFinished:
+		sb.WriteString("[+] ")
+	default:
+		sb.WriteString("[*] ")
+	}
+	if e.Type == EventLog && e.Module != "" {
+		sb.WriteString("[" + e.Module + "] ")
+	} else if e.Target != "" {
+		sb.WriteString("[" + e.Target + "] ")
+	}
+	sb.WriteString(e.Message)
+	return sb.String()
+}
@@ -0,0 +1,306 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MonitorAgent is the schedule name for a full agent-driven scan.
+const MonitorAgent = "agent"
+
+// Monitor rescans targets on per-module schedules. It keeps the latest
+// state of every target, where a module's new results replace its old
+// ones, and after each run publishes what changed as an EventScanDiff.
+type Monitor struct {
+	// Runner is the template for every run; Modules, Graph, Checkpoint,
+	// Recorder and Prior are set per run.
+	Runner *Runner
+	// Schedules maps module names (or MonitorAgent) to ParseSchedule specs.
+	Schedules map[string]string
+	// SkipInitial waits for the first scheduled time instead of running
+	// every scheduled module at startup.
+	SkipInitial bool
+	// DB, when set, stores the state after every run as a scan in
+	// Workspace, and the latest such scan is the baseline on startup.
+	DB        *DB
+	Workspace string
+
+	states map[string]*monitorState // by target
+	last   ScanSnapshot             // state after the previous run
+	runs   int
+}
+
+// monitorState is the latest known state of one target.
+type monitorState struct {
+	results map[string]Result // by module
+	store   *Store
+	assets  *AssetGraph
+}
+
+// Run monitors targets until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context, targets []Target) error {
+	log := m.Runner.Bus.Logger("", "monitor")
+	if len(m.Schedules) == 0 {
+		return fmt.Errorf("no monitor schedules configured")
+	}
+	schedules := make(map[string]Schedule, len(m.Schedules))
+	var names []string
+	for name, spec := range m.Schedules {
+		if _, ok := m.Runner.Engine.Module(name); !ok && name != MonitorAgent {
+			return fmt.Errorf("monitor schedule for unknown module %s", name)
+		}
+		s, err := ParseSchedule(spec)
+		if err != nil {
+			return fmt.Errorf("monitor schedule for %s: %v", name, err)
+		}
+		schedules[name] = s
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m.states = make(map[string]*monitorState)
+	if err := m.loadBaseline(targets); err != nil {
+		log.Warnf("Starting without a baseline: %v", err)
+	} else if m.last.ID != "" {
+		log.Infof("Continuing from scan %s (%s)", m.last.ID, m.last.Time.Format(time.RFC3339))
+	}
+
+	now := time.Now()
+	next := make(map[string]time.Time, len(schedules))
+	for name, s := range schedules {
+		next[name] = now
+		if m.SkipInitial {
+			next[name] = s.Next(now)
+		}
+	}
+	for {
+		var wake time.Time
+		for _, name := range names {
+			if t := next[name]; !t.IsZero() && (wake.IsZero() || t.Before(wake)) {
+				wake = t
+			}
+		}
+		if wake.IsZero() {
+			return fmt.Errorf("no upcoming monitor runs")
+		}
+		if wait := time.Until(wake); wait > 0 {
+			log.Infof("Next run at %s: %s", wake.Format(time.RFC3339), strings.Join(dueAt(names, next, wake), ", "))
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		due := dueAt(names, next, time.Now())
+		m.runOnce(ctx, targets, due)
+		now = time.Now()
+		for _, name := range due {
+			next[name] = schedules[name].Next(now)
+		}
+	}
+}
+
+// dueAt lists the names scheduled at or before t.
+func dueAt(names []string, next map[string]time.Time, t time.Time) []string {
+	var due []string
+	for _, name := range names {
+		if n := next[name]; !n.IsZero() && !n.After(t) {
+			due = append(due, name)
+		}
+	}
+	return due
+}
+
+// runOnce runs the due modules against every target, folds the results
+// into the monitored state, stores it and publishes the changes.
+func (m *Monitor) runOnce(ctx context.Context, targets []Target, due []string) {
+	log := m.Runner.Bus.Logger("", "monitor")
+	m.runs++
+	log.Infof("Monitor run %d: %s", m.runs, strings.Join(due, ", "))
+
+	var modules []string
+	agent := false
+	for _, name := range due {
+		if name == MonitorAgent {
+			agent = true
+		} else {
+			modules = append(modules, name)
+		}
+	}
+	// The agent picks its own modules; scheduled modules run as a graph
+	var passes [][]string
+	if agent {
+		passes = append(passes, nil)
+	}
+	if len(modules) > 0 {
+		passes = append(passes, modules)
+	}
+	for _, pass := range passes {
+		run := *m.Runner
+		run.Modules = pass
+		run.Graph = len(pass) > 0
+		run.Checkpoint = nil
+		run.Recorder = nil
+		run.Prior = m.prior
+		for _, scan := range run.Run(ctx, targets) {
+			m.fold(scan)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	status := ScanFinished
+	if ctx.Err() != nil {
+		status = ScanInterrupted
+	}
+	current := m.snapshot(fmt.Sprintf("monitor-run-%d", m.runs))
+	if m.DB != nil {
+		rec, err := m.DB.StartScan(m.Workspace, "", targets, due)
+		if err == nil {
+			for label, st := range m.states {
+				if err = rec.Save(label, sortedResults(st.results), st.store, st.assets); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil {
+			err = rec.Finish(status)
+		}
+		if err != nil {
+			log.Errorf("Failed to record monitor run in workspace %s: %v", m.Workspace, err)
+		} else {
+			current.ID = rec.ID()
+		}
+	}
+
+	if m.last.ID != "" {
+		d := DiffScans(m.last, current)
+		if d.Empty() {
+			log.Infof("No changes since %s", m.last.ID)
+		} else {
+			d.Publish(log)
+		}
+	} else {
+		log.Infof("Baseline recorded as %s", current.ID)
+	}
+	if status == ScanFinished {
+		m.last = current
+	}
+}
+
+// prior hands a run a copy of the target's last data store.
+func (m *Monitor) prior(target string) *Store {
+	st, ok := m.states[target]
+	if !ok || st.store == nil {
+		return nil
+	}
+	store, err := st.store.Clone()
+	if err != nil {
+		return nil
+	}
+	return store
+}
+
+// fold replaces what the modules that ran reported before with their new
+// results and assets.
+func (m *Monitor) fold(scan TargetScan) {
+	label := scan.Target.String()
+	st, ok := m.states[label]
+	if !ok {
+		st = &monitorState{results: make(map[string]Result), assets: NewAssetGraph()}
+		m.states[label] = st
+	}
+	var ran []string
+	for _, r := range scan.Results {
+		st.results[r.ModuleName] = r
+		ran = append(ran, r.ModuleName)
+	}
+	st.assets = st.assets.Without(ran...)
+	st.assets.Merge(scan.Assets)
+	if scan.Store != nil {
+		st.store = scan.Store
+	}
+}
+
+// snapshot merges the state of every target.
+func (m *Monitor) snapshot(id string) ScanSnapshot {
+	snap := ScanSnapshot{ID: id, Time: time.Now(), Assets: NewAssetGraph()}
+	for label, st := range m.states {
+		snap.Targets = append(snap.Targets, label)
+		snap.Results = append(snap.Results, sortedResults(st.results)...)
+		snap.Assets.Merge(st.assets)
+	}
+	sort.Strings(snap.Targets)
+	return snap
+}
+
+// loadBaseline restores the state from the latest finished scan of the
+// same targets in the workspace.
+func (m *Monitor) loadBaseline(targets []Target) error {
+	if m.DB == nil {
+		return nil
+	}
+	var labels []string
+	for _, t := range targets {
+		labels = append(labels, t.String())
+	}
+	scans, err := m.DB.Scans(m.Workspace)
+	if err != nil {
+		return err
+	}
+	for _, rec := range scans {
+		if rec.Status != ScanFinished || !sameStrings(rec.Targets, labels) {
+			continue
+		}
+		records, err := m.DB.Targets(m.Workspace, rec.ID)
+		if err != nil {
+			return err
+		}
+		for label, tr := range records {
+			st := &monitorState{results: make(map[string]Result), store: tr.Store, assets: tr.Assets}
+			if st.assets == nil {
+				st.assets = NewAssetGraph()
+			}
+			for _, r := range tr.Results {
+				st.results[r.ModuleName] = r
+			}
+			m.states[label] = st
+		}
+		m.last = m.snapshot(rec.ID)
+		m.last.Time = rec.Finished
+		return nil
+	}
+	return nil
+}
+
+func sortedResults(byModule map[string]Result) []Result {
+	names := make([]string, 0, len(byModule))
+	for name := range byModule {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		results = append(results, byModule[name])
+	}
+	return results
+}
+
+// sameStrings reports whether a and b hold the same values in any order.
+func sameStrings(a, b []string) bool {
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, "\n") == strings.Join(b, "\n")
+}
@@ -0,0 +1,514 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Notification triggers.
+const (
+	NotifyScanFinished = "scan_finished"
+	NotifyFinding      = "finding"    // a finding at or above MinSeverity
+	NotifyNewAssets    = "new_assets" // a scan diff with added assets, e.g. from monitor mode
+)
+
+var notifyTriggers = []string{NotifyScanFinished, NotifyFinding, NotifyNewAssets}
+
+// DefaultNotifySeverity is the lowest severity notified when none is set.
+const DefaultNotifySeverity = SeverityHigh
+
+// DefaultNotifyRetry applies to notification delivery.
+var DefaultNotifyRetry = RetryPolicy{
+	Attempts:   4,
+	Initial:    2 * time.Second,
+	Max:        time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+	On:         allRetryClasses,
+}
+
+// NotifyConfig sends scan events to webhooks, chat and email.
+type NotifyConfig struct {
+	// On lists the triggers (scan_finished, finding, new_assets); empty
+	// means all of them.
+	On []string `json:"on,omitempty"`
+	// MinSeverity is the lowest finding severity notified (default high).
+	MinSeverity string `json:"min_severity,omitempty"`
+	// DedupeWindow sends a repeated notification again only after this
+	// long, e.g. "24h"; by default a repeat is never sent twice per run.
+	DedupeWindow string `json:"dedupe_window,omitempty"`
+	// Retry overrides DefaultNotifyRetry.
+	Retry    *RetrySpec      `json:"retry,omitempty"`
+	Channels []NotifyChannel `json:"channels,omitempty"`
+}
+
+// NotifyChannel is one destination. Type is webhook, slack, mattermost,
+// discord or email; On and MinSeverity override the NotifyConfig values.
+type NotifyChannel struct {
+	Name        string   `json:"name,omitempty"`
+	Type        string   `json:"type"`
+	On          []string `json:"on,omitempty"`
+	MinSeverity string   `json:"min_severity,omitempty"`
+
+	// URL is the webhook or incoming-webhook address.
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Template renders a webhook's JSON body from the Notification with
+	// text/template, e.g. {"text": {{json .Title}}}; the default body is
+	// the Notification itself.
+	Template string `json:"template,omitempty"`
+
+	// SMTP is the mail server as host:port; Username enables PLAIN auth.
+	SMTP     string   `json:"smtp,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Notification is what a channel delivers.
+type Notification struct {
+	Trigger  string    `json:"trigger"`
+	Time     time.Time `json:"time"`
+	Title    string    `json:"title"`
+	Text     string    `json:"text"`
+	Target   string    `json:"target,omitempty"`
+	Severity Severity  `json:"severity,omitempty"`
+	Finding  *Finding  `json:"finding,omitempty"`
+	Diff     *ScanDiff `json:"diff,omitempty"`
+
+	key string // identifies repeats
+}
+
+// Notifier turns bus events into notifications and delivers them in the
+// background. Subscribe Handle to the bus and Close it when done.
+type Notifier struct {
+	channels []*notifyChannel
+	window   time.Duration
+	retry    RetryPolicy
+	log      *Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	seen     map[string]time.Time // channel and key -> last sent
+	findings map[Severity]int     // raised since the scan started
+	closed   bool
+}
+
+type notifyChannel struct {
+	name  string
+	on    []string
+	min   Severity
+	send  func(ctx context.Context, n Notification) error
+	queue chan Notification
+}
+
+// NewNotifier validates cfg and starts a delivery worker per channel.
+// Delivery failures are reported through log.
+func NewNotifier(cfg NotifyConfig, log *Logger) (*Notifier, error) {
+	channels, err := cfg.channels()
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifier{
+		channels: channels,
+		retry:    DefaultNotifyRetry,
+		log:      log,
+		seen:     make(map[string]time.Time),
+		findings: make(map[Severity]int),
+	}
+	if cfg.DedupeWindow != "" {
+		n.window, _ = time.ParseDuration(cfg.DedupeWindow)
+	}
+	if cfg.Retry != nil {
+		n.retry, _ = cfg.Retry.apply(n.retry)
+	}
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	for _, ch := range channels {
+		ch.queue = make(chan Notification, 100)
+		n.wg.Add(1)
+		go n.deliver(ch)
+	}
+	return n, nil
+}
+
+// Close stops accepting notifications and waits up to timeout for the
+// queued ones to be delivered.
+func (n *Notifier) Close(timeout time.Duration) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	for _, ch := range n.channels {
+		close(ch.queue)
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		n.cancel()
+		<-done
+	}
+	n.cancel()
+}
+
+// Handle is a bus subscriber.
+func (n *Notifier) Handle(e Event) {
+	switch e.Type {
+	case EventScanStarted:
+		n.mu.Lock()
+		n.findings = make(map[Severity]int)
+		n.mu.Unlock()
+	case EventFindingRaised:
+		if e.Finding == nil {
+			return
+		}
+		n.mu.Lock()
+		n.findings[e.Finding.Severity]++
+		n.mu.Unlock()
+		n.Notify(findingNotification(e))
+	case EventScanFinished:
+		n.mu.Lock()
+		counts := make(map[Severity]int, len(n.findings))
+		for sev, c := range n.findings {
+			counts[sev] = c
+		}
+		n.mu.Unlock()
+		n.Notify(scanNotification(e, counts))
+	case EventScanDiff:
+		if d, ok := e.Fields["diff"].(ScanDiff); ok && hasAdded(d) {
+			n.Notify(diffNotification(e, d))
+		}
+	}
+}
+
+// Notify queues msg on every channel that wants it and has not sent it
+// within the dedupe window.
+func (n *Notifier) Notify(msg Notification) {
+	if n == nil {
+		return
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if msg.key == "" {
+		msg.key = msg.Trigger + "|" + msg.Time.Format(time.RFC3339Nano)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	for _, ch := range n.channels {
+		if !ch.wants(msg) {
+			continue
+		}
+		key := ch.name + "|" + msg.key
+		if last, ok := n.seen[key]; ok && (n.window == 0 || msg.Time.Sub(last) < n.window) {
+			continue
+		}
+		select {
+		case ch.queue <- msg:
+			n.seen[key] = msg.Time
+		default:
+			n.log.Warnf("Notification queue for %s is full, dropping: %s", ch.name, msg.Title)
+		}
+	}
+}
+
+func (n *Notifier) deliver(ch *notifyChannel) {
+	defer n.wg.Done()
+	for msg := range ch.queue {
+		if n.ctx.Err() != nil {
+			continue
+		}
+		_, err := n.retry.Do(n.ctx, func() error {
+			ctx, cancel := context.WithTimeout(n.ctx, 30*time.Second)
+			defer cancel()
+			return ch.send(ctx, msg)
+		}, func(attempt int, wait time.Duration, err error) {
+			n.log.Debugf("Notification via %s failed (%v), retry %d in %s", ch.name, err, attempt, wait.Round(time.Millisecond))
+		})
+		if err != nil {
+			n.log.Warnf("Notification via %s failed: %v", ch.name, err)
+			// Let a later repeat try again
+			n.mu.Lock()
+			delete(n.seen, ch.name+"|"+msg.key)
+			n.mu.Unlock()
+			continue
+		}
+		n.log.Debugf("Sent %s notification via %s: %s", msg.Trigger, ch.name, msg.Title)
+	}
+}
+
+func (ch *notifyChannel) wants(msg Notification) bool {
+	if !containsString(ch.on, msg.Trigger) {
+		return false
+	}
+	return msg.Trigger != NotifyFinding || msg.Severity.Rank() >= ch.min.Rank()
+}
+
+// channels validates the config and builds its channels.
+func (c NotifyConfig) channels() ([]*notifyChannel, error) {
+	on, err := notifyOn(c.On, notifyTriggers)
+	if err != nil {
+		return nil, err
+	}
+	min := DefaultNotifySeverity
+	if c.MinSeverity != "" {
+		if min, err = ParseSeverity(c.MinSeverity); err != nil {
+			return nil, fmt.Errorf("notify: %v", err)
+		}
+	}
+	if c.DedupeWindow != "" {
+		if _, err := time.ParseDuration(c.DedupeWindow); err != nil {
+			return nil, fmt.Errorf("notify: invalid dedupe_window: %v", err)
+		}
+	}
+	if c.Retry != nil {
+		if _, err := c.Retry.apply(DefaultNotifyRetry); err != nil {
+			return nil, fmt.Errorf("notify: %v", err)
+		}
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	var out []*notifyChannel
+	names := make(map[string]bool)
+	for i, cc := range c.Channels {
+		ch := &notifyChannel{name: cc.Name, min: min}
+		if ch.name == "" {
+			ch.name = fmt.Sprintf("%s#%d", cc.Type, i+1)
+		}
+		if names[ch.name] {
+			return nil, fmt.Errorf("notify: duplicate channel name %s", ch.name)
+		}
+		names[ch.name] = true
+		if ch.on, err = notifyOn(cc.On, on); err != nil {
+			return nil, fmt.Errorf("notify channel %s: %v", ch.name, err)
+		}
+		if cc.MinSeverity != "" {
+			if ch.min, err = ParseSeverity(cc.MinSeverity); err != nil {
+				return nil, fmt.Errorf("notify channel %s: %v", ch.name, err)
+			}
+		}
+		if ch.send, err = cc.sender(client); err != nil {
+			return nil, fmt.Errorf("notify channel %s: %v", ch.name, err)
+		}
+		out = append(out, ch)
+	}
+	return out, nil
+}
+
+// notifyOn validates a trigger list, defaulting to def.
+func notifyOn(list, def []string) ([]string, error) {
+	if len(list) == 0 {
+		return def, nil
+	}
+	for _, t := range list {
+		if !containsString(notifyTriggers, t) {
+			return nil, fmt.Errorf("unknown notification trigger %q (want %s)", t, strings.Join(notifyTriggers, ", "))
+		}
+	}
+	return list, nil
+}
+
+// sender returns the delivery function for the channel type.
+func (c NotifyChannel) sender(client *http.Client) (func(context.Context, Notification) error, error) {
+	switch c.Type {
+	case "webhook", "slack", "mattermost", "discord":
+		if c.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+	}
+	switch c.Type {
+	case "webhook":
+		body := func(n Notification) ([]byte, error) { return json.Marshal(n) }
+		if c.Template != "" {
+			tmpl, err := template.New(c.Name).Funcs(template.FuncMap{"json": toJSON}).Parse(c.Template)
+			if err != nil {
+				return nil, fmt.Errorf("invalid template: %v", err)
+			}
+			body = func(n Notification) ([]byte, error) {
+				var buf bytes.Buffer
+				err := tmpl.Execute(&buf, n)
+				return buf.Bytes(), err
+			}
+		}
+		return func(ctx context.Context, n Notification) error {
+			data, err := body(n)
+			if err != nil {
+				return err
+			}
+			return postJSON(ctx, client, c.URL, c.Headers, data)
+		}, nil
+	case "slack", "mattermost":
+		return func(ctx context.Context, n Notification) error {
+			data, _ := json.Marshal(map[string]string{"text": "*" + n.Title + "*\n" + n.Text})
+			return postJSON(ctx, client, c.URL, c.Headers, data)
+		}, nil
+	case "discord":
+		return func(ctx context.Context, n Notification) error {
+			data, _ := json.Marshal(map[string]string{"content": truncate("**"+n.Title+"**\n"+n.Text, 2000)})
+			return postJSON(ctx, client, c.URL, c.Headers, data)
+		}, nil
+	case "email":
+		if c.SMTP == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("smtp, from and to are required")
+		}
+		host, _, _ := strings.Cut(c.SMTP, ":")
+		var auth smtp.Auth
+		if c.Username != "" {
+			auth = smtp.PlainAuth("", c.Username, c.Password, host)
+		}
+		return func(ctx context.Context, n Notification) error {
+			msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+				c.From, strings.Join(c.To, ", "), n.Title, n.Time.Format(time.RFC1123Z), strings.ReplaceAll(n.Text, "\n", "\r\n"))
+			// net/smtp has no context support; the connection ends with the send
+			errc := make(chan error, 1)
+			go func() { errc <- smtp.SendMail(c.SMTP, auth, c.From, c.To, []byte(msg)) }()
+			select {
+			case err := <-errc:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, nil
+	case "":
+		return nil, fmt.Errorf("type is required (webhook, slack, mattermost, discord or email)")
+	}
+	return nil, fmt.Errorf("unknown type %q (want webhook, slack, mattermost, discord or email)", c.Type)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return CheckStatus("webhook", resp)
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func findingNotification(e Event) Notification {
+	f := e.Finding
+	text := fmt.Sprintf("%s found %s on %s", f.Module, f.Title, f.Asset)
+	if f.Confidence != "" {
+		text += fmt.Sprintf(" (%s confidence)", f.Confidence)
+	}
+	if e.Target != "" {
+		text += "\nTarget: " + e.Target
+	}
+	if len(f.References) > 0 {
+		text += "\nReferences: " + strings.Join(f.References, ", ")
+	}
+	if f.Remediation != "" {
+		text += "\nRemediation: " + f.Remediation
+	}
+	return Notification{
+		Trigger:  NotifyFinding,
+		Time:     e.Time,
+		Title:    fmt.Sprintf("[%s] %s", strings.ToUpper(string(f.Severity)), f.Title),
+		Text:     text,
+		Target:   e.Target,
+		Severity: f.Severity,
+		Finding:  f,
+		key:      NotifyFinding + "|" + e.Target + "|" + f.ID,
+	}
+}
+
+func scanNotification(e Event, findings map[Severity]int) Notification {
+	var counts []string
+	for _, sev := range Severities {
+		if n := findings[sev]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, sev))
+		}
+	}
+	text := e.Message
+	if len(counts) > 0 {
+		text += "\nFindings: " + strings.Join(counts, ", ")
+	} else {
+		text += "\nNo findings"
+	}
+	return Notification{Trigger: NotifyScanFinished, Time: e.Time, Title: "Triksha scan finished", Text: text}
+}
+
+func diffNotification(e Event, d ScanDiff) Notification {
+	var lines []string
+	for _, s := range []struct {
+		name string
+		c    SetChange
+	}{
+		{"subdomain", d.Subdomains}, {"IP", d.IPs}, {"port", d.Ports},
+		{"technology", d.Technologies}, {"URL", d.URLs},
+	} {
+		for _, v := range s.c.Added {
+			lines = append(lines, fmt.Sprintf("+ %s %s", s.name, v))
+		}
+	}
+	if len(lines) > 50 {
+		lines = append(lines[:50], fmt.Sprintf("... and %d more", len(lines)-50))
+	}
+	sum := sha1.Sum([]byte(strings.Join(d.New.Targets, ",") + "\n" + strings.Join(lines, "\n")))
+	return Notification{
+		Trigger: NotifyNewAssets,
+		Time:    e.Time,
+		Title:   "New assets on " + strings.Join(d.New.Targets, ", "),
+		Text:    d.Summary() + "\n" + strings.Join(lines, "\n"),
+		Diff:    &d,
+		key:     NotifyNewAssets + "|" + hex.EncodeToString(sum[:]),
+	}
+}
+
+// hasAdded reports whether the diff found assets the old scan did not have.
+func hasAdded(d ScanDiff) bool {
+	for _, c := range []SetChange{d.Subdomains, d.IPs, d.Ports, d.Technologies, d.URLs} {
+		if len(c.Added) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most max characters.
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
@@ -0,0 +1,330 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamType names the accepted type of a module parameter.
+type ParamType string
+
+const (
+	ParamString     ParamType = "string"
+	ParamInt        ParamType = "int"
+	ParamBool       ParamType = "bool"
+	ParamDuration   ParamType = "duration"
+	ParamStringList ParamType = "[]string"
+	ParamIntList    ParamType = "[]int" // also accepts ranges, e.g. "80,443,8000-8010"
+	// ParamPath is a file under the wordlist directory, e.g. "dirs.txt";
+	// absolute paths and ".." are rejected. Modules open it through
+	// Context.Wordlist.
+	ParamPath ParamType = "path"
+)
+
+// ParamSpec describes one parameter a module accepts.
+type ParamSpec struct {
+	Name        string      `json:"name"`
+	Type        ParamType   `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description"`
+}
+
+// Parameterized is implemented by modules that accept parameters.
+type Parameterized interface {
+	Params() []ParamSpec
+}
+
+// Params holds validated parameter values, keyed by name. Values always have
+// the Go type matching their spec: string, int, bool, time.Duration,
+// []string or []int.
+type Params map[string]interface{}
+
+// String returns a string parameter, or "" when unset.
+func (p Params) String(name string) string {
+	v, _ := p[name].(string)
+	return v
+}
+
+// Int returns an int parameter, or 0 when unset.
+func (p Params) Int(name string) int {
+	v, _ := p[name].(int)
+	return v
+}
+
+// Bool returns a bool parameter, or false when unset.
+func (p Params) Bool(name string) bool {
+	v, _ := p[name].(bool)
+	return v
+}
+
+// Duration returns a duration parameter, or 0 when unset.
+func (p Params) Duration(name string) time.Duration {
+	v, _ := p[name].(time.Duration)
+	return v
+}
+
+// StringList returns a []string parameter, or nil when unset.
+func (p Params) StringList(name string) []string {
+	v, _ := p[name].([]string)
+	return v
+}
+
+// IntList returns a []int parameter, or nil when unset.
+func (p Params) IntList(name string) []int {
+	v, _ := p[name].([]int)
+	return v
+}
+
+// ResolveParams starts from the spec defaults and applies each layer in
+// order, so later layers win. Unknown names and values that cannot be
+// converted to the declared type are errors.
+func ResolveParams(specs []ParamSpec, layers ...map[string]interface{}) (Params, error) {
+	byName := make(map[string]ParamSpec, len(specs))
+	out := make(Params, len(specs))
+	for _, spec := range specs {
+		byName[spec.Name] = spec
+		if spec.Default == nil {
+			continue
+		}
+		v, err := coerceParam(spec.Type, spec.Default)
+		if err != nil {
+			return nil, fmt.Errorf("default for parameter %s: %v", spec.Name, err)
+		}
+		out[spec.Name] = v
+	}
+
+	for _, layer := range layers {
+		for _, name := range sortedParamNames(layer) {
+			spec, ok := byName[name]
+			if !ok && len(specs) == 0 {
+				return nil, fmt.Errorf("unknown parameter %q: module takes no parameters", name)
+			}
+			if !ok {
+				return nil, fmt.Errorf("unknown parameter %q (accepted: %s)", name, strings.Join(specNames(specs), ", "))
+			}
+			v, err := coerceParam(spec.Type, layer[name])
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s: %v", name, err)
+			}
+			out[name] = v
+		}
+	}
+	return out, nil
+}
+
+// ParseParamFlag splits a CLI "module.name=value" assignment.
+func ParseParamFlag(s string) (module, name, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", "", fmt.Errorf("invalid parameter %q, want module.name=value", s)
+	}
+	module, name, ok = strings.Cut(strings.TrimSpace(key), ".")
+	if !ok || module == "" || name == "" {
+		return "", "", "", fmt.Errorf("invalid parameter %q, want module.name=value", s)
+	}
+	return module, name, value, nil
+}
+
+func coerceParam(t ParamType, v interface{}) (interface{}, error) {
+	switch t {
+	case ParamString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case ParamPath:
+		if s, ok := v.(string); ok {
+			return cleanParamPath(s)
+		}
+	case ParamInt:
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case int64:
+			return int(n), nil
+		case float64:
+			if n == math.Trunc(n) {
+				return int(n), nil
+			}
+		case string:
+			i, err := strconv.Atoi(strings.TrimSpace(n))
+			if err == nil {
+				return i, nil
+			}
+		}
+	case ParamBool:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(b))
+			if err == nil {
+				return parsed, nil
+			}
+		}
+	case ParamDuration:
+		switch d := v.(type) {
+		case time.Duration:
+			return d, nil
+		case float64: // JSON numbers are taken as seconds
+			return time.Duration(d * float64(time.Second)), nil
+		case int:
+			return time.Duration(d) * time.Second, nil
+		case string:
+			parsed, err := time.ParseDuration(strings.TrimSpace(d))
+			if err == nil {
+				return parsed, nil
+			}
+		}
+	case ParamStringList:
+		switch l := v.(type) {
+		case []string:
+			return l, nil
+		case string:
+			return splitList(l), nil
+		case []interface{}:
+			out := make([]string, 0, len(l))
+			for _, item := range l {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected %s, got element %v", t, item)
+				}
+				out = append(out, s)
+			}
+			return out, nil
+		}
+	case ParamIntList:
+		switch l := v.(type) {
+		case []int:
+			return l, nil
+		case string:
+			return parseIntList(l)
+		case []interface{}:
+			out := make([]int, 0, len(l))
+			for _, item := range l {
+				n, err := coerceParam(ParamInt, item)
+				if err != nil {
+					return nil, fmt.Errorf("expected %s, got element %v", t, item)
+				}
+				out = append(out, n.(int))
+			}
+			return out, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parameter type %s", t)
+	}
+	return nil, fmt.Errorf("expected %s, got %v", t, v)
+}
+
+// cleanParamPath accepts a relative path that cannot leave the directory
+// it is resolved in.
+func cleanParamPath(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if filepath.IsAbs(s) || strings.HasPrefix(s, "/") || strings.HasPrefix(s, `\`) {
+		return "", fmt.Errorf("path %q must be relative to the wordlist directory", s)
+	}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", fmt.Errorf("path %q may not contain ..", s)
+		}
+	}
+	return filepath.Clean(s), nil
+}
+
+// UntrustedParams drops the path parameters of a module from params set
+// by the LLM agent or an API request, unless allowed lists them as
+// "module.name". It returns the kept params and the dropped names.
+func UntrustedParams(specs []ParamSpec, module string, params map[string]interface{}, allowed []string) (map[string]interface{}, []string) {
+	var dropped []string
+	kept := make(map[string]interface{}, len(params))
+	for name, v := range params {
+		if paramType(specs, name) == ParamPath && !containsString(allowed, module+"."+name) {
+			dropped = append(dropped, name)
+			continue
+		}
+		kept[name] = v
+	}
+	sort.Strings(dropped)
+	return kept, dropped
+}
+
+func paramType(specs []ParamSpec, name string) ParamType {
+	for _, spec := range specs {
+		if spec.Name == name {
+			return spec.Type
+		}
+	}
+	return ""
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// MaxIntListSize caps how many numbers an int list may expand to, which
+// is enough for every TCP port.
+const MaxIntListSize = 65536
+
+// parseIntList parses "80,443,8000-8010" into individual numbers.
+func parseIntList(s string) ([]int, error) {
+	var out []int
+	for _, part := range splitList(s) {
+		start, end, err := parseIntRange(part)
+		if err != nil {
+			return nil, err
+		}
+		if end-start >= MaxIntListSize-len(out) {
+			return nil, fmt.Errorf("list %q expands to more than %d numbers", s, MaxIntListSize)
+		}
+		for i := start; i <= end; i++ {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+// parseIntRange parses "8000-8010", or a single number as a range of one.
+func parseIntRange(part string) (start, end int, err error) {
+	lo, hi, isRange := strings.Cut(part, "-")
+	start, err = strconv.Atoi(lo)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid number %q", part)
+	}
+	end = start
+	if isRange {
+		if end, err = strconv.Atoi(hi); err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid range %q", part)
+		}
+	}
+	return start, end, nil
+}
+
+func sortedParamNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func specNames(specs []ParamSpec) []string {
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	return names
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseIntList(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantLen int // checked instead of want for long lists
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "80,443", want: []int{80, 443}},
+		{in: " 1 , 2-4 ,", want: []int{1, 2, 3, 4}},
+		{in: "8000-8000", want: []int{8000}},
+		{in: "1-65536", wantLen: MaxIntListSize},
+		{in: "0-65536", wantErr: true},
+		{in: "1-60000,60001-70000", wantErr: true},
+		{in: "1-2000000000", wantErr: true},
+		{in: "5-1", wantErr: true},
+		{in: "-5", wantErr: true},
+		{in: "1-x", wantErr: true},
+		{in: "http", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseIntList(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIntList(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantLen > 0 {
+			if len(got) != tt.wantLen {
+				t.Errorf("parseIntList(%q) has %d numbers, want %d", tt.in, len(got), tt.wantLen)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIntList(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCoerceParam(t *testing.T) {
+	tests := []struct {
+		typ     ParamType
+		in      interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{typ: ParamString, in: "x", want: "x"},
+		{typ: ParamString, in: 1, wantErr: true},
+		{typ: ParamInt, in: 5, want: 5},
+		{typ: ParamInt, in: float64(5), want: 5},
+		{typ: ParamInt, in: " 7 ", want: 7},
+		{typ: ParamInt, in: 5.5, wantErr: true},
+		{typ: ParamInt, in: "five", wantErr: true},
+		{typ: ParamBool, in: true, want: true},
+		{typ: ParamBool, in: "false", want: false},
+		{typ: ParamBool, in: "maybe", wantErr: true},
+		{typ: ParamDuration, in: "2s", want: 2 * time.Second},
+		{typ: ParamDuration, in: float64(1.5), want: 1500 * time.Millisecond},
+		{typ: ParamDuration, in: 3, want: 3 * time.Second},
+		{typ: ParamDuration, in: "soon", wantErr: true},
+		{typ: ParamStringList, in: "a, b,,c", want: []string{"a", "b", "c"}},
+		{typ: ParamStringList, in: []interface{}{"a", "b"}, want: []string{"a", "b"}},
+		{typ: ParamStringList, in: []interface{}{"a", 1}, wantErr: true},
+		{typ: ParamIntList, in: "80,8000-8002", want: []int{80, 8000, 8001, 8002}},
+		{typ: ParamIntList, in: []interface{}{float64(22), "443"}, want: []int{22, 443}},
+		{typ: ParamIntList, in: []interface{}{"ssh"}, wantErr: true},
+		{typ: ParamPath, in: "dirs.txt", want: "dirs.txt"},
+		{typ: ParamPath, in: "big/./dirs.txt", want: "big/dirs.txt"},
+		{typ: ParamPath, in: "", want: ""},
+		{typ: ParamPath, in: "/etc/shadow", wantErr: true},
+		{typ: ParamPath, in: "../secrets", wantErr: true},
+		{typ: ParamPath, in: "lists/../../secrets", wantErr: true},
+		{typ: ParamPath, in: `..\secrets`, wantErr: true},
+		{typ: "float", in: 1.0, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := coerceParam(tt.typ, tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("coerceParam(%s, %#v) error = %v, wantErr %v", tt.typ, tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("coerceParam(%s, %#v) = %#v, want %#v", tt.typ, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+	specs := []ParamSpec{
+		{Name: "threads", Type: ParamInt, Default: 10},
+		{Name: "ports", Type: ParamIntList, Default: "80,443"},
+		{Name: "wordlist", Type: ParamPath, Default: "dirs.txt"},
+	}
+	got, err := ResolveParams(specs,
+		map[string]interface{}{"threads": "20", "ports": "22"},
+		map[string]interface{}{"threads": float64(30)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Params{"threads": 30, "ports": []int{22}, "wordlist": "dirs.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveParams = %v, want %v", got, want)
+	}
+
+	for _, layer := range []map[string]interface{}{
+		{"unknown": 1},
+		{"threads": "many"},
+		{"wordlist": "/etc/passwd"},
+	} {
+		if _, err := ResolveParams(specs, layer); err == nil {
+			t.Errorf("ResolveParams(%v) succeeded, want an error", layer)
+		}
+	}
+	if _, err := ResolveParams(nil, map[string]interface{}{"x": 1}); err == nil {
+		t.Error("ResolveParams without specs accepted a parameter")
+	}
+	if _, err := ResolveParams([]ParamSpec{{Name: "n", Type: ParamInt, Default: "x"}}); err == nil {
+		t.Error("ResolveParams accepted an invalid default")
+	}
+}
+
+func TestUntrustedParams(t *testing.T) {
+	specs := []ParamSpec{
+		{Name: "wordlist", Type: ParamPath},
+		{Name: "threads", Type: ParamInt},
+	}
+	params := map[string]interface{}{"wordlist": "big.txt", "threads": 5}
+
+	kept, dropped := UntrustedParams(specs, "webenum", params, nil)
+	if !reflect.DeepEqual(kept, map[string]interface{}{"threads": 5}) || !reflect.DeepEqual(dropped, []string{"wordlist"}) {
+		t.Errorf("UntrustedParams without allowlist = %v, %v", kept, dropped)
+	}
+	kept, dropped = UntrustedParams(specs, "webenum", params, []string{"webenum.wordlist"})
+	if !reflect.DeepEqual(kept, params) || len(dropped) != 0 {
+		t.Errorf("UntrustedParams with allowlist = %v, %v", kept, dropped)
+	}
+	_, dropped = UntrustedParams(specs, "webenum", params, []string{"subdomain.wordlist"})
+	if !reflect.DeepEqual(dropped, []string{"wordlist"}) {
+		t.Errorf("another module's allowlist entry kept %v", dropped)
+	}
+}
+
+func TestParseParamFlag(t *testing.T) {
+	module, name, value, err := ParseParamFlag("webenum.threads=20")
+	if err != nil || module != "webenum" || name != "threads" || value != "20" {
+		t.Errorf("ParseParamFlag = %q, %q, %q, %v", module, name, value, err)
+	}
+	for _, in := range []string{"webenum.threads", "threads=20", ".threads=1", "webenum.=1"} {
+		if _, _, _, err := ParseParamFlag(in); err == nil {
+			t.Errorf("ParseParamFlag(%q) succeeded, want an error", in)
+		}
+	}
+}
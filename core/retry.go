@@ -0,0 +1,354 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryClass names a kind of transient failure a policy may retry.
+type RetryClass string
+
+const (
+	RetryTimeout     RetryClass = "timeout"
+	RetryRateLimit   RetryClass = "rate_limit"   // HTTP 429 or a source's quota message
+	RetryServerError RetryClass = "server_error" // HTTP 5xx
+	RetryDNSServfail RetryClass = "dns_servfail"
+	RetryNetwork     RetryClass = "network" // connection reset or cut short
+)
+
+var allRetryClasses = []RetryClass{RetryTimeout, RetryRateLimit, RetryServerError, RetryDNSServfail, RetryNetwork}
+
+// RetryPolicy retries classified failures with exponential backoff:
+// Initial, Initial*Multiplier, ... capped at Max, each spread by ±Jitter.
+type RetryPolicy struct {
+	Attempts   int // total tries; 1 disables retrying
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64 // fraction of the delay, 0 to 1
+	On         []RetryClass
+}
+
+// DefaultSourceRetry applies to third-party data sources and DNS lookups.
+var DefaultSourceRetry = RetryPolicy{
+	Attempts:   3,
+	Initial:    time.Second,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+	On:         allRetryClasses,
+}
+
+// DefaultModuleRetry applies to whole module runs, which are expensive to
+// repeat, so it does not retry unless configured.
+var DefaultModuleRetry = RetryPolicy{
+	Attempts:   1,
+	Initial:    5 * time.Second,
+	Max:        time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+	On:         []RetryClass{RetryTimeout, RetryRateLimit, RetryServerError, RetryNetwork},
+}
+
+// StatusError is a non-success HTTP answer from a target or data source.
+type StatusError struct {
+	Source     string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Source, e.Status)
+}
+
+// CheckStatus returns a *StatusError for a non-2xx response.
+func CheckStatus(source string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	e := &StatusError{Source: source, StatusCode: resp.StatusCode, Status: resp.Status}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
+// ClassifyError returns the retry class of err, or "" if it is permanent.
+func ClassifyError(err error) RetryClass {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrOutOfScope) || errors.Is(err, ErrModuleStuck) {
+		return ""
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch {
+		case status.StatusCode == http.StatusTooManyRequests:
+			return RetryRateLimit
+		case status.StatusCode >= 500:
+			return RetryServerError
+		}
+		return ""
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		switch {
+		case dnsErr.IsTimeout:
+			return RetryTimeout
+		case dnsErr.IsNotFound:
+			return ""
+		case dnsErr.IsTemporary:
+			// The Go resolver reports SERVFAIL as a temporary "server misbehaving"
+			return RetryDNSServfail
+		}
+		return ""
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return RetryTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return RetryTimeout
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return RetryNetwork
+	}
+	return ""
+}
+
+// retries reports whether the policy retries class.
+func (p RetryPolicy) retries(class RetryClass) bool {
+	for _, c := range p.On {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns the backoff before retry number n (1-based).
+func (p RetryPolicy) Delay(n int) time.Duration {
+	d := float64(p.Initial) * math.Pow(math.Max(p.Multiplier, 1), float64(n-1))
+	if p.Max > 0 && d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// Do calls fn until it succeeds, fails permanently, runs out of attempts
+// or ctx is done. onRetry, if set, sees each failure that will be retried.
+// It returns the number of retries made.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error, onRetry func(n int, wait time.Duration, err error)) (int, error) {
+	retries := 0
+	for {
+		err := fn()
+		if err == nil || ctx.Err() != nil || retries+1 >= p.Attempts {
+			return retries, err
+		}
+		class := ClassifyError(err)
+		if class == "" || !p.retries(class) {
+			return retries, err
+		}
+		retries++
+		wait := p.Delay(retries)
+		var status *StatusError
+		if errors.As(err, &status) && status.RetryAfter > wait {
+			wait = status.RetryAfter
+		}
+		if onRetry != nil {
+			onRetry(retries, wait, err)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return retries, err
+		}
+	}
+}
+
+// RetrySpec is the config form of a RetryPolicy; unset fields keep the
+// defaults.
+type RetrySpec struct {
+	Attempts   int      `json:"attempts,omitempty"`
+	Initial    string   `json:"initial,omitempty"`
+	Max        string   `json:"max,omitempty"`
+	Multiplier float64  `json:"multiplier,omitempty"`
+	Jitter     float64  `json:"jitter,omitempty"`
+	On         []string `json:"on,omitempty"`
+}
+
+// apply returns p with the spec's fields set.
+func (s RetrySpec) apply(p RetryPolicy) (RetryPolicy, error) {
+	if s.Attempts > 0 {
+		p.Attempts = s.Attempts
+	}
+	for _, f := range []struct {
+		raw string
+		dst *time.Duration
+	}{{s.Initial, &p.Initial}, {s.Max, &p.Max}} {
+		if f.raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(f.raw)
+		if err != nil {
+			return p, fmt.Errorf("invalid retry delay %q: %v", f.raw, err)
+		}
+		*f.dst = d
+	}
+	if s.Multiplier > 0 {
+		p.Multiplier = s.Multiplier
+	}
+	if s.Jitter < 0 || s.Jitter > 1 {
+		return p, fmt.Errorf("invalid retry jitter %g (want 0 to 1)", s.Jitter)
+	}
+	if s.Jitter > 0 {
+		p.Jitter = s.Jitter
+	}
+	if s.On != nil {
+		p.On = nil
+		for _, raw := range s.On {
+			class := RetryClass(strings.ToLower(raw))
+			if !containsClass(allRetryClasses, class) {
+				return p, fmt.Errorf("unknown retry class %q", raw)
+			}
+			p.On = append(p.On, class)
+		}
+	}
+	return p, nil
+}
+
+func containsClass(list []RetryClass, c RetryClass) bool {
+	for _, v := range list {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryConfig configures retries for module runs and data sources.
+type RetryConfig struct {
+	ModuleDefault RetrySpec            `json:"module_default,omitempty"`
+	SourceDefault RetrySpec            `json:"source_default,omitempty"`
+	Modules       map[string]RetrySpec `json:"modules,omitempty"`
+	Sources       map[string]RetrySpec `json:"sources,omitempty"`
+}
+
+// RetryPolicies resolves the policy for a module or data source. A nil
+// *RetryPolicies uses the built-in defaults.
+type RetryPolicies struct {
+	moduleDefault RetryPolicy
+	sourceDefault RetryPolicy
+	modules       map[string]RetryPolicy
+	sources       map[string]RetryPolicy
+}
+
+// Policies parses the config on top of the built-in defaults.
+func (c RetryConfig) Policies() (*RetryPolicies, error) {
+	var err error
+	p := &RetryPolicies{modules: map[string]RetryPolicy{}, sources: map[string]RetryPolicy{}}
+	if p.moduleDefault, err = c.ModuleDefault.apply(DefaultModuleRetry); err != nil {
+		return nil, fmt.Errorf("retry module_default: %v", err)
+	}
+	if p.sourceDefault, err = c.SourceDefault.apply(DefaultSourceRetry); err != nil {
+		return nil, fmt.Errorf("retry source_default: %v", err)
+	}
+	for name, spec := range c.Modules {
+		if p.modules[name], err = spec.apply(p.moduleDefault); err != nil {
+			return nil, fmt.Errorf("retry for module %s: %v", name, err)
+		}
+	}
+	for name, spec := range c.Sources {
+		if p.sources[name], err = spec.apply(p.sourceDefault); err != nil {
+			return nil, fmt.Errorf("retry for source %s: %v", name, err)
+		}
+	}
+	return p, nil
+}
+
+// Module returns the policy for whole runs of the named module.
+func (p *RetryPolicies) Module(name string) RetryPolicy {
+	if p == nil {
+		return DefaultModuleRetry
+	}
+	if policy, ok := p.modules[name]; ok {
+		return policy
+	}
+	return p.moduleDefault
+}
+
+// Source returns the policy for the named data source.
+func (p *RetryPolicies) Source(name string) RetryPolicy {
+	if p == nil {
+		return DefaultSourceRetry
+	}
+	if policy, ok := p.sources[name]; ok {
+		return policy
+	}
+	return p.sourceDefault
+}
+
+// WithRetry calls fetch under the source's retry policy on behalf of
+// module. Retries are logged and counted in the module's Result.Retries.
+func WithRetry[T any](ctx context.Context, rctx *Context, module, source string, fetch func() (T, error)) (T, error) {
+	var value T
+	log := rctx.Logger(module)
+	policy := rctx.retryPolicies().Source(source)
+	retries, err := policy.Do(ctx, func() error {
+		var err error
+		value, err = fetch()
+		return err
+	}, func(n int, wait time.Duration, err error) {
+		log.Warnf("Retrying %s in %s (attempt %d/%d): %v", source, wait.Round(time.Millisecond), n+1, policy.Attempts, err)
+	})
+	rctx.addRetries(module, source, retries)
+	return value, err
+}
+
+func (c *Context) retryPolicies() *RetryPolicies {
+	if c == nil {
+		return nil
+	}
+	return c.Retry
+}
+
+// addRetries counts retries made by module against source.
+func (c *Context) addRetries(module, source string, n int) {
+	if c == nil || n == 0 {
+		return
+	}
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+	if c.retries == nil {
+		c.retries = make(map[string]map[string]int)
+	}
+	if c.retries[module] == nil {
+		c.retries[module] = make(map[string]int)
+	}
+	c.retries[module][source] += n
+}
+
+// takeRetries returns and clears the retry counts recorded for module.
+func (c *Context) takeRetries(module string) map[string]int {
+	if c == nil {
+		return nil
+	}
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+	counts := c.retries[module]
+	delete(c.retries, module)
+	return counts
+}
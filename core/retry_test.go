@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want RetryClass
+	}{
+		{"nil", nil, ""},
+		{"plain", errors.New("boom"), ""},
+		{"canceled", fmt.Errorf("module x: %w", context.Canceled), ""},
+		{"deadline", fmt.Errorf("module x: %w", context.DeadlineExceeded), RetryTimeout},
+		{"stuck module", fmt.Errorf("module x: %w (%w after 10s)", context.DeadlineExceeded, ErrModuleStuck), ""},
+		{"out of scope", outOfScope("evil.com", "not matched"), ""},
+		{"429", &StatusError{Source: "crtsh", StatusCode: 429}, RetryRateLimit},
+		{"503", fmt.Errorf("lookup: %w", &StatusError{Source: "crtsh", StatusCode: 503}), RetryServerError},
+		{"404", &StatusError{Source: "crtsh", StatusCode: 404}, ""},
+		{"dns timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, RetryTimeout},
+		{"dns nxdomain", &net.DNSError{Err: "no such host", IsNotFound: true}, ""},
+		{"dns servfail", &net.DNSError{Err: "server misbehaving", IsTemporary: true}, RetryDNSServfail},
+		{"dial timeout", &net.OpError{Op: "dial", Err: timeoutErr{}}, RetryTimeout},
+		{"reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, RetryNetwork},
+		{"unexpected eof", fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), RetryNetwork},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("%s: ClassifyError(%v) = %q, want %q", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, Initial: time.Millisecond, Multiplier: 1, On: []RetryClass{RetryServerError}}
+	transient := &StatusError{StatusCode: 502}
+	tests := []struct {
+		name        string
+		errs        []error // returned by successive calls; nil after the list ends
+		wantCalls   int
+		wantRetries int
+		wantErr     bool
+	}{
+		{"success", nil, 1, 0, false},
+		{"recovers", []error{transient, transient}, 3, 2, false},
+		{"gives up", []error{transient, transient, transient, transient}, 3, 2, true},
+		{"permanent", []error{errors.New("bad input")}, 1, 0, true},
+		{"class not retried", []error{&StatusError{StatusCode: 429}}, 1, 0, true},
+	}
+	for _, tt := range tests {
+		calls := 0
+		retries, err := policy.Do(context.Background(), func() error {
+			calls++
+			if calls <= len(tt.errs) {
+				return tt.errs[calls-1]
+			}
+			return nil
+		}, nil)
+		if calls != tt.wantCalls || retries != tt.wantRetries || (err != nil) != tt.wantErr {
+			t.Errorf("%s: calls=%d retries=%d err=%v, want calls=%d retries=%d wantErr=%v",
+				tt.name, calls, retries, err, tt.wantCalls, tt.wantRetries, tt.wantErr)
+		}
+	}
+}
+
+func TestRetryPolicyDoStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{Attempts: 5, Initial: time.Hour, On: allRetryClasses}
+	calls := 0
+	_, err := policy.Do(ctx, func() error {
+		calls++
+		return &StatusError{StatusCode: 500}
+	}, func(int, time.Duration, error) { cancel() })
+	if err == nil || calls != 1 {
+		t.Errorf("Do after cancel: calls=%d err=%v, want one call and the error", calls, err)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+	for n, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := p.Delay(n + 1); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", n+1, got, want)
+		}
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Delay(1); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("Delay(1) with 50%% jitter = %s, want within 0.5s-1.5s", d)
+		}
+	}
+}
+
+func TestRetrySpecPolicies(t *testing.T) {
+	p, err := RetryConfig{
+		ModuleDefault: RetrySpec{Attempts: 2},
+		Sources:       map[string]RetrySpec{"crtsh": {Attempts: 5, Initial: "2s", On: []string{"rate_limit"}}},
+	}.Policies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Module("webenum").Attempts; got != 2 {
+		t.Errorf("module attempts = %d, want 2", got)
+	}
+	crtsh := p.Source("crtsh")
+	if crtsh.Attempts != 5 || crtsh.Initial != 2*time.Second || len(crtsh.On) != 1 || crtsh.On[0] != RetryRateLimit {
+		t.Errorf("crtsh policy = %+v", crtsh)
+	}
+	if got := p.Source("hackertarget"); got.Attempts != DefaultSourceRetry.Attempts {
+		t.Errorf("default source attempts = %d, want %d", got.Attempts, DefaultSourceRetry.Attempts)
+	}
+	for _, cfg := range []RetryConfig{
+		{ModuleDefault: RetrySpec{Initial: "soon"}},
+		{Sources: map[string]RetrySpec{"crtsh": {On: []string{"sometimes"}}}},
+	} {
+		if _, err := cfg.Policies(); err == nil {
+			t.Errorf("Policies(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
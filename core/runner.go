@@ -0,0 +1,412 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runner drives the module pipeline for one or more targets.
+type Runner struct {
+	Engine *Engine
+	// NewAgent builds a fresh agent per target; agents keep per-scan state.
+	NewAgent func() Agent
+	// Modules, when set, runs exactly these modules instead of asking the agent.
+	Modules []string
+	// Graph runs modules as a dependency graph (Modules, or every module
+	// with a data contract) instead of serially.
+	Graph bool
+	// Workers bounds parallel modules per target in graph mode.
+	Workers int
+	// TargetWorkers bounds how many targets are scanned at once (default 1).
+	TargetWorkers int
+	// Scope, when set, is enforced for every target and module action.
+	Scope *Scope
+	// Checkpoint, when set, saves progress after every module and lets a
+	// resumed scan skip finished targets and modules.
+	Checkpoint *Checkpoint
+	// Recorder, when set, stores results and assets in the workspace
+	// database after every module.
+	Recorder *ScanRecorder
+	// Bus receives progress events; nil runs silently.
+	Bus *Bus
+	// Limiter is shared by every target so limits hold scan-wide.
+	Limiter *Limiter
+	// HTTP builds the modules' HTTP clients.
+	HTTP *HTTPClientFactory
+	// Cache is shared by every target for third-party lookups.
+	Cache *Cache
+	// Retry holds the module and data source retry policies.
+	Retry *RetryPolicies
+	// Prior, when set, returns a target's Store from an earlier scan, so
+	// modules can consume data from modules that are not run again.
+	Prior func(target string) *Store
+	// Control, when set, lets an operator pause, skip, rerun and approve
+	// modules while the scan runs. Graph mode does not honor it.
+	Control *Control
+	// APIKeys, Settings and WordlistDir are handed to every target's Context.
+	APIKeys     map[string]string
+	Settings    map[string]interface{}
+	WordlistDir string
+	// AllowedParams lists the path parameters, as "module.name", agent
+	// decisions may set; the agent's other path parameters are dropped.
+	AllowedParams []string
+}
+
+// TargetScan is the outcome of scanning one target.
+type TargetScan struct {
+	Target  Target
+	Results []Result
+	Errors  map[string]error // keyed by module name
+	Store   *Store
+	Assets  *AssetGraph
+}
+
+// Run scans every target and returns their outcomes in input order.
+func (r *Runner) Run(ctx context.Context, targets []Target) []TargetScan {
+	scans := make([]TargetScan, len(targets))
+	log := r.Bus.Logger("", "")
+	log.Publish(Event{
+		Type:    EventScanStarted,
+		Level:   LevelInfo,
+		Message: fmt.Sprintf("Scanning %d target(s)", len(targets)),
+		Fields:  map[string]interface{}{"targets": len(targets)},
+	})
+	workers := r.TargetWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, workers)
+	for i, t := range targets {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			scans[i] = TargetScan{Target: t, Errors: map[string]error{"": ctx.Err()}}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			scans[i] = r.ScanTarget(ctx, t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	results := 0
+	for _, scan := range scans {
+		results += len(scan.Results)
+	}
+	log.Publish(Event{
+		Type:    EventScanFinished,
+		Level:   LevelInfo,
+		Message: fmt.Sprintf("Scan finished with %d module result(s)", results),
+		Fields:  map[string]interface{}{"targets": len(targets), "results": results},
+	})
+	return scans
+}
+
+// ScanTarget runs the pipeline for a single target with a fresh Context.
+func (r *Runner) ScanTarget(ctx context.Context, target Target) TargetScan {
+	rctx := NewContext(target.String())
+	rctx.Scope = r.Scope
+	rctx.Bus = r.Bus
+	rctx.Limiter = r.Limiter
+	rctx.HTTP = r.HTTP
+	rctx.Cache = r.Cache
+	rctx.Retry = r.Retry
+	rctx.APIKeys = r.APIKeys
+	rctx.Settings = r.Settings
+	rctx.WordlistDir = r.WordlistDir
+	label := target.String()
+	if r.Prior != nil {
+		if store := r.Prior(label); store != nil {
+			rctx.Store = store
+		}
+	}
+	scan := TargetScan{Target: target, Errors: make(map[string]error), Store: rctx.Store, Assets: rctx.Assets}
+	log := rctx.Logger("")
+	log.Publish(Event{Type: EventTargetStarted, Level: LevelInfo, Message: "Scanning target"})
+	defer func() {
+		log.Publish(Event{
+			Type:    EventTargetFinished,
+			Level:   LevelInfo,
+			Message: fmt.Sprintf("Target finished: %d result(s), %d error(s)", len(scan.Results), len(scan.Errors)),
+			Fields:  map[string]interface{}{"results": len(scan.Results), "errors": len(scan.Errors)},
+		})
+	}()
+
+	// Out-of-scope targets are recorded but never handed to a module
+	if err := rctx.Enforce("", label); err != nil {
+		scan.Errors[""] = err
+		return scan
+	}
+
+	// Pick up where an interrupted scan left off
+	saved, resumed := r.Checkpoint.Restore(label, rctx)
+	if resumed {
+		scan.Results = saved.Results
+		if saved.Done {
+			log.Infof("Already completed, reusing %d saved result(s)", len(saved.Results))
+			return scan
+		}
+		log.Infof("Resuming with %d completed module(s)", len(saved.Results))
+	}
+	scan.Store, scan.Assets = rctx.Store, rctx.Assets
+	seedAssets(rctx, target)
+	completed := make(map[string]bool)
+	for _, result := range scan.Results {
+		completed[result.ModuleName] = true
+	}
+
+	var agent Agent
+	checkpoint := func(done bool) {
+		var executions map[string]int
+		if sa, ok := agent.(StatefulAgent); ok {
+			executions = sa.ExecutionCounts()
+		}
+		if err := r.Checkpoint.Save(label, rctx, scan.Results, executions, done); err != nil {
+			log.Warnf("Failed to save scan state: %v", err)
+		}
+		if err := r.Recorder.Save(label, scan.Results, rctx.Store, rctx.Assets); err != nil {
+			log.Errorf("Failed to record scan in workspace %s: %v", r.Recorder.Workspace(), err)
+		}
+	}
+
+	var mu sync.Mutex
+	record := func(result Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Target = label
+		scan.Results = append(scan.Results, result)
+		completed[result.ModuleName] = true
+		checkpoint(false)
+	}
+
+	switch {
+	case r.Graph:
+		names := pending(r.Modules, completed)
+		if len(r.Modules) == 0 {
+			names = pending(r.Engine.GraphModules(), completed)
+		}
+		log.Infof("Running modules as a dependency graph: %s", strings.Join(names, ", "))
+		_, failed, err := r.Engine.RunGraph(ctx, names, rctx.Target, rctx, r.Workers, record)
+		if err != nil {
+			scan.Errors[""] = err
+			return scan
+		}
+		for name, err := range failed {
+			scan.Errors[name] = err
+		}
+
+	case len(r.Modules) > 0:
+		log.Infof("Running specific modules: %s", strings.Join(r.Modules, ", "))
+		queue := pending(r.Modules, completed)
+		for r.hold(ctx, log) == nil {
+			name, rerun := r.Control.nextRerun(label)
+			if rerun {
+				log.Infof("Running module '%s' again at operator request", name)
+			} else if len(queue) > 0 {
+				name, queue = queue[0], queue[1:]
+			} else {
+				break
+			}
+			result, err := r.runModule(ctx, rctx, name, nil)
+			if err != nil {
+				if !r.skipped(log, name, err) {
+					scan.Errors[name] = err
+				}
+				continue
+			}
+			delete(scan.Errors, name)
+			record(result)
+		}
+
+	default:
+		agent = r.NewAgent()
+		if sa, ok := agent.(StatefulAgent); ok && resumed {
+			sa.RestoreExecutionCounts(saved.Executions)
+		}
+		r.runAgent(ctx, agent, rctx, &scan, record)
+	}
+
+	// A target is only done once it finished without interruption
+	checkpoint(ctx.Err() == nil)
+	return scan
+}
+
+// seedAssets records the target itself, and the range it was expanded
+// from, as the first assets of the scan.
+func seedAssets(rctx *Context, target Target) {
+	w := rctx.AssetWriter("").From("target")
+	switch target.Kind {
+	case TargetURL:
+		w.URL(target.URL, nil)
+	case TargetHostPort:
+		w.Service(target.Host, target.Port, nil)
+	default:
+		id := w.Host(target.Host, nil)
+		if strings.Contains(target.Raw, "/") {
+			w.Link(w.Add(NodeNetblock, target.Raw, nil), EdgeContains, id)
+		}
+	}
+}
+
+// pending filters out modules that already completed.
+func pending(names []string, completed map[string]bool) []string {
+	var out []string
+	for _, name := range names {
+		if !completed[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// runAgent lets the agent pick modules until it reports completion.
+func (r *Runner) runAgent(ctx context.Context, agent Agent, rctx *Context, scan *TargetScan, record func(Result)) {
+	log := rctx.Logger("agent")
+	label := scan.Target.String()
+	for r.hold(ctx, log) == nil {
+		if name, ok := r.Control.nextRerun(label); ok {
+			log.Infof("Running module '%s' again at operator request", name)
+			r.Recorder.Decide(label, AgentDecision{Time: time.Now(), Module: name, Reason: "rerun requested by operator"})
+			r.runDecision(ctx, agent, rctx, scan, record, Action{ModuleName: name})
+			continue
+		}
+
+		log.Debugf("Asking agent for next action...")
+		action, err := agent.DecideNextAction(rctx, scan.Results)
+		if err != nil {
+			if strings.Contains(err.Error(), "all modules completed") {
+				log.Infof("Recon complete: %s", err.Error())
+			} else {
+				log.Errorf("Agent error: %v", err)
+				scan.Errors["agent"] = err
+			}
+			return
+		}
+		var dropped []string
+		action.Params, dropped = r.Engine.UntrustedParams(action.ModuleName, action.Params, r.AllowedParams)
+		if len(dropped) > 0 {
+			log.Warnf("Dropped path parameter(s) %s from the agent's decision; allow them with allowed_params", strings.Join(dropped, ", "))
+		}
+
+		message := fmt.Sprintf("Agent decision: Run module '%s' (%s)", action.ModuleName, action.Reason)
+		if len(action.Params) > 0 {
+			message += fmt.Sprintf(" with params %v", action.Params)
+		}
+		log.Publish(Event{
+			Type:    EventAgentDecision,
+			Level:   LevelInfo,
+			Message: message,
+			Fields: map[string]interface{}{
+				"action": action.ModuleName,
+				"reason": action.Reason,
+				"params": action.Params,
+			},
+		})
+		r.Recorder.Decide(label, AgentDecision{
+			Time:   time.Now(),
+			Module: action.ModuleName,
+			Reason: action.Reason,
+			Params: action.Params,
+		})
+
+		if r.Control.Approval() {
+			log.Infof("Waiting for approval to run module '%s'", action.ModuleName)
+		}
+		approved, err := r.Control.approve(ctx, label, action)
+		if err != nil {
+			return
+		}
+		if !approved {
+			log.Warnf("Operator rejected module '%s'", action.ModuleName)
+			if ra, ok := agent.(ReviewedAgent); ok {
+				ra.Rejected(action)
+			}
+			r.Recorder.Decide(label, AgentDecision{Time: time.Now(), Module: action.ModuleName, Reason: "rejected by operator"})
+			continue
+		}
+		if r.hold(ctx, log) != nil {
+			return
+		}
+		r.runDecision(ctx, agent, rctx, scan, record, action)
+	}
+}
+
+// runDecision runs the module the agent, or the operator, picked.
+func (r *Runner) runDecision(ctx context.Context, agent Agent, rctx *Context, scan *TargetScan, record func(Result), action Action) {
+	log := rctx.Logger("agent")
+	result, err := r.runModule(ctx, rctx, action.ModuleName, action.Params)
+	if err != nil {
+		if r.skipped(log, action.ModuleName, err) {
+			return
+		}
+		scan.Errors[action.ModuleName] = err
+		if ctx.Err() != nil {
+			return
+		}
+		// Ask agent how to handle error
+		recoveryAction, _ := agent.RecoverFromError(rctx, scan.Results, err)
+		log.Infof("Agent recovery suggestion: %s", recoveryAction.Reason)
+		return
+	}
+
+	delete(scan.Errors, action.ModuleName)
+	record(result)
+}
+
+// runModule runs one module so that the operator can skip it.
+func (r *Runner) runModule(ctx context.Context, rctx *Context, name string, params map[string]interface{}) (Result, error) {
+	mctx, done := r.Control.track(ctx, rctx.Target, name)
+	result, err := r.Engine.RunModule(mctx, name, rctx.Target, params, rctx)
+	if done() && err != nil && ctx.Err() == nil {
+		return result, errSkipped
+	}
+	return result, err
+}
+
+var errSkipped = errors.New("skipped by operator")
+
+// skipped reports, and logs, whether err means the operator skipped the
+// module rather than it failing.
+func (r *Runner) skipped(log *Logger, name string, err error) bool {
+	if err != errSkipped {
+		return false
+	}
+	log.Warnf("Skipped module '%s' at operator request", name)
+	return true
+}
+
+// hold blocks while the operator has paused the scan, and reports whether
+// the scan was cancelled.
+func (r *Runner) hold(ctx context.Context, log *Logger) error {
+	if r.Control.Paused() {
+		log.Infof("Paused, waiting for the operator to resume")
+	}
+	return r.Control.wait(ctx)
+}
+
+// AllAssets merges the asset graphs of several target scans.
+func AllAssets(scans []TargetScan) *AssetGraph {
+	all := NewAssetGraph()
+	for _, s := range scans {
+		all.Merge(s.Assets)
+	}
+	return all
+}
+
+// AllResults flattens the results of several target scans.
+func AllResults(scans []TargetScan) []Result {
+	var all []Result
+	for _, s := range scans {
+		all = append(all, s.Results...)
+	}
+	return all
+}
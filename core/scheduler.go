@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultWorkers is the scheduler's parallelism when none is configured.
+const DefaultWorkers = 4
+
+// ErrUpstreamFailed marks a module that was skipped because a module it
+// depends on failed or was skipped itself.
+var ErrUpstreamFailed = errors.New("upstream module failed")
+
+// Dependent is implemented by modules that declare the Context.Store keys
+// they read and write (e.g. "portscan.open_ports"). The scheduler derives
+// execution order from these contracts, and only modules implementing it
+// take part in a default (all modules) graph run.
+type Dependent interface {
+	Consumes() []string
+	Produces() []string
+}
+
+// GraphModules returns the registered modules that declare a data contract,
+// sorted by name.
+func (e *Engine) GraphModules() []string {
+	var names []string
+	for name, mod := range e.modules {
+		if _, ok := mod.(Dependent); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dependencies resolves, for each named module, the modules in the same set
+// whose produced keys it consumes. It also returns a topological order and
+// fails on unknown modules or dependency cycles.
+func (e *Engine) Dependencies(names []string) (map[string][]string, []string, error) {
+	producers := make(map[string][]string)
+	for _, name := range names {
+		mod, ok := e.modules[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("module not found: %s", name)
+		}
+		if dep, ok := mod.(Dependent); ok {
+			for _, key := range dep.Produces() {
+				producers[key] = append(producers[key], name)
+			}
+		}
+	}
+
+	deps := make(map[string][]string, len(names))
+	for _, name := range names {
+		seen := map[string]bool{}
+		deps[name] = nil
+		dep, ok := e.modules[name].(Dependent)
+		if !ok {
+			continue
+		}
+		for _, key := range dep.Consumes() {
+			for _, producer := range producers[key] {
+				if producer != name && !seen[producer] {
+					seen[producer] = true
+					deps[name] = append(deps[name], producer)
+				}
+			}
+		}
+		sort.Strings(deps[name])
+	}
+
+	// Kahn's algorithm; names keep their given order among ready nodes.
+	indegree := make(map[string]int, len(names))
+	dependents := make(map[string][]string)
+	for _, name := range names {
+		indegree[name] = len(deps[name])
+		for _, d := range deps[name] {
+			dependents[d] = append(dependents[d], name)
+		}
+	}
+	var order []string
+	placed := map[string]bool{}
+	for len(order) < len(names) {
+		progressed := false
+		for _, name := range names {
+			if placed[name] || indegree[name] > 0 {
+				continue
+			}
+			placed[name] = true
+			order = append(order, name)
+			for _, next := range dependents[name] {
+				indegree[next]--
+			}
+			progressed = true
+		}
+		if !progressed {
+			var stuck []string
+			for _, name := range names {
+				if !placed[name] {
+					stuck = append(stuck, name)
+				}
+			}
+			return nil, nil, fmt.Errorf("dependency cycle involving modules: %s", strings.Join(stuck, ", "))
+		}
+	}
+	return deps, order, nil
+}
+
+// RunGraph runs the named modules as a dependency graph. Independent modules
+// run in parallel, bounded by workers; a module starts only once every module
+// it depends on has finished, and is skipped with ErrUpstreamFailed if any of
+// them failed. Results come back in topological order, and errors are keyed by
+// module name. onResult, if set, sees each result as soon as its module
+// completes (calls are serialized).
+func (e *Engine) RunGraph(ctx context.Context, names []string, target string, rctx *Context, workers int, onResult func(Result)) ([]Result, map[string]error, error) {
+	deps, order, err := e.Dependencies(names)
+	if err != nil {
+		return nil, nil, err
+	}
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]Result)
+		failed  = make(map[string]error)
+		done    = make(map[string]chan struct{}, len(order))
+		slots   = make(chan struct{}, workers)
+	)
+	for _, name := range order {
+		done[name] = make(chan struct{})
+	}
+
+	for _, name := range order {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer close(done[name])
+
+			for _, dep := range deps[name] {
+				<-done[dep]
+			}
+			mu.Lock()
+			for _, dep := range deps[name] {
+				if _, bad := failed[dep]; bad {
+					failed[name] = fmt.Errorf("%w: %s", ErrUpstreamFailed, dep)
+					mu.Unlock()
+					rctx.Logger(name).Warnf("Skipping module %s: dependency %s did not complete", name, dep)
+					return
+				}
+			}
+			mu.Unlock()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				mu.Lock()
+				failed[name] = fmt.Errorf("module %s not started: %w", name, ctx.Err())
+				mu.Unlock()
+				return
+			}
+			defer func() { <-slots }()
+
+			result, err := e.RunModule(ctx, name, target, nil, rctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[name] = err
+				return
+			}
+			results[name] = result
+			if onResult != nil {
+				onResult(result)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	ordered := make([]Result, 0, len(results))
+	for _, name := range order {
+		if r, ok := results[name]; ok {
+			ordered = append(ordered, r)
+		}
+	}
+	return ordered, failed, nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// contractModule is a Dependent module that records its run and fails
+// when told to.
+type contractModule struct {
+	name               string
+	consumes, produces []string
+	fail               bool
+	ran                *sync.Map
+}
+
+func (m *contractModule) Name() string       { return m.name }
+func (m *contractModule) Consumes() []string { return m.consumes }
+func (m *contractModule) Produces() []string { return m.produces }
+
+func (m *contractModule) Run(ctx context.Context, target string, params Params, rctx *Context) (Result, error) {
+	m.ran.Store(m.name, true)
+	if m.fail {
+		return Result{}, errors.New("failed")
+	}
+	return Result{ModuleName: m.name}, nil
+}
+
+func graphEngine(modules ...*contractModule) (*Engine, *sync.Map) {
+	ran := &sync.Map{}
+	e := NewEngine()
+	for _, m := range modules {
+		m.ran = ran
+		e.RegisterModule(m)
+	}
+	return e, ran
+}
+
+func TestDependencies(t *testing.T) {
+	e, _ := graphEngine(
+		&contractModule{name: "subdomain", produces: []string{"subdomain.all"}},
+		&contractModule{name: "portscan", consumes: []string{"subdomain.all"}, produces: []string{"portscan.open_ports"}},
+		&contractModule{name: "webenum", consumes: []string{"portscan.open_ports"}, produces: []string{"webenum.tech_detected"}},
+		&contractModule{name: "vulnscan", consumes: []string{"webenum.tech_detected", "portscan.open_ports"}},
+		&contractModule{name: "passive", produces: []string{"passive.whois"}},
+	)
+	deps, order, err := e.Dependencies([]string{"vulnscan", "webenum", "portscan", "subdomain", "passive"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDeps := map[string][]string{
+		"subdomain": nil,
+		"passive":   nil,
+		"portscan":  {"subdomain"},
+		"webenum":   {"portscan"},
+		"vulnscan":  {"portscan", "webenum"},
+	}
+	if !reflect.DeepEqual(deps, wantDeps) {
+		t.Errorf("deps = %v, want %v", deps, wantDeps)
+	}
+	wantOrder := []string{"subdomain", "passive", "portscan", "webenum", "vulnscan"}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("order = %v, want %v", order, wantOrder)
+	}
+
+	// Only modules in the set count as producers
+	deps, _, err = e.Dependencies([]string{"webenum", "vulnscan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deps["webenum"], []string(nil)) || !reflect.DeepEqual(deps["vulnscan"], []string{"webenum"}) {
+		t.Errorf("subset deps = %v", deps)
+	}
+}
+
+func TestDependenciesErrors(t *testing.T) {
+	e, _ := graphEngine(
+		&contractModule{name: "a", consumes: []string{"b.out"}, produces: []string{"a.out"}},
+		&contractModule{name: "b", consumes: []string{"a.out"}, produces: []string{"b.out"}},
+		&contractModule{name: "c"},
+	)
+	if _, _, err := e.Dependencies([]string{"a", "b", "c"}); err == nil {
+		t.Error("Dependencies accepted a cycle")
+	}
+	if _, _, err := e.Dependencies([]string{"c", "missing"}); err == nil {
+		t.Error("Dependencies accepted an unknown module")
+	}
+}
+
+func TestRunGraphSkipsDownstreamOfFailure(t *testing.T) {
+	e, ran := graphEngine(
+		&contractModule{name: "subdomain", produces: []string{"subdomain.all"}, fail: true},
+		&contractModule{name: "portscan", consumes: []string{"subdomain.all"}, produces: []string{"portscan.open_ports"}},
+		&contractModule{name: "webenum", consumes: []string{"portscan.open_ports"}},
+		&contractModule{name: "passive", produces: []string{"passive.whois"}},
+	)
+	e.SetTimeouts(0, nil)
+	var seen []string
+	results, failed, err := e.RunGraph(context.Background(), []string{"subdomain", "portscan", "webenum", "passive"}, "example.com", NewContext("example.com"), 2, func(r Result) {
+		seen = append(seen, r.ModuleName)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ModuleName != "passive" || !reflect.DeepEqual(seen, []string{"passive"}) {
+		t.Errorf("results = %v, onResult saw %v, want only passive", results, seen)
+	}
+	for _, name := range []string{"portscan", "webenum"} {
+		if !errors.Is(failed[name], ErrUpstreamFailed) {
+			t.Errorf("%s error = %v, want ErrUpstreamFailed", name, failed[name])
+		}
+		if _, ok := ran.Load(name); ok {
+			t.Errorf("%s ran after its dependency failed", name)
+		}
+	}
+	if failed["subdomain"] == nil || errors.Is(failed["subdomain"], ErrUpstreamFailed) {
+		t.Errorf("subdomain error = %v, want its own failure", failed["subdomain"])
+	}
+}
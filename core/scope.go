@@ -0,0 +1,367 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrOutOfScope is returned (wrapped) when an action targets an asset the
+// rules of engagement do not allow.
+var ErrOutOfScope = errors.New("out of scope")
+
+// OutOfScopeKey lists assets that were discovered but never actively touched.
+var OutOfScopeKey = NewKey[[]string]("scope.out_of_scope")
+
+// ScopeConfig is the rules-of-engagement section of the config file.
+// Domain rules are globs ("*.example.com" matches any subdomain, not the
+// apex). Ports use the "80,443,8000-8100" list syntax. Empty include lists
+// allow everything; excludes always win.
+type ScopeConfig struct {
+	IncludeDomains []string `json:"include_domains,omitempty"`
+	ExcludeDomains []string `json:"exclude_domains,omitempty"`
+	IncludeCIDRs   []string `json:"include_cidrs,omitempty"`
+	ExcludeCIDRs   []string `json:"exclude_cidrs,omitempty"`
+	IncludePorts   string   `json:"include_ports,omitempty"`
+	ExcludePorts   string   `json:"exclude_ports,omitempty"`
+	IncludePaths   []string `json:"include_paths,omitempty"`
+	ExcludePaths   []string `json:"exclude_paths,omitempty"`
+}
+
+// Violation records one blocked action.
+type Violation struct {
+	Time   time.Time `json:"time"`
+	Module string    `json:"module,omitempty"`
+	Asset  string    `json:"asset"`
+	Reason string    `json:"reason"`
+}
+
+// Scope is the compiled form of a ScopeConfig. A nil *Scope allows
+// everything, so modules can call its checks unconditionally.
+type Scope struct {
+	includeDomains []string
+	excludeDomains []string
+	includeCIDRs   []netip.Prefix
+	excludeCIDRs   []netip.Prefix
+	includePorts   map[int]bool
+	excludePorts   map[int]bool
+	includePaths   []string
+	excludePaths   []string
+	config         ScopeConfig
+
+	mu         sync.Mutex
+	violations []Violation
+}
+
+// NewScope validates and compiles cfg.
+func NewScope(cfg ScopeConfig) (*Scope, error) {
+	s := &Scope{
+		includeDomains: lowerAll(cfg.IncludeDomains),
+		excludeDomains: lowerAll(cfg.ExcludeDomains),
+		includePaths:   cfg.IncludePaths,
+		excludePaths:   cfg.ExcludePaths,
+		config:         cfg,
+	}
+	for _, glob := range append(append([]string{}, s.includeDomains...), s.excludeDomains...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid scope domain %q: %v", glob, err)
+		}
+	}
+	var err error
+	if s.includeCIDRs, err = parsePrefixes(cfg.IncludeCIDRs); err != nil {
+		return nil, err
+	}
+	if s.excludeCIDRs, err = parsePrefixes(cfg.ExcludeCIDRs); err != nil {
+		return nil, err
+	}
+	if s.includePorts, err = parsePortSet(cfg.IncludePorts); err != nil {
+		return nil, err
+	}
+	if s.excludePorts, err = parsePortSet(cfg.ExcludePorts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CheckHost reports whether a hostname or IP may be actively touched.
+// Hostnames are matched against domain rules and IPs against CIDR rules;
+// once any include rule exists, a host must match one of them.
+func (s *Scope) CheckHost(host string) error {
+	if s == nil {
+		return nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, p := range s.excludeCIDRs {
+			if p.Contains(addr) {
+				return outOfScope(host, "excluded by CIDR "+p.String())
+			}
+		}
+		for _, p := range s.includeCIDRs {
+			if p.Contains(addr) {
+				return nil
+			}
+		}
+	} else {
+		for _, glob := range s.excludeDomains {
+			if matchDomain(glob, host) {
+				return outOfScope(host, "excluded by domain "+glob)
+			}
+		}
+		for _, glob := range s.includeDomains {
+			if matchDomain(glob, host) {
+				return nil
+			}
+		}
+	}
+	if len(s.includeDomains) > 0 || len(s.includeCIDRs) > 0 {
+		return outOfScope(host, "not matched by any include rule")
+	}
+	return nil
+}
+
+// Restricted reports whether any include rule exists, so hosts passing
+// CheckHost were named by the engagement rather than allowed by default.
+func (s *Scope) Restricted() bool {
+	return s != nil && (len(s.includeDomains) > 0 || len(s.includeCIDRs) > 0)
+}
+
+// Config returns the rules s was compiled from; a nil *Scope has none.
+func (s *Scope) Config() ScopeConfig {
+	if s == nil {
+		return ScopeConfig{}
+	}
+	return s.config
+}
+
+// CheckPort reports whether host:port may be actively touched.
+func (s *Scope) CheckPort(host string, port int) error {
+	if s == nil {
+		return nil
+	}
+	if err := s.CheckHost(host); err != nil {
+		return err
+	}
+	asset := net.JoinHostPort(host, strconv.Itoa(port))
+	if s.excludePorts[port] {
+		return outOfScope(asset, "port excluded")
+	}
+	if len(s.includePorts) > 0 && !s.includePorts[port] {
+		return outOfScope(asset, "port not included")
+	}
+	return nil
+}
+
+// CheckURL checks a URL's host, effective port and path.
+func (s *Scope) CheckURL(raw string) error {
+	if s == nil {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return outOfScope(raw, "unparseable URL")
+	}
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	if p := u.Port(); p != "" {
+		if port, err = strconv.Atoi(p); err != nil {
+			return outOfScope(raw, "invalid port")
+		}
+	}
+	if err := s.CheckPort(u.Hostname(), port); err != nil {
+		return err
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	for _, prefix := range s.excludePaths {
+		if strings.HasPrefix(p, prefix) {
+			return outOfScope(raw, "path excluded by "+prefix)
+		}
+	}
+	if len(s.includePaths) == 0 {
+		return nil
+	}
+	for _, prefix := range s.includePaths {
+		if strings.HasPrefix(p, prefix) {
+			return nil
+		}
+	}
+	return outOfScope(raw, "path not included")
+}
+
+// CheckTarget checks a scan target in any form accepted by ParseTarget.
+func (s *Scope) CheckTarget(target string) error {
+	if s == nil {
+		return nil
+	}
+	if strings.Contains(target, "://") {
+		return s.CheckURL(target)
+	}
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return outOfScope(target, "invalid port")
+		}
+		return s.CheckPort(host, p)
+	}
+	return s.CheckHost(target)
+}
+
+// Record keeps a blocked action for the report and returns it.
+func (s *Scope) Record(module string, err error) Violation {
+	if s == nil || !errors.Is(err, ErrOutOfScope) {
+		return Violation{}
+	}
+	var se *scopeError
+	v := Violation{Time: time.Now(), Module: module, Reason: err.Error()}
+	if errors.As(err, &se) {
+		v.Asset, v.Reason = se.asset, se.reason
+	}
+	s.mu.Lock()
+	s.violations = append(s.violations, v)
+	s.mu.Unlock()
+	return v
+}
+
+// Violations returns every blocked action so far.
+func (s *Scope) Violations() []Violation {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Violation(nil), s.violations...)
+}
+
+// Enforce checks an asset (host, host:port or URL) against the scan's
+// scope before module touches it. A blocked asset is logged as a violation
+// and added to OutOfScopeKey so it still shows up in results.
+func (c *Context) Enforce(module, asset string) error {
+	if c == nil {
+		return nil
+	}
+	err := c.Scope.CheckTarget(asset)
+	if err != nil {
+		v := c.Scope.Record(module, err)
+		c.Logger("scope").Warnf("Blocked %s (%s): %s", v.Asset, module, v.Reason)
+		MarkOutOfScope(c, asset)
+	}
+	return err
+}
+
+// ScopedTransport wraps base so every HTTP request, redirects included, is
+// checked with Enforce and then rate limited before it leaves the process.
+func (c *Context) ScopedTransport(module string, base http.RoundTripper) http.RoundTripper {
+	base = c.Limiter.Transport(base)
+	return &scopedTransport{rctx: c, module: module, base: base}
+}
+
+type scopedTransport struct {
+	rctx   *Context
+	module string
+	base   http.RoundTripper
+}
+
+func (t *scopedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.rctx.Enforce(t.module, req.URL.String()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// MarkOutOfScope records an asset that was seen but left alone.
+func MarkOutOfScope(c *Context, asset string) {
+	Update(c.Store, OutOfScopeKey, func(assets []string) []string {
+		for _, a := range assets {
+			if a == asset {
+				return assets
+			}
+		}
+		return append(assets, asset)
+	})
+}
+
+type scopeError struct {
+	asset, reason string
+}
+
+func (e *scopeError) Error() string {
+	return fmt.Sprintf("%s is out of scope: %s", e.asset, e.reason)
+}
+
+func (e *scopeError) Is(target error) bool { return target == ErrOutOfScope }
+
+func outOfScope(asset, reason string) error {
+	return &scopeError{asset: asset, reason: reason}
+}
+
+// matchDomain matches a host against a glob; "*" spans labels.
+func matchDomain(glob, host string) bool {
+	ok, _ := path.Match(glob, host)
+	return ok
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid scope CIDR %q: %v", c, err)
+			}
+			out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope CIDR %q: %v", c, err)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+func parsePortSet(s string) (map[int]bool, error) {
+	for _, part := range splitList(s) {
+		start, end, err := parseIntRange(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope ports: %v", err)
+		}
+		if start < 1 || end > 65535 {
+			return nil, fmt.Errorf("invalid scope ports: %q is outside 1-65535", part)
+		}
+	}
+	ports, err := parseIntList(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scope ports: %v", err)
+	}
+	set := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		set[p] = true
+	}
+	return set, nil
+}
+
+func lowerAll(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func testScope(t *testing.T) *Scope {
+	t.Helper()
+	s, err := NewScope(ScopeConfig{
+		IncludeDomains: []string{"example.com", "*.example.com"},
+		ExcludeDomains: []string{"admin.example.com"},
+		IncludeCIDRs:   []string{"10.0.0.0/8", "192.0.2.1"},
+		ExcludeCIDRs:   []string{"10.1.0.0/16"},
+		IncludePorts:   "80,443,8000-8010",
+		ExcludePorts:   "8005",
+		ExcludePaths:   []string{"/logout"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestScopeCheckHost(t *testing.T) {
+	s := testScope(t)
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"www.example.com", true},
+		{"a.b.example.com", true},
+		{"WWW.Example.COM.", true},
+		{"admin.example.com", false},
+		{"notexample.com", false},
+		{"example.org", false},
+		{"10.2.3.4", true},
+		{"10.1.2.3", false},
+		{"::ffff:10.2.3.4", true},
+		{"192.0.2.1", true},
+		{"192.0.2.2", false},
+		{"[2001:db8::1]", false},
+	}
+	for _, tt := range tests {
+		err := s.CheckHost(tt.host)
+		if (err == nil) != tt.want {
+			t.Errorf("CheckHost(%q) = %v, want in scope %v", tt.host, err, tt.want)
+		}
+		if err != nil && !errors.Is(err, ErrOutOfScope) {
+			t.Errorf("CheckHost(%q) error %v is not ErrOutOfScope", tt.host, err)
+		}
+	}
+}
+
+func TestScopeCheckPortAndURL(t *testing.T) {
+	s := testScope(t)
+	ports := []struct {
+		host string
+		port int
+		want bool
+	}{
+		{"example.com", 443, true},
+		{"example.com", 8003, true},
+		{"example.com", 8005, false},
+		{"example.com", 22, false},
+		{"evil.com", 443, false},
+	}
+	for _, tt := range ports {
+		if err := s.CheckPort(tt.host, tt.port); (err == nil) != tt.want {
+			t.Errorf("CheckPort(%q, %d) = %v, want in scope %v", tt.host, tt.port, err, tt.want)
+		}
+	}
+
+	targets := []struct {
+		target string
+		want   bool
+	}{
+		{"https://a.example.com/x", true},
+		{"http://a.example.com:8001/", true},
+		{"http://a.example.com:8080/", false},
+		{"https://a.example.com/logout?next=/", false},
+		{"https://admin.example.com/", false},
+		{"a.example.com:443", true},
+		{"a.example.com:22", false},
+		{"www.example.com", true},
+		{"http:///nohost", false},
+	}
+	for _, tt := range targets {
+		if err := s.CheckTarget(tt.target); (err == nil) != tt.want {
+			t.Errorf("CheckTarget(%q) = %v, want in scope %v", tt.target, err, tt.want)
+		}
+	}
+}
+
+func TestScopeUnrestricted(t *testing.T) {
+	empty, err := NewScope(ScopeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var none *Scope
+	for _, s := range []*Scope{empty, none} {
+		if s.Restricted() {
+			t.Errorf("%v: Restricted() = true for a scope without include rules", s)
+		}
+		for _, target := range []string{"anything.test", "203.0.113.9", "https://x.test:9999/a"} {
+			if err := s.CheckTarget(target); err != nil {
+				t.Errorf("CheckTarget(%q) = %v, want allowed", target, err)
+			}
+		}
+	}
+	if !testScope(t).Restricted() {
+		t.Error("Restricted() = false for a scope with include rules")
+	}
+}
+
+func TestParsePortSet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int // number of ports
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "80", want: 1},
+		{in: "80,443,8000-8010", want: 13},
+		{in: "1-65535", want: 65535},
+		{in: "0", wantErr: true},
+		{in: "65536", wantErr: true},
+		{in: "0-80", wantErr: true},
+		{in: "1-70000", wantErr: true},
+		{in: "10-5", wantErr: true},
+		{in: "-5", wantErr: true},
+		{in: "http", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePortSet(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePortSet(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if len(got) != tt.want {
+			t.Errorf("parsePortSet(%q) has %d ports, want %d", tt.in, len(got), tt.want)
+		}
+	}
+}
+
+func TestNewScopeErrors(t *testing.T) {
+	for _, cfg := range []ScopeConfig{
+		{IncludeDomains: []string{"[bad"}},
+		{IncludeCIDRs: []string{"10.0.0.0/33"}},
+		{ExcludeCIDRs: []string{"not-an-ip"}},
+		{IncludePorts: "99999"},
+	} {
+		if _, err := NewScope(cfg); err == nil {
+			t.Errorf("NewScope(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrTypeMismatch is returned when a Store entry holds a different type than
+// the key used to read or write it.
+var ErrTypeMismatch = errors.New("store type mismatch")
+
+// Key names a Store entry holding values of type T. Modules should share the
+// well-known keys below rather than building their own from raw strings.
+type Key[T any] struct {
+	name string
+}
+
+// keyDecoders restores typed values for every declared key when a Store is
+// loaded from a checkpoint.
+var (
+	keyDecodersMu sync.RWMutex
+	keyDecoders   = map[string]func(json.RawMessage) (interface{}, error){}
+)
+
+// NewKey declares a typed Store key.
+func NewKey[T any](name string) Key[T] {
+	keyDecodersMu.Lock()
+	keyDecoders[name] = func(raw json.RawMessage) (interface{}, error) {
+		var v T
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+	keyDecodersMu.Unlock()
+	return Key[T]{name: name}
+}
+
+// Name returns the key's string form, as used in Consumes/Produces.
+func (k Key[T]) Name() string { return k.name }
+
+// OpenPort is one open port found by portscan.
+type OpenPort struct {
+	Port    int    `json:"port"`
+	Banner  string `json:"banner,omitempty"`
+	Service string `json:"service,omitempty"`
+}
+
+// DirEntry is one web path found by webenum's directory brute-force.
+type DirEntry struct {
+	Path       string `json:"path"`
+	StatusCode int    `json:"status_code"`
+	Size       int64  `json:"size"`
+	Title      string `json:"title,omitempty"`
+}
+
+// Well-known keys forming the inter-module data contract.
+var (
+	SubdomainsKey   = NewKey[[]string]("subdomain.all")
+	OpenPortsKey    = NewKey[[]OpenPort]("portscan.open_ports")
+	TechDetectedKey = NewKey[[]string]("webenum.tech_detected")
+	DirsFoundKey    = NewKey[[]DirEntry]("webenum.dirs_found")
+	VulnsKey        = NewKey[[]Finding]("vulnscan.vulns")
+)
+
+// Store is the concurrency-safe data store shared by modules during a scan.
+type Store struct {
+	mu   sync.RWMutex
+	data map[string]interface{}
+}
+
+// NewStore returns an empty Store.
+func NewStore() *Store {
+	return &Store{data: make(map[string]interface{})}
+}
+
+// Get reads the value stored under key. The bool reports whether the key is
+// present; a value of the wrong type yields ErrTypeMismatch.
+func Get[T any](s *Store, key Key[T]) (T, bool, error) {
+	var zero T
+	s.mu.RLock()
+	raw, ok := s.data[key.name]
+	s.mu.RUnlock()
+	if !ok {
+		return zero, false, nil
+	}
+	v, ok := raw.(T)
+	if !ok {
+		return zero, true, fmt.Errorf("%w: %s holds %T, want %T", ErrTypeMismatch, key.name, raw, zero)
+	}
+	return v, true, nil
+}
+
+// Set stores v under key, refusing to replace a value of another type.
+func Set[T any](s *Store, key Key[T], v T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.data[key.name]; ok {
+		if _, same := old.(T); !same {
+			return fmt.Errorf("%w: %s holds %T, cannot set %T", ErrTypeMismatch, key.name, old, v)
+		}
+	}
+	s.data[key.name] = v
+	return nil
+}
+
+// Update atomically replaces the value under key with fn(current). The
+// current value is the zero value when the key is absent.
+func Update[T any](s *Store, key Key[T], fn func(T) T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cur T
+	if old, ok := s.data[key.name]; ok {
+		v, same := old.(T)
+		if !same {
+			return fmt.Errorf("%w: %s holds %T, want %T", ErrTypeMismatch, key.name, old, cur)
+		}
+		cur = v
+	}
+	s.data[key.name] = fn(cur)
+	return nil
+}
+
+// Has reports whether a key is present.
+func (s *Store) Has(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.data[name]
+	return ok
+}
+
+// Keys returns the stored key names, sorted.
+func (s *Store) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Snapshot returns a shallow copy of the stored values.
+func (s *Store) Snapshot() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]interface{}, len(s.data))
+	for k, v := range s.data {
+		out[k] = v
+	}
+	return out
+}
+
+// Clone returns an independent copy of the Store.
+func (s *Store) Clone() (*Store, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	c := NewStore()
+	return c, json.Unmarshal(data, c)
+}
+
+// MarshalJSON encodes every stored value, keyed by name.
+func (s *Store) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Snapshot())
+}
+
+// UnmarshalJSON replaces the Store's contents. Values under declared keys get
+// their typed form back; unknown keys keep their generic JSON shape.
+func (s *Store) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	values := make(map[string]interface{}, len(raw))
+	for name, msg := range raw {
+		v, err := decodeStoreValue(name, msg)
+		if err != nil {
+			return err
+		}
+		values[name] = v
+	}
+	s.mu.Lock()
+	s.data = values
+	s.mu.Unlock()
+	return nil
+}
+
+// SetJSON stores a JSON-encoded value under name, decoding it into the
+// declared key's type so typed readers (Get) keep working.
+func (s *Store) SetJSON(name string, raw json.RawMessage) error {
+	v, err := decodeStoreValue(name, raw)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[name] = v
+	return nil
+}
+
+func decodeStoreValue(name string, raw json.RawMessage) (interface{}, error) {
+	keyDecodersMu.RLock()
+	decode, ok := keyDecoders[name]
+	keyDecodersMu.RUnlock()
+	var v interface{}
+	var err error
+	if ok {
+		v, err = decode(raw)
+	} else {
+		err = json.Unmarshal(raw, &v)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store key %s: %v", name, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStoreTypedAccess(t *testing.T) {
+	s := NewStore()
+	if _, ok, err := Get(s, SubdomainsKey); ok || err != nil {
+		t.Errorf("Get on an empty store = %v, %v", ok, err)
+	}
+	if err := Set(s, SubdomainsKey, []string{"a.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(s, SubdomainsKey, func(v []string) []string { return append(v, "b.example.com") }); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := Get(s, SubdomainsKey)
+	if !ok || err != nil || !reflect.DeepEqual(got, []string{"a.example.com", "b.example.com"}) {
+		t.Errorf("Get = %v, %v, %v", got, ok, err)
+	}
+
+	// Another key type under the same name is refused
+	names := NewKey[[]string]("test.names")
+	if err := Set(s, names, []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	clash := NewKey[int]("test.names")
+	if _, _, err := Get(s, clash); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Get with the wrong type = %v, want ErrTypeMismatch", err)
+	}
+	if err := Set(s, clash, 1); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Set with the wrong type = %v, want ErrTypeMismatch", err)
+	}
+	if err := Update(s, clash, func(n int) int { return n + 1 }); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Update with the wrong type = %v, want ErrTypeMismatch", err)
+	}
+}
+
+func TestStoreJSONKeepsTypes(t *testing.T) {
+	s := NewStore()
+	ports := []OpenPort{{Port: 443, Service: "https"}, {Port: 22, Banner: "OpenSSH_9.6"}}
+	if err := Set(s, OpenPortsKey, ports); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetJSON("plugin.extra", json.RawMessage(`{"n": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetJSON("webenum.dirs_found", json.RawMessage(`[{"path": "/admin", "status_code": 403}]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := Get(c, OpenPortsKey)
+	if err != nil || !reflect.DeepEqual(got, ports) {
+		t.Errorf("cloned open ports = %v, %v, want %v", got, err, ports)
+	}
+	dirs, _, err := Get(c, DirsFoundKey)
+	if err != nil || len(dirs) != 1 || dirs[0].Path != "/admin" || dirs[0].StatusCode != 403 {
+		t.Errorf("cloned dirs = %v, %v", dirs, err)
+	}
+	if extra, ok := c.Snapshot()["plugin.extra"].(map[string]interface{}); !ok || extra["n"] != float64(1) {
+		t.Errorf("undeclared key = %#v, want generic JSON", c.Snapshot()["plugin.extra"])
+	}
+	if !reflect.DeepEqual(c.Keys(), []string{"plugin.extra", "portscan.open_ports", "webenum.dirs_found"}) {
+		t.Errorf("Keys() = %v", c.Keys())
+	}
+
+	// The clone is independent
+	Set(c, OpenPortsKey, nil)
+	if got, _, _ := Get(s, OpenPortsKey); len(got) != 2 {
+		t.Errorf("changing the clone changed the original: %v", got)
+	}
+
+	if err := s.SetJSON("portscan.open_ports", json.RawMessage(`"not a list"`)); err == nil {
+		t.Error("SetJSON accepted a value of the wrong shape for a declared key")
+	}
+}
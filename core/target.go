@@ -0,0 +1,209 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// MaxCIDRHosts caps how many addresses a single CIDR range may expand to.
+const MaxCIDRHosts = 65536
+
+// TargetKind classifies a normalized target.
+type TargetKind string
+
+const (
+	TargetDomain   TargetKind = "domain"   // apex domain or hostname
+	TargetIP       TargetKind = "ip"       // single IPv4/IPv6 address (also expanded CIDRs)
+	TargetHostPort TargetKind = "hostport" // host or IP with an explicit port
+	TargetURL      TargetKind = "url"      // full http(s) URL
+)
+
+// Target is one normalized scan target. Hostnames are lowercased and
+// converted to punycode; URLs drop default ports.
+type Target struct {
+	Raw  string     `json:"raw"`
+	Kind TargetKind `json:"kind"`
+	Host string     `json:"host"`
+	Port int        `json:"port,omitempty"`
+	URL  string     `json:"url,omitempty"`
+}
+
+// String returns the canonical form handed to modules.
+func (t Target) String() string {
+	switch t.Kind {
+	case TargetURL:
+		return t.URL
+	case TargetHostPort:
+		return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	default:
+		return t.Host
+	}
+}
+
+// ParseTarget normalizes one raw target. CIDR ranges expand to one target
+// per host address, up to MaxCIDRHosts.
+func ParseTarget(raw string) ([]Target, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return nil, fmt.Errorf("empty target")
+	}
+
+	if strings.Contains(s, "://") {
+		t, err := parseURLTarget(s)
+		if err != nil {
+			return nil, err
+		}
+		return []Target{t}, nil
+	}
+
+	if strings.Contains(s, "/") {
+		return expandCIDR(s)
+	}
+
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		h, err := normalizeHost(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %v", raw, err)
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid port in target %q", raw)
+		}
+		return []Target{{Raw: raw, Kind: TargetHostPort, Host: h, Port: p}}, nil
+	}
+
+	h, err := normalizeHost(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %q: %v", raw, err)
+	}
+	kind := TargetDomain
+	if _, err := netip.ParseAddr(h); err == nil {
+		kind = TargetIP
+	}
+	return []Target{{Raw: raw, Kind: kind, Host: h}}, nil
+}
+
+// ReadTargets parses one target per line, skipping blank lines and
+// "#" comments, and drops duplicates. Parse errors are collected so one bad
+// line does not discard the rest of the list.
+func ReadTargets(r io.Reader) ([]Target, []error) {
+	var (
+		targets []Target
+		errs    []error
+		seen    = map[string]bool{}
+	)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parsed, err := ParseTarget(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", line, err))
+			continue
+		}
+		for _, t := range parsed {
+			if !seen[t.String()] {
+				seen[t.String()] = true
+				targets = append(targets, t)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return targets, errs
+}
+
+func parseURLTarget(raw string) (Target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid URL %q: %v", raw, err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Target{}, fmt.Errorf("unsupported URL scheme in %q", raw)
+	}
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid URL %q: %v", raw, err)
+	}
+
+	port := 0
+	if p := u.Port(); p != "" {
+		if port, err = strconv.Atoi(p); err != nil {
+			return Target{}, fmt.Errorf("invalid port in URL %q", raw)
+		}
+	}
+	if (u.Scheme == "http" && port == 80) || (u.Scheme == "https" && port == 443) {
+		port = 0
+	}
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if port != 0 {
+		u.Host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	u.Fragment = ""
+	if port == 0 {
+		port = 80
+		if u.Scheme == "https" {
+			port = 443
+		}
+	}
+	return Target{Raw: raw, Kind: TargetURL, Host: host, Port: port, URL: u.String()}, nil
+}
+
+// normalizeHost lowercases a hostname and converts IDNs to punycode.
+// IP addresses are returned in canonical form.
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.Trim(strings.TrimSpace(host), "[]"), ".")
+	if host == "" {
+		return "", fmt.Errorf("empty host")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.String(), nil
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(ascii), nil
+}
+
+func expandCIDR(raw string) ([]Target, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %v", raw, err)
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("CIDR %s is too large (max %d addresses)", prefix, MaxCIDRHosts)
+	}
+
+	var targets []Target
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		targets = append(targets, Target{Raw: raw, Kind: TargetIP, Host: addr.String()})
+		if !addr.Next().IsValid() {
+			break
+		}
+	}
+	// Drop the IPv4 network and broadcast addresses of regular subnets
+	if prefix.Addr().Is4() && hostBits >= 2 {
+		targets = targets[1 : len(targets)-1]
+	}
+	return targets, nil
+}
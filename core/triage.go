@@ -0,0 +1,189 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// TriageStatus is where a finding stands in review.
+type TriageStatus string
+
+const (
+	TriageNew           TriageStatus = "new"
+	TriageConfirmed     TriageStatus = "confirmed"
+	TriageFalsePositive TriageStatus = "false_positive"
+	TriageFixed         TriageStatus = "fixed"
+)
+
+// TriageStatuses lists every triage status in review order.
+var TriageStatuses = []TriageStatus{TriageNew, TriageConfirmed, TriageFalsePositive, TriageFixed}
+
+// ParseTriageStatus accepts a status name, with "-" or " " for "_".
+func ParseTriageStatus(s string) (TriageStatus, error) {
+	status := TriageStatus(strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(s))))
+	for _, known := range TriageStatuses {
+		if status == known {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("unknown triage status %q (want new, confirmed, false_positive or fixed)", s)
+}
+
+// Triage is the review state of a finding in a workspace. Findings nobody
+// reviewed yet are TriageNew.
+type Triage struct {
+	Status  TriageStatus `json:"status"`
+	Note    string       `json:"note,omitempty"`
+	Updated *time.Time   `json:"updated,omitempty"`
+}
+
+// FindingRecord is a finding as seen across a workspace's scans: the
+// finding from the latest scan that raised it, and its triage.
+type FindingRecord struct {
+	Finding
+	Target    string    `json:"target"`
+	Scans     []string  `json:"scans"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Triage    Triage    `json:"triage"`
+}
+
+// FindingQuery filters a workspace's findings. Zero values match everything.
+type FindingQuery struct {
+	Statuses    []TriageStatus
+	MinSeverity Severity
+	Scan        string // only findings raised by this scan
+	Match       string // case-insensitive substring of the title, asset or module
+}
+
+func (q FindingQuery) match(rec FindingRecord) bool {
+	if len(q.Statuses) > 0 && !containsTriage(q.Statuses, rec.Triage.Status) {
+		return false
+	}
+	if q.MinSeverity != "" && rec.Severity.Rank() < q.MinSeverity.Rank() {
+		return false
+	}
+	if q.Scan != "" && !containsString(rec.Scans, q.Scan) {
+		return false
+	}
+	if q.Match != "" {
+		text := strings.ToLower(rec.Title + "\x00" + rec.Asset.String() + "\x00" + rec.Module)
+		if !strings.Contains(text, strings.ToLower(q.Match)) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTriage(list []TriageStatus, s TriageStatus) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Findings returns the findings of every scan in the workspace matching
+// q, most serious first.
+func (d *DB) Findings(ws string, q FindingQuery) ([]FindingRecord, error) {
+	var list []FindingRecord
+	err := d.view(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, ws)
+		if err != nil || b == nil {
+			return err
+		}
+		started := make(map[string]time.Time)
+		if err := b.Bucket(bucketScans).ForEach(func(k, raw []byte) error {
+			var rec ScanRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			started[string(k)] = rec.Started
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		byID := make(map[string]*FindingRecord)
+		err = b.Bucket(bucketTargets).ForEach(func(k, raw []byte) error {
+			scan, target, _ := strings.Cut(string(k), "\x00")
+			var tr struct {
+				Results []Result `json:"results"`
+			}
+			if err := json.Unmarshal(raw, &tr); err != nil {
+				return fmt.Errorf("corrupt target record %q: %v", k, err)
+			}
+			seen := started[scan]
+			for _, f := range CollectFindings(tr.Results) {
+				rec, ok := byID[f.ID]
+				if !ok {
+					rec = &FindingRecord{FirstSeen: seen, LastSeen: seen}
+					byID[f.ID] = rec
+				}
+				if !seen.Before(rec.LastSeen) || rec.ID == "" {
+					rec.Finding, rec.Target, rec.LastSeen = f, target, seen
+				}
+				if seen.Before(rec.FirstSeen) {
+					rec.FirstSeen = seen
+				}
+				if !containsString(rec.Scans, scan) {
+					rec.Scans = append(rec.Scans, scan)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		triage := b.Bucket(bucketTriage)
+		for id, rec := range byID {
+			rec.Triage = Triage{Status: TriageNew}
+			if triage != nil {
+				if raw := triage.Get([]byte(id)); raw != nil {
+					if err := json.Unmarshal(raw, &rec.Triage); err != nil {
+						return err
+					}
+				}
+			}
+			sort.Slice(rec.Scans, func(i, j int) bool { return started[rec.Scans[i]].Before(started[rec.Scans[j]]) })
+			if q.match(*rec) {
+				list = append(list, *rec)
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(list, func(i, j int) bool { return findingLess(list[i].Finding, list[j].Finding) })
+	return list, err
+}
+
+// SetTriage records the review state of finding id in the workspace.
+func (d *DB) SetTriage(ws, id string, status TriageStatus, note string) (Triage, error) {
+	now := time.Now()
+	t := Triage{Status: status, Note: note, Updated: &now}
+	err := d.update(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, ws)
+		if err != nil {
+			return err
+		}
+		if b.Bucket(bucketAssets).Get([]byte(NodeID(NodeFinding, id))) == nil {
+			return fmt.Errorf("finding %s in workspace %s: %w", id, ws, ErrNotFound)
+		}
+		triage, err := b.CreateBucketIfNotExists(bucketTriage) // workspaces from older versions lack it
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return triage.Put([]byte(id), raw)
+	})
+	return t, err
+}
@@ -0,0 +1,582 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultDBPath holds the workspaces and their scans.
+const DefaultDBPath = ".triksha/triksha.db"
+
+// DefaultWorkspace is used until another workspace is selected.
+const DefaultWorkspace = "default"
+
+// ErrNotFound is returned for unknown workspaces and scans.
+var ErrNotFound = errors.New("not found")
+
+var workspaceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Bucket layout: "meta" holds the current workspace, "workspaces" one
+// Workspace per name, and "ws:<name>" the workspace's own buckets.
+var (
+	bucketMeta       = []byte("meta")
+	bucketWorkspaces = []byte("workspaces")
+	bucketScans      = []byte("scans")   // scan ID -> ScanRecord
+	bucketTargets    = []byte("targets") // scan ID \x00 target -> TargetRecord
+	bucketAssets     = []byte("assets")  // node ID -> AssetRecord
+	bucketTriage     = []byte("triage")  // finding ID -> Triage
+	keyCurrent       = []byte("current")
+)
+
+// Workspace groups the scans of one engagement.
+type Workspace struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// ScanStatus tells whether a recorded scan completed.
+type ScanStatus string
+
+const (
+	ScanRunning     ScanStatus = "running"
+	ScanFinished    ScanStatus = "finished"
+	ScanInterrupted ScanStatus = "interrupted"
+)
+
+// ScanRecord summarizes a scan stored in a workspace.
+type ScanRecord struct {
+	ID            string           `json:"id"`
+	Started       time.Time        `json:"started"`
+	Finished      time.Time        `json:"finished,omitempty"`
+	Status        ScanStatus       `json:"status"`
+	Targets       []string         `json:"targets"`
+	Modules       []string         `json:"modules,omitempty"`
+	Results       int              `json:"results"`
+	Assets        map[NodeKind]int `json:"assets,omitempty"`
+	FindingCounts map[Severity]int `json:"finding_counts,omitempty"`
+}
+
+// TargetRecord is the stored outcome of one target of a scan.
+type TargetRecord struct {
+	Results   []Result        `json:"results"`
+	Store     *Store          `json:"store,omitempty"`
+	Assets    *AssetGraph     `json:"assets"`
+	Decisions []AgentDecision `json:"decisions,omitempty"`
+}
+
+// AgentDecision is one module the agent chose to run, and why.
+type AgentDecision struct {
+	Time   time.Time              `json:"time"`
+	Module string                 `json:"module"`
+	Reason string                 `json:"reason,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// AssetRecord is an asset as seen across a workspace's scans: the node
+// from the latest scan that reported it, and every scan that did.
+type AssetRecord struct {
+	Node
+	Scans     []string  `json:"scans"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// AssetQuery filters a workspace's assets. Zero values match everything.
+type AssetQuery struct {
+	Kinds []NodeKind
+	Scan  string // only assets reported by this scan
+	Match string // case-insensitive substring of the value
+}
+
+// dbLockTimeout bounds the wait for another process's transaction. The
+// file is only locked while an operation runs, so waits are short.
+const dbLockTimeout = 10 * time.Second
+
+// DB stores workspaces, scans, per-target results and asset graphs, and
+// an asset inventory merged across each workspace's scans.
+//
+// The file is opened for each operation rather than held, so a monitor or
+// server recording scans does not lock out the query commands or other
+// scans. Reads take a shared lock.
+type DB struct {
+	path string
+	mu   sync.RWMutex // serializes this process's writes
+}
+
+// OpenDB creates the database at path if needed and checks it can be
+// opened.
+func OpenDB(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	d := &DB{path: path}
+	if err := d.update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketMeta); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketWorkspaces)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// view runs fn in a read-only transaction.
+func (d *DB) view(fn func(*bolt.Tx) error) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	db, err := d.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// update runs fn in a read-write transaction.
+func (d *DB) update(fn func(*bolt.Tx) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, err := d.open(false)
+	if err != nil {
+		return err
+	}
+	if err := db.Update(fn); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+func (d *DB) open(readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(d.path, 0644, &bolt.Options{Timeout: dbLockTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("open database %s: locked by another triksha process", d.path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open database %s: %v", d.path, err)
+	}
+	return db, nil
+}
+
+// CreateWorkspace adds an empty workspace.
+func (d *DB) CreateWorkspace(name, description string) error {
+	if !workspaceName.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q (letters, digits, '.', '_' and '-')", name)
+	}
+	return d.update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketWorkspaces).Get([]byte(name)) != nil {
+			return fmt.Errorf("workspace %s already exists", name)
+		}
+		return createWorkspace(tx, Workspace{Name: name, Description: description, Created: time.Now()})
+	})
+}
+
+func createWorkspace(tx *bolt.Tx, ws Workspace) error {
+	raw, err := json.Marshal(ws)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketWorkspaces).Put([]byte(ws.Name), raw); err != nil {
+		return err
+	}
+	b, err := tx.CreateBucketIfNotExists(workspaceBucket(ws.Name))
+	if err != nil {
+		return err
+	}
+	for _, name := range [][]byte{bucketScans, bucketTargets, bucketAssets, bucketTriage} {
+		if _, err := b.CreateBucketIfNotExists(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func workspaceBucket(name string) []byte { return []byte("ws:" + name) }
+
+// workspace returns the buckets of an existing workspace. The default
+// workspace is created on first write; until then it reads as nil.
+func workspace(tx *bolt.Tx, name string) (*bolt.Bucket, error) {
+	if b := tx.Bucket(workspaceBucket(name)); b != nil {
+		return b, nil
+	}
+	if name != DefaultWorkspace {
+		return nil, fmt.Errorf("workspace %s: %w", name, ErrNotFound)
+	}
+	if !tx.Writable() {
+		return nil, nil
+	}
+	if err := createWorkspace(tx, Workspace{Name: name, Created: time.Now()}); err != nil {
+		return nil, err
+	}
+	return tx.Bucket(workspaceBucket(name)), nil
+}
+
+// Workspaces lists every workspace by name.
+func (d *DB) Workspaces() ([]Workspace, error) {
+	var list []Workspace
+	err := d.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketWorkspaces).ForEach(func(_, raw []byte) error {
+			var ws Workspace
+			if err := json.Unmarshal(raw, &ws); err != nil {
+				return err
+			}
+			list = append(list, ws)
+			return nil
+		})
+	})
+	return list, err
+}
+
+// DeleteWorkspace removes a workspace and everything recorded in it.
+func (d *DB) DeleteWorkspace(name string) error {
+	return d.update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketWorkspaces).Get([]byte(name)) == nil {
+			return fmt.Errorf("workspace %s: %w", name, ErrNotFound)
+		}
+		if err := tx.Bucket(bucketWorkspaces).Delete([]byte(name)); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(workspaceBucket(name)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		if string(tx.Bucket(bucketMeta).Get(keyCurrent)) == name {
+			return tx.Bucket(bucketMeta).Delete(keyCurrent)
+		}
+		return nil
+	})
+}
+
+// CurrentWorkspace returns the selected workspace, DefaultWorkspace if
+// none was selected.
+func (d *DB) CurrentWorkspace() (string, error) {
+	name := DefaultWorkspace
+	err := d.view(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(bucketMeta).Get(keyCurrent); v != nil {
+			name = string(v)
+		}
+		return nil
+	})
+	return name, err
+}
+
+// UseWorkspace selects the workspace later scans record into.
+func (d *DB) UseWorkspace(name string) error {
+	return d.update(func(tx *bolt.Tx) error {
+		if _, err := workspace(tx, name); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketMeta).Put(keyCurrent, []byte(name))
+	})
+}
+
+// Scans lists a workspace's scans, newest first.
+func (d *DB) Scans(ws string) ([]ScanRecord, error) {
+	var scans []ScanRecord
+	err := d.view(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, ws)
+		if err != nil || b == nil {
+			return err
+		}
+		return b.Bucket(bucketScans).ForEach(func(_, raw []byte) error {
+			var rec ScanRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			scans = append(scans, rec)
+			return nil
+		})
+	})
+	sort.Slice(scans, func(i, j int) bool { return scans[i].Started.After(scans[j].Started) })
+	return scans, err
+}
+
+// Scan returns one recorded scan.
+func (d *DB) Scan(ws, id string) (ScanRecord, error) {
+	var rec ScanRecord
+	err := d.view(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, ws)
+		if err != nil {
+			return err
+		}
+		var raw []byte
+		if b != nil {
+			raw = b.Bucket(bucketScans).Get([]byte(id))
+		}
+		if raw == nil {
+			return fmt.Errorf("scan %s in workspace %s: %w", id, ws, ErrNotFound)
+		}
+		return json.Unmarshal(raw, &rec)
+	})
+	return rec, err
+}
+
+// Snapshot loads a recorded scan's results and merged asset graph.
+func (d *DB) Snapshot(ws, id string) (ScanSnapshot, error) {
+	rec, err := d.Scan(ws, id)
+	if err != nil {
+		return ScanSnapshot{}, err
+	}
+	snap := ScanSnapshot{ID: rec.ID, Time: rec.Started, Targets: rec.Targets, Assets: NewAssetGraph()}
+	if !rec.Finished.IsZero() {
+		snap.Time = rec.Finished
+	}
+	targets, err := d.Targets(ws, id)
+	for _, target := range rec.Targets {
+		if tr, ok := targets[target]; ok {
+			snap.Results = append(snap.Results, tr.Results...)
+			snap.Assets.Merge(tr.Assets)
+		}
+	}
+	return snap, err
+}
+
+// Targets loads the stored targets of a scan, keyed by target.
+func (d *DB) Targets(ws, id string) (map[string]TargetRecord, error) {
+	targets := make(map[string]TargetRecord)
+	err := d.view(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, ws)
+		if err != nil || b == nil {
+			return err
+		}
+		c := b.Bucket(bucketTargets).Cursor()
+		prefix := id + "\x00"
+		for k, raw := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, raw = c.Next() {
+			var tr TargetRecord
+			if err := json.Unmarshal(raw, &tr); err != nil {
+				return fmt.Errorf("corrupt target record %q: %v", k, err)
+			}
+			targets[strings.TrimPrefix(string(k), prefix)] = tr
+		}
+		return nil
+	})
+	return targets, err
+}
+
+// Assets returns the workspace's inventory matching q, sorted by kind
+// and value.
+func (d *DB) Assets(ws string, q AssetQuery) ([]AssetRecord, error) {
+	var assets []AssetRecord
+	match := strings.ToLower(q.Match)
+	err := d.view(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, ws)
+		if err != nil || b == nil {
+			return err
+		}
+		return b.Bucket(bucketAssets).ForEach(func(_, raw []byte) error {
+			var rec AssetRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+			if len(q.Kinds) > 0 && !containsKind(q.Kinds, rec.Kind) {
+				return nil
+			}
+			if q.Scan != "" && !containsString(rec.Scans, q.Scan) {
+				return nil
+			}
+			if match != "" && !strings.Contains(strings.ToLower(rec.Value), match) {
+				return nil
+			}
+			assets = append(assets, rec)
+			return nil
+		})
+	})
+	sort.Slice(assets, func(i, j int) bool {
+		if assets[i].Kind != assets[j].Kind {
+			return kindRank(assets[i].Kind) < kindRank(assets[j].Kind)
+		}
+		return assets[i].Value < assets[j].Value
+	})
+	return assets, err
+}
+
+// ScanRecorder writes one scan's progress into a workspace as modules
+// finish. A nil *ScanRecorder records nothing.
+type ScanRecorder struct {
+	db        *DB
+	workspace string
+
+	mu        sync.Mutex
+	rec       ScanRecord
+	results   map[string][]Result
+	assets    map[string]*AssetGraph
+	decisions map[string][]AgentDecision
+}
+
+// StartScan records a new scan, or reopens a resumed one, in workspace ws.
+// An empty id generates one.
+func (d *DB) StartScan(ws, id string, targets []Target, modules []string) (*ScanRecorder, error) {
+	if id == "" {
+		var err error
+		if id, err = NewScanID(); err != nil {
+			return nil, err
+		}
+	}
+	r := &ScanRecorder{
+		db:        d,
+		workspace: ws,
+		results:   make(map[string][]Result),
+		assets:    make(map[string]*AssetGraph),
+		decisions: make(map[string][]AgentDecision),
+	}
+	if rec, err := d.Scan(ws, id); err == nil {
+		r.rec = rec
+		targets, err := d.Targets(ws, id)
+		if err != nil {
+			return nil, err
+		}
+		for target, tr := range targets {
+			r.results[target] = tr.Results
+			r.assets[target] = tr.Assets
+			r.decisions[target] = tr.Decisions
+		}
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	} else {
+		r.rec = ScanRecord{ID: id, Started: time.Now(), Modules: modules}
+		for _, t := range targets {
+			r.rec.Targets = append(r.rec.Targets, t.String())
+		}
+	}
+	r.rec.Status = ScanRunning
+	r.rec.Finished = time.Time{}
+	return r, d.update(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, ws)
+		if err != nil {
+			return err
+		}
+		return r.putScan(b)
+	})
+}
+
+// ID returns the recorded scan's ID.
+func (r *ScanRecorder) ID() string { return r.rec.ID }
+
+// Workspace returns the workspace the scan is recorded in.
+func (r *ScanRecorder) Workspace() string { return r.workspace }
+
+// Decide notes an agent decision for target. It is stored with the
+// target's next Save.
+func (r *ScanRecorder) Decide(target string, d AgentDecision) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decisions[target] = append(r.decisions[target], d)
+}
+
+// Save stores a target's results, data store, asset graph and agent
+// decisions and folds its assets into the workspace inventory.
+func (r *ScanRecorder) Save(target string, results []Result, store *Store, assets *AssetGraph) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[target] = append([]Result(nil), results...)
+	r.assets[target] = assets
+	r.tally()
+
+	raw, err := json.Marshal(TargetRecord{Results: results, Store: store, Assets: assets, Decisions: r.decisions[target]})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return r.db.update(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, r.workspace)
+		if err != nil {
+			return err
+		}
+		if err := b.Bucket(bucketTargets).Put([]byte(r.rec.ID+"\x00"+target), raw); err != nil {
+			return err
+		}
+		inventory := b.Bucket(bucketAssets)
+		for _, n := range assets.Nodes() {
+			var rec AssetRecord
+			if old := inventory.Get([]byte(n.ID)); old != nil {
+				if err := json.Unmarshal(old, &rec); err != nil {
+					return err
+				}
+			} else {
+				rec.FirstSeen = now
+			}
+			rec.Node = n
+			rec.LastSeen = now
+			if !containsString(rec.Scans, r.rec.ID) {
+				rec.Scans = append(rec.Scans, r.rec.ID)
+			}
+			data, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			if err := inventory.Put([]byte(n.ID), data); err != nil {
+				return err
+			}
+		}
+		return r.putScan(b)
+	})
+}
+
+// Finish marks the scan finished or interrupted.
+func (r *ScanRecorder) Finish(status ScanStatus) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Status = status
+	r.rec.Finished = time.Now()
+	return r.db.update(func(tx *bolt.Tx) error {
+		b, err := workspace(tx, r.workspace)
+		if err != nil {
+			return err
+		}
+		return r.putScan(b)
+	})
+}
+
+// tally refreshes the scan's result, asset and finding counts. Callers
+// hold r.mu.
+func (r *ScanRecorder) tally() {
+	var all []Result
+	graph := NewAssetGraph()
+	for _, results := range r.results {
+		all = append(all, results...)
+	}
+	for _, g := range r.assets {
+		graph.Merge(g)
+	}
+	r.rec.Results = len(all)
+	r.rec.Assets = graph.Counts()
+	r.rec.FindingCounts = CountBySeverity(CollectFindings(all))
+}
+
+func (r *ScanRecorder) putScan(b *bolt.Bucket) error {
+	raw, err := json.Marshal(r.rec)
+	if err != nil {
+		return err
+	}
+	return b.Bucket(bucketScans).Put([]byte(r.rec.ID), raw)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
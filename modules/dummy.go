@@ -1,7 +1,7 @@
 package modules
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/r4j3sh-com/triksha/core"
 )
@@ -10,8 +10,17 @@ type DummyModule struct{}
 
 func (m *DummyModule) Name() string { return "dummy" }
 
-func (m *DummyModule) Run(target string, ctx *core.Context) (core.Result, error) {
-	fmt.Printf("[dummy] Running dummy recon for target: %s\n", target)
+func (m *DummyModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: "Test module that only logs the target",
+		Category:    core.CategoryPassive,
+		Noise:       core.NoiseNone,
+		Manual:      true,
+	}
+}
+
+func (m *DummyModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	rctx.Logger(m.Name()).Infof("Running dummy recon for target: %s", target)
 	result := core.Result{
 		ModuleName: m.Name(),
 		Data:       map[string]interface{}{"message": "dummy recon complete"},
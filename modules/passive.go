@@ -1,9 +1,12 @@
 package modules
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
+	"strings"
+	"time"
 
 	"github.com/likexian/whois"
 	whoisparser "github.com/likexian/whois-parser"
@@ -21,16 +24,42 @@ type PassiveModule struct{}
 
 func (m *PassiveModule) Name() string { return "passive" }
 
-func (m *PassiveModule) Run(target string, ctx *core.Context) (core.Result, error) {
+func (m *PassiveModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: "Passive reconnaissance: WHOIS, DNS records and certificate transparency",
+		Category:    core.CategoryPassive,
+		Noise:       core.NoiseNone,
+		Order:       10,
+	}
+}
+
+func (m *PassiveModule) Consumes() []string { return nil }
+
+func (m *PassiveModule) Produces() []string { return nil }
+
+func (m *PassiveModule) Params() []core.ParamSpec {
+	return []core.ParamSpec{
+		{Name: "record_types", Type: core.ParamStringList, Default: []string{"A", "NS", "MX"}, Description: "DNS record types to query (A, NS, MX)"},
+		{Name: "crtsh", Type: core.ParamBool, Default: true, Description: "Query crt.sh certificate transparency logs"},
+	}
+}
+
+func (m *PassiveModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	// URL and host:port targets are reduced to their host
+	target = hostOf(target)
 	result := PassiveReconResult{
 		Whois:        make(map[string]interface{}),
 		DNSRecords:   make(map[string][]string),
 		CrtshEntries: []string{},
 	}
-	fmt.Printf("[passive] Running passive recon for: %s\n", target)
+	rctx.Logger(m.Name()).Infof("Running passive recon for: %s", target)
 
 	// 1. WHOIS Lookup
-	whoisRaw, err := whois.Whois(target)
+	whoisRaw, err := core.Cached(rctx.Cache, "whois", target, func() (string, error) {
+		return core.WithRetry(ctx, rctx, m.Name(), "whois", func() (string, error) {
+			return whoisLookup(ctx, rctx.Limiter, target)
+		})
+	})
 	if err == nil {
 		parsedWhois, err := whoisparser.Parse(whoisRaw)
 		if err == nil {
@@ -44,33 +73,60 @@ func (m *PassiveModule) Run(target string, ctx *core.Context) (core.Result, erro
 	}
 
 	// 2. DNS Records (A, MX, NS)
-	for _, recordType := range []string{"A", "NS", "MX"} {
-		records, err := lookupDNS(target, recordType)
+	for _, recordType := range params.StringList("record_types") {
+		recordType = strings.ToUpper(recordType)
+		records, err := core.WithRetry(ctx, rctx, m.Name(), "dns", func() ([]string, error) {
+			return lookupDNS(ctx, rctx.Limiter.Resolver(), target, recordType)
+		})
 		if err == nil {
 			result.DNSRecords[recordType] = records
 		}
 	}
+	assets := rctx.AssetWriter(m.Name())
+	assets.From("dns").Resolves(target, result.DNSRecords["A"])
+
+	if err := ctx.Err(); err != nil {
+		return core.Result{}, err
+	}
 
 	// 3. crt.sh (subdomains by certificate transparency logs)
-	entries, err := FetchCRTshEntries(target)
-	if err == nil {
-		result.CrtshEntries = entries
+	data := map[string]interface{}{
+		"whois":       result.Whois,
+		"dns_records": result.DNSRecords,
+	}
+	if params.Bool("crtsh") {
+		entries, err := cachedCRTsh(ctx, rctx, m.Name(), target)
+		if err == nil {
+			result.CrtshEntries = entries
+			crtsh := assets.From("crtsh")
+			for _, entry := range entries {
+				if !strings.HasPrefix(entry, "*.") {
+					crtsh.Host(entry, nil)
+				}
+			}
+		} else if ctx.Err() == nil {
+			rctx.Logger(m.Name()).Warnf("crt.sh lookup failed: %v", err)
+			data["crtsh_error"] = err.Error()
+		}
 	}
+	data["crtsh_entries"] = result.CrtshEntries
+
+	return core.Result{ModuleName: m.Name(), Data: data}, nil
+}
 
-	return core.Result{
-		ModuleName: m.Name(),
-		Data: map[string]interface{}{
-			"whois":         result.Whois,
-			"dns_records":   result.DNSRecords,
-			"crtsh_entries": result.CrtshEntries,
-		},
-	}, nil
+// whoisLookup runs a WHOIS query that is aborted once ctx is done.
+func whoisLookup(ctx context.Context, lim *core.Limiter, domain string) (string, error) {
+	client := whois.NewClient().SetDialer(&ctxDialer{ctx: ctx, limiter: lim})
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < 30*time.Second {
+		client.SetTimeout(time.Until(deadline))
+	}
+	return client.Whois(domain)
 }
 
-func lookupDNS(domain string, recordType string) ([]string, error) {
+func lookupDNS(ctx context.Context, resolver *net.Resolver, domain string, recordType string) ([]string, error) {
 	switch recordType {
 	case "A":
-		ips, err := net.LookupIP(domain)
+		ips, err := resolver.LookupIP(ctx, "ip", domain)
 		if err != nil {
 			return nil, err
 		}
@@ -80,7 +136,7 @@ func lookupDNS(domain string, recordType string) ([]string, error) {
 		}
 		return results, nil
 	case "NS":
-		nss, err := net.LookupNS(domain)
+		nss, err := resolver.LookupNS(ctx, domain)
 		if err != nil {
 			return nil, err
 		}
@@ -90,7 +146,7 @@ func lookupDNS(domain string, recordType string) ([]string, error) {
 		}
 		return results, nil
 	case "MX":
-		mxs, err := net.LookupMX(domain)
+		mxs, err := resolver.LookupMX(ctx, domain)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,398 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+// PluginProtocol is the version of the plugin protocol described in
+// docs/plugins.md.
+const PluginProtocol = 1
+
+// DefaultPluginDir returns the user-wide plugins directory, e.g.
+// ~/.config/triksha/plugins, or "" if there is no config directory.
+// Plugins are never loaded from the working directory unless asked to.
+func DefaultPluginDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "triksha", "plugins")
+}
+
+// describeTimeout bounds the "describe" call made while loading a plugin.
+const describeTimeout = 10 * time.Second
+
+var pluginNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// pluginManifest is a plugin's answer to "describe".
+type pluginManifest struct {
+	Protocol    int              `json:"protocol"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Params      []core.ParamSpec `json:"params"`
+	Consumes    []string         `json:"consumes"`
+	Produces    []string         `json:"produces"`
+	Category    core.Category    `json:"category"`
+	Noise       core.Noise       `json:"noise"`
+	Binaries    []string         `json:"binaries"`
+	Order       int              `json:"order"`
+	MaxRuns     int              `json:"max_runs"`
+	APIKeys     []string         `json:"api_keys"`
+}
+
+// pluginRequest is written to the plugin's stdin for "run".
+type pluginRequest struct {
+	Protocol int                    `json:"protocol"`
+	Target   string                 `json:"target"`
+	Params   map[string]interface{} `json:"params"`
+	Store    *core.Store            `json:"store"`
+	Assets   *core.AssetGraph       `json:"assets"`
+	Limits   core.RateLimits        `json:"rate_limits"`
+	Scope    core.ScopeConfig       `json:"scope"`
+	APIKeys  map[string]string      `json:"api_keys,omitempty"`
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// pluginMessage is one JSON line streamed back by a running plugin.
+type pluginMessage struct {
+	Type    string                 `json:"type"` // log, asset, finding, store, result or error
+	Level   string                 `json:"level,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Kind    string                 `json:"kind,omitempty"`
+	Key     string                 `json:"key,omitempty"`
+	Value   json.RawMessage        `json:"value,omitempty"`
+	Finding *core.Finding          `json:"finding,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// PluginModule wraps an external executable speaking the plugin protocol
+// as a core.Module.
+type PluginModule struct {
+	Path     string
+	manifest pluginManifest
+}
+
+func (p *PluginModule) Name() string { return p.manifest.Name }
+
+// Description is the plugin's one-line summary from "describe".
+func (p *PluginModule) Description() string { return p.manifest.Description }
+
+// Info returns the catalog metadata from "describe".
+func (p *PluginModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: p.manifest.Description,
+		Category:    p.manifest.Category,
+		Noise:       p.manifest.Noise,
+		Binaries:    p.manifest.Binaries,
+		Order:       p.manifest.Order,
+		MaxRuns:     p.manifest.MaxRuns,
+	}
+}
+
+func (p *PluginModule) Params() []core.ParamSpec { return p.manifest.Params }
+
+func (p *PluginModule) Consumes() []string { return p.manifest.Consumes }
+
+func (p *PluginModule) Produces() []string { return p.manifest.Produces }
+
+// LoadPlugins describes every executable in dir. A missing directory is not
+// an error; plugins that fail to load are reported and skipped. A directory
+// or file other users can write to is refused, since anyone who can write
+// it can run code as the operator.
+func LoadPlugins(dir string) ([]*PluginModule, []error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if info, err := os.Stat(dir); err == nil && info.Mode().Perm()&0022 != 0 {
+		return nil, []error{fmt.Errorf("plugin directory %s is group- or world-writable", dir)}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+
+	var (
+		plugins []*PluginModule
+		errs    []error
+		seen    = map[string]string{}
+	)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if info.Mode().Perm()&0022 != 0 {
+			errs = append(errs, fmt.Errorf("plugin %s: file is group- or world-writable", path))
+			continue
+		}
+		p, err := LoadPlugin(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other, dup := seen[p.Name()]; dup {
+			errs = append(errs, fmt.Errorf("plugin %s: name %q already used by %s", path, p.Name(), other))
+			continue
+		}
+		seen[p.Name()] = path
+		plugins = append(plugins, p)
+	}
+	return plugins, errs
+}
+
+// LoadPlugin runs "<path> describe" and validates the manifest.
+func LoadPlugin(path string) (*PluginModule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "describe")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s: describe failed: %v %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var m pluginManifest
+	if err := json.Unmarshal(stdout.Bytes(), &m); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid describe output: %v", path, err)
+	}
+	if m.Protocol != PluginProtocol {
+		return nil, fmt.Errorf("plugin %s: unsupported protocol %d (want %d)", path, m.Protocol, PluginProtocol)
+	}
+	if !pluginNameRe.MatchString(m.Name) {
+		return nil, fmt.Errorf("plugin %s: invalid name %q", path, m.Name)
+	}
+	switch m.Category {
+	case "", core.CategoryPassive, core.CategoryActive, core.CategoryReport:
+	default:
+		return nil, fmt.Errorf("plugin %s: unknown category %q", path, m.Category)
+	}
+	switch m.Noise {
+	case "", core.NoiseNone, core.NoiseLow, core.NoiseMedium, core.NoiseHigh:
+	default:
+		return nil, fmt.Errorf("plugin %s: unknown noise level %q", path, m.Noise)
+	}
+	// Resolving the defaults validates every declared param type
+	if _, err := core.ResolveParams(m.Params); err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", path, err)
+	}
+	return &PluginModule{Path: path, manifest: m}, nil
+}
+
+// Run executes "<path> run", sends the request on stdin and applies the
+// streamed messages as they arrive.
+func (p *PluginModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	log := rctx.Logger(p.Name())
+	log.Infof("Running plugin %s for: %s", p.Path, target)
+
+	req, err := json.Marshal(pluginRequest{
+		Protocol: PluginProtocol,
+		Target:   target,
+		Params:   p.encodeParams(params, rctx),
+		Store:    rctx.Store,
+		Assets:   rctx.Assets,
+		Limits:   rctx.Limiter.Limits(),
+		Scope:    rctx.Scope.Config(),
+		APIKeys:  p.apiKeys(rctx),
+		Settings: rctx.Settings,
+	})
+	if err != nil {
+		return core.Result{}, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path, "run")
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return core.Result{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return core.Result{}, fmt.Errorf("plugin %s: %v", p.Name(), err)
+	}
+
+	result := core.Result{ModuleName: p.Name(), Data: map[string]interface{}{}}
+	var pluginErr error
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg pluginMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			log.Debugf("Ignoring non-JSON output: %s", line)
+			continue
+		}
+		if err := p.apply(msg, &result, rctx, log); err != nil && pluginErr == nil {
+			pluginErr = err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// Keep draining so the plugin never blocks on a full pipe
+		io.Copy(io.Discard, stdout)
+		if pluginErr == nil {
+			pluginErr = fmt.Errorf("plugin %s: reading output: %v", p.Name(), err)
+		}
+	}
+	waitErr := cmd.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return core.Result{}, err
+	}
+	if waitErr != nil {
+		return core.Result{}, fmt.Errorf("plugin %s: %v %s", p.Name(), waitErr, strings.TrimSpace(stderr.String()))
+	}
+	if pluginErr != nil {
+		return core.Result{}, pluginErr
+	}
+	return result, nil
+}
+
+// apiKeys returns the configured credentials for the services the
+// manifest declares; the plugin sees no others.
+func (p *PluginModule) apiKeys(rctx *core.Context) map[string]string {
+	keys := map[string]string{}
+	for _, service := range p.manifest.APIKeys {
+		if key := rctx.APIKey(service); key != "" {
+			keys[service] = key
+		}
+	}
+	return keys
+}
+
+// recordAsset adds an asset the plugin reported to the scan's graph.
+// Hosts, services and URLs outside the scope are kept but marked out of
+// scope, as the built-in modules do. Kinds the graph does not know are
+// only announced on the event bus.
+func (p *PluginModule) recordAsset(rctx *core.Context, log *core.Logger, kind, value string) {
+	assets := rctx.AssetWriter(p.Name())
+	switch core.NodeKind(kind) {
+	case core.NodeDomain, core.NodeSubdomain, core.NodeIP:
+		assets.Host(value, scopeAttrs(rctx, value, rctx.Scope.CheckHost(value)))
+	case core.NodeService:
+		host, port, err := net.SplitHostPort(value)
+		n, _ := strconv.Atoi(port)
+		if err != nil || n == 0 {
+			log.Asset(kind, value)
+			return
+		}
+		assets.Service(host, n, scopeAttrs(rctx, value, rctx.Scope.CheckPort(host, n)))
+	case core.NodeURL:
+		assets.URL(value, scopeAttrs(rctx, value, rctx.Scope.CheckURL(value)))
+	case core.NodeNetblock, core.NodeTechnology, core.NodeCertificate:
+		assets.Add(core.NodeKind(kind), value, nil)
+	default:
+		log.Asset(kind, value)
+	}
+}
+
+// scopeAttrs marks asset as seen but out of scope when err is set.
+func scopeAttrs(rctx *core.Context, asset string, err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+	core.MarkOutOfScope(rctx, asset)
+	return map[string]string{"out_of_scope": "true"}
+}
+
+// apply handles one streamed message.
+func (p *PluginModule) apply(msg pluginMessage, result *core.Result, rctx *core.Context, log *core.Logger) error {
+	switch msg.Type {
+	case "log":
+		level, err := core.ParseLevel(msg.Level)
+		if err != nil {
+			level = core.LevelInfo
+		}
+		log.Publish(core.Event{Type: core.EventLog, Level: level, Message: msg.Message})
+	case "asset":
+		var value string
+		if err := json.Unmarshal(msg.Value, &value); err != nil {
+			return fmt.Errorf("plugin %s: asset value must be a string", p.Name())
+		}
+		p.recordAsset(rctx, log, msg.Kind, value)
+	case "finding":
+		if msg.Finding == nil {
+			return fmt.Errorf("plugin %s: finding message without a finding", p.Name())
+		}
+		f := *msg.Finding
+		if f.Severity != "" {
+			sev, err := core.ParseSeverity(string(f.Severity))
+			if err != nil {
+				return fmt.Errorf("plugin %s: %v", p.Name(), err)
+			}
+			f.Severity = sev
+		}
+		f.Module = p.Name()
+		result.AddFinding(f)
+	case "store":
+		if !contains(p.Produces(), msg.Key) {
+			return fmt.Errorf("plugin %s: store key %q is not declared in produces", p.Name(), msg.Key)
+		}
+		if err := rctx.Store.SetJSON(msg.Key, msg.Value); err != nil {
+			return fmt.Errorf("plugin %s: %v", p.Name(), err)
+		}
+	case "result":
+		for k, v := range msg.Data {
+			result.Data[k] = v
+		}
+	case "error":
+		return fmt.Errorf("plugin %s: %s", p.Name(), msg.Message)
+	default:
+		log.Debugf("Ignoring unknown message type %q", msg.Type)
+	}
+	return nil
+}
+
+// encodeParams renders durations as strings ("2s") so plugins get the
+// same form users write in config files, and resolves path parameters
+// under the wordlist directory.
+func (p *PluginModule) encodeParams(params core.Params, rctx *core.Context) map[string]interface{} {
+	out := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		if d, ok := v.(time.Duration); ok {
+			out[k] = d.String()
+			continue
+		}
+		out[k] = v
+	}
+	for _, spec := range p.manifest.Params {
+		if v, ok := params[spec.Name].(string); ok && spec.Type == core.ParamPath {
+			out[spec.Name] = rctx.Wordlist(v)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
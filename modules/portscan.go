@@ -3,6 +3,7 @@ package modules
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -16,11 +17,7 @@ import (
 )
 
 // PortScanResult is a struct for open port info
-type PortScanResult struct {
-	Port    int    `json:"port"`
-	Banner  string `json:"banner,omitempty"`
-	Service string `json:"service,omitempty"`
-}
+type PortScanResult = core.OpenPort
 
 // NaabuResult represents the JSON output from Naabu CLI
 type NaabuResult struct {
@@ -33,60 +30,103 @@ type PortscanModule struct{}
 
 func (m *PortscanModule) Name() string { return "portscan" }
 
-func (m *PortscanModule) Run(target string, ctx *core.Context) (core.Result, error) {
-	fmt.Printf("[portscan] Scanning ports for: %s\n", target)
+func (m *PortscanModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: "Scans for open ports and detects services",
+		Category:    core.CategoryActive,
+		Noise:       core.NoiseHigh,
+		Binaries:    []string{"naabu", "nmap"},
+		Order:       30,
+	}
+}
+
+func (m *PortscanModule) Consumes() []string { return nil }
+
+func (m *PortscanModule) Produces() []string { return []string{core.OpenPortsKey.Name()} }
+
+// commonPorts is the default port list.
+var commonPorts = []int{
+	21, 22, 23, 25, 53, 80, 110, 111, 135, 139, 143, 443,
+	445, 465, 587, 993, 995, 1433, 1521, 1723, 3306, 3389,
+	389, 5900, 8080, 8443, 8888, 9090, 9200, 9300, 27017, 6379,
+}
 
-	// Define common ports to scan
-	commonPorts := []int{
-		21, 22, 23, 25, 53, 80, 110, 111, 135, 139, 143, 443,
-		445, 465, 587, 993, 995, 1433, 1521, 1723, 3306, 3389,
-		389, 5900, 8080, 8443, 8888, 9090, 9200, 9300, 27017, 6379,
+func (m *PortscanModule) Params() []core.ParamSpec {
+	return []core.ParamSpec{
+		{Name: "ports", Type: core.ParamIntList, Default: commonPorts, Description: "Ports to scan, e.g. \"80,443,8000-8100\""},
+		{Name: "rate", Type: core.ParamInt, Default: 500, Description: "Naabu packets per second"},
+		{Name: "timeout", Type: core.ParamDuration, Default: "2s", Description: "Connect timeout for the fallback scanner"},
+		{Name: "service_detection", Type: core.ParamBool, Default: true, Description: "Run nmap -sV on open ports"},
 	}
+}
+
+func (m *PortscanModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	// URL and host:port targets are reduced to their host
+	target = hostOf(target)
+	log := rctx.Logger(m.Name())
+	log.Infof("Scanning ports for: %s", target)
+
+	ports := scopedPorts(rctx, target, params.IntList("ports"))
+	if len(ports) == 0 {
+		return core.Result{}, fmt.Errorf("no ports to scan")
+	}
+	timeout := params.Duration("timeout")
 
 	// Step 1: Check if naabu is installed
 	_, err := exec.LookPath("naabu")
 	if err != nil {
-		fmt.Println("[portscan] Naabu not found, falling back to basic port scanner")
-		return runBasicPortScan(target, commonPorts)
+		log.Infof("Naabu not found, falling back to basic port scanner")
+		openPorts, err := runBasicPortScan(ctx, log, rctx.Limiter, target, ports, timeout)
+		if err != nil {
+			return core.Result{}, err
+		}
+		return m.result(rctx, target, openPorts)
 	}
 
 	// Convert ports to string format for Naabu
-	portsStr := make([]string, len(commonPorts))
-	for i, port := range commonPorts {
+	portsStr := make([]string, len(ports))
+	for i, port := range ports {
 		portsStr[i] = fmt.Sprintf("%d", port)
 	}
 
 	// Step 2: Run Naabu for fast port discovery
-	fmt.Println("[portscan] Starting Naabu port scan...")
-	openPorts, err := runNaabuScan(target, portsStr)
+	log.Infof("Starting Naabu port scan...")
+	openPorts, err := runNaabuScan(ctx, log, target, portsStr, rctx.Limiter.Rate(params.Int("rate"), core.BudgetTCP, true), rctx.Limiter.Concurrency(50))
 	if err != nil {
-		fmt.Printf("[portscan] Naabu error: %v\n", err)
-		fmt.Println("[portscan] Falling back to basic port scanner")
-		return runBasicPortScan(target, commonPorts)
+		if ctx.Err() != nil {
+			return core.Result{}, ctx.Err()
+		}
+		log.Warnf("Naabu error: %v", err)
+		log.Infof("Falling back to basic port scanner")
+		basicPorts, err := runBasicPortScan(ctx, log, rctx.Limiter, target, ports, timeout)
+		if err != nil {
+			return core.Result{}, err
+		}
+		return m.result(rctx, target, basicPorts)
 	}
 
 	if len(openPorts) == 0 {
-		fmt.Println("[portscan] No open ports found")
-		return core.Result{
-			ModuleName: "portscan",
-			Data: map[string]interface{}{
-				"open_ports": []PortScanResult{},
-				"count":      0,
-				"target":     target,
-				"timestamp":  time.Now().String(),
-			},
-		}, nil
+		log.Infof("No open ports found")
+		return m.result(rctx, target, []PortScanResult{})
 	}
 
 	// Step 3: Run Nmap for service detection on open ports
-	portResults, err := runNmapServiceDetection(target, openPorts)
-	if err != nil {
-		fmt.Printf("[portscan] Nmap error: %v, using basic service detection\n", err)
+	var portResults []PortScanResult
+	if params.Bool("service_detection") {
+		portResults, err = runNmapServiceDetection(ctx, log, rctx.Limiter, target, openPorts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return core.Result{}, ctx.Err()
+			}
+			log.Warnf("Nmap error: %v, using basic service detection", err)
+		}
+	}
+	if portResults == nil {
 		// Fall back to basic service detection
 		portResults = make([]PortScanResult, len(openPorts))
 		for i, port := range openPorts {
 			service := getServiceName(port)
-			banner, _ := grabBanner(target, port)
+			banner, _ := grabBanner(ctx, rctx.Limiter, target, port)
 			portResults[i] = PortScanResult{
 				Port:    port,
 				Service: service,
@@ -95,10 +135,39 @@ func (m *PortscanModule) Run(target string, ctx *core.Context) (core.Result, err
 		}
 	}
 
-	// Store results in context for other modules to use
-	ctx.Store["portscan.open_ports"] = portResults
+	return m.result(rctx, target, portResults)
+}
 
-	return core.Result{
+// scopedPorts drops ports the scope does not allow on host, so neither the
+// built-in scanner nor naabu/nmap ever probe them.
+func scopedPorts(rctx *core.Context, host string, ports []int) []int {
+	var allowed []int
+	for _, port := range ports {
+		if rctx.Scope.CheckPort(host, port) == nil {
+			allowed = append(allowed, port)
+		}
+	}
+	if skipped := len(ports) - len(allowed); skipped > 0 {
+		rctx.Logger("portscan").Infof("Skipping %d out-of-scope port(s) on %s", skipped, host)
+	}
+	return allowed
+}
+
+// result stores the open ports for downstream modules and builds the Result.
+func (m *PortscanModule) result(rctx *core.Context, target string, portResults []PortScanResult) (core.Result, error) {
+	if portResults == nil {
+		portResults = []PortScanResult{}
+	}
+	if err := core.Set(rctx.Store, core.OpenPortsKey, portResults); err != nil {
+		return core.Result{}, err
+	}
+
+	assets := rctx.AssetWriter(m.Name())
+	for _, p := range portResults {
+		assets.Service(target, p.Port, map[string]string{"service": p.Service, "banner": p.Banner})
+	}
+
+	result := core.Result{
 		ModuleName: "portscan",
 		Data: map[string]interface{}{
 			"open_ports": portResults,
@@ -106,18 +175,44 @@ func (m *PortscanModule) Run(target string, ctx *core.Context) (core.Result, err
 			"target":     target,
 			"timestamp":  time.Now().String(),
 		},
-	}, nil
+	}
+	for _, p := range portResults {
+		if name, risky := exposedServices[p.Port]; risky {
+			result.AddFinding(core.Finding{
+				Title:       fmt.Sprintf("%s exposed to the network", name),
+				Severity:    core.SeverityMedium,
+				Confidence:  core.ConfidenceFirm,
+				Asset:       core.Asset{Host: target, Port: p.Port},
+				Evidence:    p.Banner,
+				Remediation: "Restrict access with a firewall or bind the service to internal interfaces.",
+			})
+		}
+	}
+	return result, nil
+}
+
+// exposedServices are services that rarely belong on an internet-facing host.
+var exposedServices = map[int]string{
+	23:    "Telnet",
+	1433:  "MS SQL Server",
+	3306:  "MySQL",
+	3389:  "RDP",
+	5900:  "VNC",
+	6379:  "Redis",
+	9200:  "Elasticsearch",
+	27017: "MongoDB",
 }
 
 // runNaabuScan runs a Naabu scan and returns open ports
-func runNaabuScan(target string, ports []string) ([]int, error) {
+func runNaabuScan(ctx context.Context, log *core.Logger, target string, ports []string, rate, concurrency int) ([]int, error) {
 	// Prepare naabu command
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		"naabu",
 		"-host", target,
 		"-p", strings.Join(ports, ","),
-		"-rate", "500",
-		"-c", "50",
+		"-rate", strconv.Itoa(rate),
+		"-c", strconv.Itoa(concurrency),
 		"-timeout", "5",
 		"-retries", "2",
 		"-silent",
@@ -144,11 +239,11 @@ func runNaabuScan(target string, ports []string) ([]int, error) {
 
 		var result NaabuResult
 		if err := json.Unmarshal([]byte(line), &result); err != nil {
-			fmt.Printf("[portscan] Error parsing Naabu result: %v\n", err)
+			log.Debugf("Error parsing Naabu result: %v", err)
 			continue
 		}
 
-		fmt.Printf("[portscan] Found open port: %d\n", result.Port)
+		log.Debugf("Found open port: %d", result.Port)
 		openPorts = append(openPorts, result.Port)
 	}
 
@@ -156,7 +251,7 @@ func runNaabuScan(target string, ports []string) ([]int, error) {
 }
 
 // runNmapServiceDetection runs Nmap service detection on open ports
-func runNmapServiceDetection(target string, ports []int) ([]PortScanResult, error) {
+func runNmapServiceDetection(ctx context.Context, log *core.Logger, lim *core.Limiter, target string, ports []int) ([]PortScanResult, error) {
 	// Check if nmap is installed
 	_, err := exec.LookPath("nmap")
 	if err != nil {
@@ -170,31 +265,33 @@ func runNmapServiceDetection(target string, ports []int) ([]PortScanResult, erro
 	}
 
 	// Prepare nmap command
-	cmd := exec.Command(
-		"nmap",
+	args := []string{
 		"-sV", // Service/version detection
 		"-T4", // Timing template (higher is faster)
 		"-Pn", // Treat all hosts as online -- skip host discovery
 		"-p", strings.Join(portsStr, ","),
-		target,
-	)
+	}
+	if rate := lim.Rate(0, core.BudgetTCP, true); rate > 0 {
+		args = append(args, "--max-rate", strconv.Itoa(rate))
+	}
+	cmd := exec.CommandContext(ctx, "nmap", append(args, target)...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	fmt.Println("[portscan] Running Nmap service detection...")
+	log.Infof("Running Nmap service detection...")
 	err = cmd.Run()
 	if err != nil {
 		return nil, fmt.Errorf("nmap error: %v - %s", err, stderr.String())
 	}
 
 	// Parse Nmap output
-	return parseNmapOutput(stdout.String(), ports), nil
+	return parseNmapOutput(ctx, log, lim, target, stdout.String(), ports), nil
 }
 
 // parseNmapOutput parses the Nmap output to extract service information
-func parseNmapOutput(output string, ports []int) []PortScanResult {
+func parseNmapOutput(ctx context.Context, log *core.Logger, lim *core.Limiter, target string, output string, ports []int) []PortScanResult {
 	results := make([]PortScanResult, 0, len(ports))
 
 	// Create a map for quick lookup of ports
@@ -229,12 +326,12 @@ func parseNmapOutput(output string, ports []int) []PortScanResult {
 				result.Banner = version
 			} else {
 				// Try to grab banner if no version info
-				banner, _ := grabBanner(scanner.Text(), port)
+				banner, _ := grabBanner(ctx, lim, target, port)
 				result.Banner = banner
 			}
 
 			results = append(results, result)
-			fmt.Printf("[portscan] Service detected: %d/%s - %s\n", port, service, version)
+			log.Debugf("Service detected: %d/%s - %s", port, service, version)
 
 			// Remove from map to track which ports were found
 			delete(portMap, port)
@@ -244,7 +341,7 @@ func parseNmapOutput(output string, ports []int) []PortScanResult {
 	// For any ports not found in nmap output, add them with basic service detection
 	for port := range portMap {
 		service := getServiceName(port)
-		banner, _ := grabBanner(scanner.Text(), port)
+		banner, _ := grabBanner(ctx, lim, target, port)
 		results = append(results, PortScanResult{
 			Port:    port,
 			Service: service,
@@ -256,14 +353,20 @@ func parseNmapOutput(output string, ports []int) []PortScanResult {
 }
 
 // runBasicPortScan is a fallback method if Naabu is not available
-func runBasicPortScan(target string, ports []int) (core.Result, error) {
+func runBasicPortScan(ctx context.Context, log *core.Logger, lim *core.Limiter, target string, ports []int, timeout time.Duration) ([]PortScanResult, error) {
 	var openPorts []PortScanResult
-	timeout := 2 * time.Second
+	dialer := net.Dialer{Timeout: timeout}
 
 	for _, port := range ports {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		address := net.JoinHostPort(target, fmt.Sprintf("%d", port))
-		conn, err := net.DialTimeout("tcp", address, timeout)
+		conn, err := lim.DialContext(ctx, &dialer, "tcp", address)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			continue // closed or filtered
 		}
 
@@ -293,18 +396,10 @@ func runBasicPortScan(target string, ports []int) (core.Result, error) {
 			Service: service,
 		})
 
-		fmt.Printf("[portscan] Found open port: %d (%s)\n", port, service)
+		log.Debugf("Found open port: %d (%s)", port, service)
 	}
 
-	return core.Result{
-		ModuleName: "portscan",
-		Data: map[string]interface{}{
-			"open_ports": openPorts,
-			"count":      len(openPorts),
-			"target":     target,
-			"timestamp":  time.Now().String(),
-		},
-	}, nil
+	return openPorts, nil
 }
 
 // getServiceName returns a common service name for well-known ports
@@ -347,8 +442,9 @@ func getServiceName(port int) string {
 }
 
 // grabBanner attempts to grab service banner from an open port
-func grabBanner(target string, port int) (string, error) {
-	conn, err := net.DialTimeout("tcp", net.JoinHostPort(target, fmt.Sprintf("%d", port)), 5*time.Second)
+func grabBanner(ctx context.Context, lim *core.Limiter, target string, port int) (string, error) {
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := lim.DialContext(ctx, &dialer, "tcp", net.JoinHostPort(target, fmt.Sprintf("%d", port)))
 	if err != nil {
 		return "", err
 	}
@@ -359,7 +455,7 @@ func grabBanner(target string, port int) (string, error) {
 
 	// Send appropriate probe based on port
 	if port == 80 || port == 443 || port == 8080 || port == 8443 {
-		fmt.Fprintf(conn, "HEAD / HTTP/1.1\r\nHost: %s\r\nUser-Agent: Triksha/1.0\r\nConnection: close\r\n\r\n", target)
+		fmt.Fprintf(conn, "HEAD / HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\nConnection: close\r\n\r\n", target, core.DefaultUserAgent)
 	} else if port == 25 || port == 587 {
 		// SMTP
 		fmt.Fprintf(conn, "EHLO triksha.local\r\n")
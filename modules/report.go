@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -11,66 +12,113 @@ type ReportModule struct{}
 
 func (m *ReportModule) Name() string { return "report" }
 
-func (m *ReportModule) Run(target string, ctx *core.Context) (core.Result, error) {
-	fmt.Printf("[report] Generating summary report for: %s\n", target)
+func (m *ReportModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: "Summarizes the scan's findings into a final report",
+		Category:    core.CategoryReport,
+		Noise:       core.NoiseNone,
+		Order:       90,
+	}
+}
+
+func (m *ReportModule) Consumes() []string {
+	return []string{
+		core.OpenPortsKey.Name(),
+		core.TechDetectedKey.Name(),
+		core.VulnsKey.Name(),
+		core.DirsFoundKey.Name(),
+	}
+}
+
+func (m *ReportModule) Produces() []string { return nil }
+
+func (m *ReportModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	rctx.Logger(m.Name()).Infof("Generating summary report for: %s", target)
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("# Triksha Recon Report for %s\n\n", target))
 
-	// 1. Open Ports
-	if p, ok := ctx.Store["portscan.open_ports"]; ok {
+	assets := rctx.Assets
+
+	// 1. Hosts and what they resolve to
+	if hosts := assets.Nodes(core.NodeDomain, core.NodeSubdomain); len(hosts) > 0 {
+		sb.WriteString("## Hosts\n")
+		for _, h := range hosts {
+			line := "- " + h.Value
+			if ips := nodeValues(assets.Out(h.ID, core.EdgeResolvesTo)); len(ips) > 0 {
+				line += " -> " + strings.Join(ips, ", ")
+			}
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	// 2. Open Ports
+	if services := assets.Nodes(core.NodeService); len(services) > 0 {
 		sb.WriteString("## Open Ports\n")
-		if openPorts, ok := p.([]interface{}); ok && len(openPorts) > 0 {
-			for _, portInfo := range openPorts {
-				if m, ok := portInfo.(map[string]interface{}); ok {
-					sb.WriteString(fmt.Sprintf("- %v (banner: %s)\n", m["port"], m["banner"]))
-				}
+		for _, svc := range services {
+			line := "- " + svc.Value
+			if name := svc.Attrs["service"]; name != "" {
+				line += " " + name
+			}
+			if banner := svc.Attrs["banner"]; banner != "" {
+				line += fmt.Sprintf(" (banner: %s)", banner)
 			}
-		} else {
-			sb.WriteString("No open ports found.\n")
+			sb.WriteString(line + "\n")
 		}
 		sb.WriteString("\n")
 	}
 
-	// 2. Web Technologies
-	if t, ok := ctx.Store["webenum.tech_detected"]; ok {
+	// 3. Web Technologies, with the URLs running them
+	if techs := assets.Nodes(core.NodeTechnology); len(techs) > 0 {
 		sb.WriteString("## Web Technologies Detected\n")
-		if techs, ok := t.([]string); ok && len(techs) > 0 {
-			sb.WriteString("- " + strings.Join(techs, "\n- ") + "\n\n")
-		} else {
-			sb.WriteString("No significant web technologies found.\n\n")
+		for _, tech := range techs {
+			sb.WriteString(fmt.Sprintf("- %s (%s)\n", tech.Value, strings.Join(nodeValues(assets.In(tech.ID, core.EdgeRuns)), ", ")))
 		}
+		sb.WriteString("\n")
 	}
 
-	// 3. Vulnerabilities
-	if v, ok := ctx.Store["vulnscan.vulns"]; ok {
+	// 4. Vulnerabilities, from every module's findings
+	if findings := assets.Nodes(core.NodeFinding); len(findings) > 0 {
 		sb.WriteString("## Vulnerabilities & Findings\n")
-		if vulns, ok := v.([]string); ok && len(vulns) > 0 {
-			for _, vuln := range vulns {
-				sb.WriteString(fmt.Sprintf("- %s\n", vuln))
+		sorted := make([]core.Finding, 0, len(findings))
+		for _, n := range findings {
+			f := core.Finding{ID: n.Value, Title: n.Attrs["title"], Severity: core.Severity(n.Attrs["severity"])}
+			if affected := nodeValues(assets.Out(n.ID, core.EdgeAffects)); len(affected) > 0 {
+				f.Asset.Host = strings.Join(affected, ", ")
 			}
-		} else {
-			sb.WriteString("No vulnerabilities detected by automated checks.\n")
+			sorted = append(sorted, f)
+		}
+		core.SortFindings(sorted)
+		for _, f := range sorted {
+			sb.WriteString(fmt.Sprintf("- [%s] %s (%s)\n", strings.ToUpper(string(f.Severity)), f.Title, f.Asset))
 		}
 		sb.WriteString("\n")
+	} else if rctx.Store.Has(core.VulnsKey.Name()) {
+		sb.WriteString("## Vulnerabilities & Findings\n")
+		sb.WriteString("No vulnerabilities detected by automated checks.\n\n")
 	}
 
-	// 4. Sensitive Endpoints
-	if d, ok := ctx.Store["webenum.dirs_found"]; ok {
-		sb.WriteString("## Sensitive Endpoints Discovered\n")
-		if dirs, ok := d.([]interface{}); ok && len(dirs) > 0 {
-			for _, dir := range dirs {
-				if dr, ok := dir.(map[string]interface{}); ok {
-					path := dr["path"]
-					code := dr["status_code"]
-					sb.WriteString(fmt.Sprintf("- %v (HTTP %v)\n", path, code))
-				}
+	// 5. Web endpoints
+	if urls := assets.Nodes(core.NodeURL); len(urls) > 0 {
+		sb.WriteString("## Web Endpoints Discovered\n")
+		for _, u := range urls {
+			line := "- " + u.Value
+			if status := u.Attrs["status_code"]; status != "" {
+				line += fmt.Sprintf(" (HTTP %s)", status)
 			}
+			sb.WriteString(line + "\n")
 		}
 		sb.WriteString("\n")
 	}
 
-	// 5. Recommendations
+	// 6. Out-of-scope assets (recorded, never touched)
+	if outOfScope, ok, _ := core.Get(rctx.Store, core.OutOfScopeKey); ok && len(outOfScope) > 0 {
+		sb.WriteString("## Out of Scope (not tested)\n")
+		sb.WriteString("- " + strings.Join(outOfScope, "\n- ") + "\n\n")
+	}
+
+	// 7. Recommendations
 	sb.WriteString("## Recommendations\n")
 	sb.WriteString("- Review all findings and consider manual validation.\n")
 	sb.WriteString("- Run specialized vulnerability scanners for detected techs (e.g., WPScan for WordPress).\n")
@@ -85,4 +133,13 @@ func (m *ReportModule) Run(target string, ctx *core.Context) (core.Result, error
 	}, nil
 }
 
+// nodeValues lists the values of nodes.
+func nodeValues(nodes []core.Node) []string {
+	values := make([]string, len(nodes))
+	for i, n := range nodes {
+		values[i] = n.Value
+	}
+	return values
+}
+
 var Report core.Module = &ReportModule{}
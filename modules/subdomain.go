@@ -3,13 +3,16 @@ package modules
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -44,6 +47,7 @@ type HttpxRawResult struct {
 type SubdomainResult struct {
 	Source     string   `json:"source"`
 	Subdomains []string `json:"subdomains"`
+	Error      string   `json:"error,omitempty"`
 }
 
 // SubdomainModule is the module struct.
@@ -51,30 +55,74 @@ type SubdomainModule struct{}
 
 func (m *SubdomainModule) Name() string { return "subdomain" }
 
-func (m *SubdomainModule) Run(target string, ctx *core.Context) (core.Result, error) {
-	fmt.Printf("[subdomain] Enumerating subdomains for: %s\n", target)
+func (m *SubdomainModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: "Enumerates subdomains from public sources and DNS brute-force, then probes them",
+		Category:    core.CategoryActive,
+		Noise:       core.NoiseMedium,
+		Binaries:    []string{"subfinder", "httpx"},
+		Order:       20,
+		MaxRuns:     2, // benefits from a second run with other sources
+	}
+}
 
-	var results []SubdomainResult
+func (m *SubdomainModule) Consumes() []string { return nil }
 
-	// 1. crt.sh (certificate transparency logs, reused from passive)
-	crtshSubs, _ := FetchCRTshEntries(target)
-	results = append(results, SubdomainResult{Source: "crtsh", Subdomains: crtshSubs})
+func (m *SubdomainModule) Produces() []string { return []string{core.SubdomainsKey.Name()} }
 
-	// 2. DNSDumpster
-	dnsdumpsterSubs, _ := fetchDNSDumpster(target)
-	results = append(results, SubdomainResult{Source: "dnsdumpster", Subdomains: dnsdumpsterSubs})
+// subdomainSources lists the enumeration sources in the order they run.
+var subdomainSources = []string{"crtsh", "hackertarget", "bruteforce", "subfinder"}
 
-	// 3. hackertarget.com
-	hackertargetSubs, _ := fetchHackerTarget(target)
-	results = append(results, SubdomainResult{Source: "hackertarget", Subdomains: hackertargetSubs})
+func (m *SubdomainModule) Params() []core.ParamSpec {
+	return []core.ParamSpec{
+		{Name: "sources", Type: core.ParamStringList, Default: subdomainSources, Description: "Enumeration sources to use"},
+		{Name: "wordlist", Type: core.ParamPath, Default: "subdomains.txt", Description: "Wordlist for DNS brute-force, in the wordlist directory"},
+		{Name: "probe", Type: core.ParamBool, Default: true, Description: "Probe discovered subdomains with httpx"},
+	}
+}
 
-	// 4. Wordlist brute-force
-	bruteSubs, _ := bruteForceSubdomains(target, "wordlists/subdomains.txt")
-	results = append(results, SubdomainResult{Source: "bruteforce", Subdomains: bruteSubs})
+func (m *SubdomainModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	// URL and host:port targets are reduced to their host
+	target = hostOf(target)
+	log := rctx.Logger(m.Name())
+	log.Infof("Enumerating subdomains for: %s", target)
 
-	// 5. Subfinder
-	subfinderSubs, _ := runSubfinder(target)
-	results = append(results, SubdomainResult{Source: "subfinder", Subdomains: subfinderSubs})
+	var results []SubdomainResult
+	for _, source := range params.StringList("sources") {
+		var (
+			subs []string
+			err  error
+		)
+		switch source {
+		case "crtsh":
+			// certificate transparency logs, shared with passive via the cache
+			subs, err = cachedCRTsh(ctx, rctx, m.Name(), target)
+		case "hackertarget":
+			subs, err = core.Cached(rctx.Cache, source, target, func() ([]string, error) {
+				return core.WithRetry(ctx, rctx, m.Name(), source, func() ([]string, error) {
+					return fetchHackerTarget(ctx, rctx.SourceClient(10*time.Second), target, rctx.APIKey("hackertarget"))
+				})
+			})
+		case "bruteforce":
+			subs, err = bruteForceSubdomains(ctx, rctx, m.Name(), target, rctx.Wordlist(params.String("wordlist")))
+		case "subfinder":
+			subs, err = core.Cached(rctx.Cache, source, target, func() ([]string, error) {
+				return runSubfinder(ctx, rctx.Limiter, target)
+			})
+		default:
+			return core.Result{}, fmt.Errorf("unknown subdomain source %q (available: %s)", source, strings.Join(subdomainSources, ", "))
+		}
+		sr := SubdomainResult{Source: source, Subdomains: subs}
+		if err != nil && ctx.Err() == nil {
+			log.Warnf("Source %s failed: %v", source, err)
+			sr.Error = err.Error()
+		}
+		results = append(results, sr)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return core.Result{}, err
+	}
 
 	// Merge, deduplicate, and filter out wildcard subdomains (*.domain.com)
 	all := map[string]bool{}
@@ -92,14 +140,69 @@ func (m *SubdomainModule) Run(target string, ctx *core.Context) (core.Result, er
 		}
 	}
 
+	if unique == nil {
+		unique = []string{}
+	}
+	if err := core.Set(rctx.Store, core.SubdomainsKey, unique); err != nil {
+		return core.Result{}, err
+	}
+
+	// Every source that reported a subdomain is credited in the graph
+	assets := rctx.AssetWriter(m.Name())
+	for _, r := range results {
+		w := assets.From(r.Source)
+		for _, s := range r.Subdomains {
+			if s = strings.TrimSpace(s); s != "" && !strings.HasPrefix(s, "*.") {
+				w.Host(s, nil)
+			}
+		}
+	}
+
+	// Out-of-scope subdomains stay in the results but are never probed
+	inScope := []string{}
+	outOfScope := []string{}
+	for _, s := range unique {
+		if rctx.Scope.CheckHost(s) != nil {
+			core.MarkOutOfScope(rctx, s)
+			assets.Host(s, map[string]string{"out_of_scope": "true"})
+			outOfScope = append(outOfScope, s)
+			continue
+		}
+		inScope = append(inScope, s)
+	}
+	if len(outOfScope) > 0 {
+		log.Infof("%d subdomain(s) are out of scope and will not be probed", len(outOfScope))
+	}
+
 	screenshotsDir := fmt.Sprintf("screenshots/%s", target)
 
 	// Probe subdomains with httpx
-	httpxResults, err := probeWithHttpx(unique, screenshotsDir)
-	if err != nil {
-		fmt.Printf("[subdomain] Error probing with httpx: %v\n", err)
-	} else {
-		fmt.Printf("[subdomain] Successfully probed %d subdomains with httpx\n", len(httpxResults))
+	var httpxResults []HttpxRawResult
+	if params.Bool("probe") {
+		var err error
+		httpxResults, err = probeWithHttpx(ctx, log, rctx.Limiter, rctx.HTTP, inScope, screenshotsDir)
+		if err != nil {
+			log.Warnf("Error probing with httpx: %v", err)
+		} else {
+			log.Infof("Successfully probed %d subdomains with httpx", len(httpxResults))
+		}
+	}
+	probed := assets.From("httpx")
+	for _, r := range httpxResults {
+		if r.Failed || r.URL == "" {
+			continue
+		}
+		if r.Input != "" {
+			probed.Resolves(r.Input, r.A)
+		}
+		id := probed.URL(r.URL, map[string]string{
+			"status_code":  strconv.Itoa(r.StatusCode),
+			"webserver":    r.Webserver,
+			"content_type": r.ContentType,
+		})
+		for _, tech := range r.Tech {
+			probed.Technology(id, tech, nil)
+		}
 	}
 
 	return core.Result{
@@ -108,25 +211,46 @@ func (m *SubdomainModule) Run(target string, ctx *core.Context) (core.Result, er
 			"sources":      results,      // Raw subdomain sources
 			"all":          unique,       // Deduped + filtered subdomains
 			"count":        len(unique),  // Count of deduped
+			"out_of_scope": outOfScope,   // Recorded but never touched
 			"httpxResults": httpxResults, // <-- INCLUDE THE HTTPX RESULTS HERE!
 		},
 	}, nil
 }
 
 // ----------- Subdomain Sources -----------
-// fetchDNSDumpster scrapes DNSDumpster for subdomains (basic)
-func fetchDNSDumpster(domain string) ([]string, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	url := "https://api.hackertarget.com/hostsearch/?q=" + domain
-	resp, err := client.Get(url)
+// hackerTargetURL is hackertarget's host search endpoint.
+const hackerTargetURL = "https://api.hackertarget.com/hostsearch/"
+
+// fetchHackerTarget fetches subdomains from hackertarget.com (rate limited
+// unless an API key is configured)
+func fetchHackerTarget(ctx context.Context, client *http.Client, domain, apiKey string) ([]string, error) {
+	query := url.Values{"q": {domain}}
+	if apiKey != "" {
+		query.Set("apikey", apiKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", hackerTargetURL+"?"+query.Encode(), nil)
 	if err != nil {
-		return nil, err
+		return nil, withoutURL("hackertarget", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, withoutURL("hackertarget", err)
 	}
 	defer resp.Body.Close()
+	// Errors must not end up cached as an empty answer
+	if err := core.CheckStatus("hackertarget", resp); err != nil {
+		return nil, err
+	}
 	var subdomains []string
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
+		if strings.HasPrefix(line, "API count exceeded") {
+			return nil, &core.StatusError{Source: "hackertarget", StatusCode: http.StatusTooManyRequests, Status: line}
+		}
+		if strings.HasPrefix(line, "error") {
+			return nil, fmt.Errorf("hackertarget: %s", line)
+		}
 		parts := strings.Split(line, ",")
 		if len(parts) > 0 && strings.HasSuffix(parts[0], domain) {
 			subdomains = append(subdomains, parts[0])
@@ -135,29 +259,19 @@ func fetchDNSDumpster(domain string) ([]string, error) {
 	return subdomains, nil
 }
 
-// fetchHackerTarget fetches subdomains from hackertarget.com (rate limited!)
-func fetchHackerTarget(domain string) ([]string, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	url := "https://api.hackertarget.com/hostsearch/?q=" + domain
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, err
+// withoutURL drops the request URL, which may carry an API key, from a
+// request error. The cause stays wrapped so retries can classify it.
+func withoutURL(source string, err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
 	}
-	defer resp.Body.Close()
-	var subdomains []string
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, ",")
-		if len(parts) > 0 && strings.HasSuffix(parts[0], domain) {
-			subdomains = append(subdomains, parts[0])
-		}
-	}
-	return subdomains, nil
+	return fmt.Errorf("%s: %w", source, err)
 }
 
 // bruteForceSubdomains does a wordlist-based brute-force
-func bruteForceSubdomains(domain, wordlistPath string) ([]string, error) {
+func bruteForceSubdomains(ctx context.Context, rctx *core.Context, module, domain, wordlistPath string) ([]string, error) {
+	resolver := rctx.Limiter.Resolver()
 	file, err := os.Open(wordlistPath)
 	if err != nil {
 		return nil, nil // skip if wordlist not found
@@ -166,12 +280,17 @@ func bruteForceSubdomains(domain, wordlistPath string) ([]string, error) {
 	var found []string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return found, err
+		}
 		prefix := scanner.Text()
 		if prefix == "" || strings.HasPrefix(prefix, "#") {
 			continue
 		}
 		fqdn := prefix + "." + domain
-		ips, err := net.LookupHost(fqdn)
+		ips, err := core.WithRetry(ctx, rctx, module, "dns", func() ([]string, error) {
+			return resolver.LookupHost(ctx, fqdn)
+		})
 		if err == nil && len(ips) > 0 {
 			found = append(found, fqdn)
 		}
@@ -180,7 +299,7 @@ func bruteForceSubdomains(domain, wordlistPath string) ([]string, error) {
 }
 
 // runSubfinder uses subfinder tool to discover subdomains
-func runSubfinder(domain string) ([]string, error) {
+func runSubfinder(ctx context.Context, lim *core.Limiter, domain string) ([]string, error) {
 	// Check if subfinder is installed
 	_, err := exec.LookPath("subfinder")
 	if err != nil {
@@ -188,11 +307,11 @@ func runSubfinder(domain string) ([]string, error) {
 	}
 
 	// Run subfinder command
-	cmd := exec.Command(
-		"subfinder",
-		"-d", domain,
-		"-silent",
-	)
+	args := []string{"-d", domain, "-silent"}
+	if rate := lim.Rate(0, core.BudgetHTTP, false); rate > 0 {
+		args = append(args, "-rl", strconv.Itoa(rate))
+	}
+	cmd := exec.CommandContext(ctx, "subfinder", args...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -217,7 +336,7 @@ func runSubfinder(domain string) ([]string, error) {
 }
 
 // probeWithHttpx uses httpx to probe subdomains and take screenshots
-func probeWithHttpx(subdomains []string, screenshotsDir string) ([]HttpxRawResult, error) {
+func probeWithHttpx(ctx context.Context, log *core.Logger, lim *core.Limiter, hc *core.HTTPClientFactory, subdomains []string, screenshotsDir string) ([]HttpxRawResult, error) {
 	// Ensure httpx is installed
 	_, err := exec.LookPath("httpx")
 	if err != nil {
@@ -247,8 +366,7 @@ func probeWithHttpx(subdomains []string, screenshotsDir string) ([]HttpxRawResul
 	tmpfile.Close()
 
 	// Build httpx command
-	cmd := exec.Command(
-		"httpx",
+	args := []string{
 		"-l", tmpfile.Name(),
 		"-silent",
 		"-title",
@@ -257,7 +375,19 @@ func probeWithHttpx(subdomains []string, screenshotsDir string) ([]HttpxRawResul
 		"-status-code",
 		"-json",
 		"-timeout", "5",
-	)
+		"-rl", strconv.Itoa(lim.Rate(150, core.BudgetHTTP, false)),
+		"-threads", strconv.Itoa(lim.Concurrency(50)),
+		"-H", "User-Agent: " + hc.UserAgent(),
+	}
+	// Follow the shared HTTP settings so httpx goes through the same proxy
+	httpCfg := hc.Config()
+	if httpCfg.Proxy != "" {
+		args = append(args, "-http-proxy", httpCfg.Proxy)
+	}
+	for k, v := range httpCfg.Headers {
+		args = append(args, "-H", k+": "+v)
+	}
+	cmd := exec.CommandContext(ctx, "httpx", args...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -265,7 +395,7 @@ func probeWithHttpx(subdomains []string, screenshotsDir string) ([]HttpxRawResul
 
 	err = cmd.Run()
 	if err != nil {
-		fmt.Printf("httpx stderr: %s\n", stderr.String())
+		log.Debugf("httpx stderr: %s", stderr.String())
 		return nil, fmt.Errorf("httpx error: %v", err)
 	}
 
@@ -279,7 +409,7 @@ func probeWithHttpx(subdomains []string, screenshotsDir string) ([]HttpxRawResul
 		}
 		var result HttpxRawResult
 		if err := json.Unmarshal([]byte(line), &result); err != nil {
-			fmt.Printf("[subdomain] Error parsing httpx result: %v\n", err)
+			log.Debugf("Error parsing httpx result: %v", err)
 			continue
 		}
 
@@ -297,7 +427,7 @@ func probeWithHttpx(subdomains []string, screenshotsDir string) ([]HttpxRawResul
 		results = append(results, result)
 	}
 	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading httpx output: %v\n", err)
+		log.Warnf("Error reading httpx output: %v", err)
 	}
 
 	return results, nil
@@ -0,0 +1,60 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+const testAPIKey = "s3cr3t&key=1"
+
+func TestFetchHackerTargetErrorHidesKey(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, syscall.ECONNRESET
+	})}
+	_, err := fetchHackerTarget(context.Background(), client, "example.com", testAPIKey)
+	if err == nil {
+		t.Fatal("fetchHackerTarget succeeded over a failing transport")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") || strings.Contains(err.Error(), "apikey") {
+		t.Errorf("error leaks the API key: %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "hackertarget: ") {
+		t.Errorf("error %q does not name the source", err)
+	}
+	if !errors.Is(err, syscall.ECONNRESET) || core.ClassifyError(err) != core.RetryNetwork {
+		t.Errorf("error %v lost its cause, so it would not be retried", err)
+	}
+}
+
+func TestFetchHackerTargetQuery(t *testing.T) {
+	var query map[string][]string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		query = req.URL.Query()
+		body := "www.example.com,192.0.2.1\nother.test,192.0.2.2\n"
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})}
+	subs, err := fetchHackerTarget(context.Background(), client, "example.com", testAPIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0] != "www.example.com" {
+		t.Errorf("subdomains = %v, want [www.example.com]", subs)
+	}
+	if got := query["apikey"]; len(got) != 1 || got[0] != testAPIKey {
+		t.Errorf("apikey = %v, want the key escaped as one value", got)
+	}
+	if _, ok := query["key"]; ok {
+		t.Error("the key's '&' split it into another query parameter")
+	}
+}
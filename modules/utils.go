@@ -1,28 +1,37 @@
 package modules
 
 import (
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
 )
 
 // FetchCRTshEntries scrapes crt.sh for subdomains (shared)
-func FetchCRTshEntries(domain string) ([]string, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
+func FetchCRTshEntries(ctx context.Context, client *http.Client, domain string) ([]string, error) {
 	url := "https://crt.sh/?q=%25." + domain + "&output=json"
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "TrikshaReconBot/1.0")
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
+	if err := core.CheckStatus("crt.sh", resp); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 	var entries []map[string]interface{}
 	if err := json.Unmarshal(body, &entries); err != nil {
 		return nil, err
@@ -45,6 +54,57 @@ func FetchCRTshEntries(domain string) ([]string, error) {
 	return subdomains, nil
 }
 
+// cachedCRTsh is FetchCRTshEntries through the scan's lookup cache, so
+// passive and subdomain share one crt.sh query per domain.
+func cachedCRTsh(ctx context.Context, rctx *core.Context, module, domain string) ([]string, error) {
+	return core.Cached(rctx.Cache, "crtsh", domain, func() ([]string, error) {
+		return core.WithRetry(ctx, rctx, module, "crtsh", func() ([]string, error) {
+			return FetchCRTshEntries(ctx, rctx.SourceClient(10*time.Second), domain)
+		})
+	})
+}
+
+// hostOf extracts the bare host from a domain, host:port or URL target.
+func hostOf(target string) string {
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+			return u.Hostname()
+		}
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return target
+}
+
+// ctxDialer dials through the limiter with ctx and closes the connection
+// once ctx is done, unblocking libraries that only accept a plain Dial
+// (e.g. whois).
+type ctxDialer struct {
+	ctx     context.Context
+	limiter *core.Limiter
+}
+
+func (d *ctxDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := d.limiter.DialContext(d.ctx, nil, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(d.ctx, func() { conn.Close() })
+	return &ctxConn{Conn: conn, stop: stop}, nil
+}
+
+// ctxConn releases the cancellation hook when closed normally.
+type ctxConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (c *ctxConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
 // FetchCRTshEntries fetches subdomains from crt.sh
 /* func FetchCRTshEntries(domain string) ([]string, error) {
 	client := &http.Client{Timeout: 15 * time.Second}
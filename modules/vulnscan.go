@@ -1,8 +1,10 @@
 package modules
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/r4j3sh-com/triksha/core"
@@ -10,84 +12,176 @@ import (
 
 // VulnScanResult holds detected vulnerabilities or findings
 type VulnScanResult struct {
-	Vulns []string `json:"vulns"`
+	Vulns []core.Finding `json:"vulns"`
 }
 
 type VulnscanModule struct{}
 
 func (m *VulnscanModule) Name() string { return "vulnscan" }
 
-func (m *VulnscanModule) Run(target string, ctx *core.Context) (core.Result, error) {
-	fmt.Printf("[vulnscan] Running vuln scan for: %s\n", target)
+func (m *VulnscanModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: "Matches discovered services and technologies against known vulnerabilities",
+		Category:    core.CategoryPassive,
+		Noise:       core.NoiseNone,
+		Order:       50,
+	}
+}
+
+func (m *VulnscanModule) Consumes() []string {
+	return []string{core.OpenPortsKey.Name(), core.TechDetectedKey.Name()}
+}
+
+func (m *VulnscanModule) Produces() []string { return []string{core.VulnsKey.Name()} }
+
+func (m *VulnscanModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	log := rctx.Logger(m.Name())
+	log.Infof("Running vuln scan for: %s", target)
 
 	// 1. Gather previous results from context.Store
-	var webTechs []string
-	if t, ok := ctx.Store["webenum.tech_detected"]; ok {
-		webTechs, _ = t.([]string)
+	webTechs, _, err := core.Get(rctx.Store, core.TechDetectedKey)
+	if err != nil {
+		return core.Result{}, err
 	}
-	var banners []string
-	if p, ok := ctx.Store["portscan.open_ports"]; ok {
-		if openPorts, ok := p.([]interface{}); ok {
-			for _, port := range openPorts {
-				if portInfo, ok := port.(map[string]interface{}); ok {
-					if b, ok := portInfo["banner"].(string); ok && b != "" {
-						banners = append(banners, b)
-					}
-				}
-			}
-		}
+	openPorts, _, err := core.Get(rctx.Store, core.OpenPortsKey)
+	if err != nil {
+		return core.Result{}, err
 	}
 
-	var vulns []string
+	result := core.Result{ModuleName: m.Name()}
+	host := hostOf(target)
+	site := core.Asset{Host: host, URL: ensureHTTP(target)}
 
 	// 2. Check for outdated server/software
 	for _, tech := range webTechs {
 		if strings.Contains(strings.ToLower(tech), "wordpress") {
-			vulns = append(vulns, "Detected WordPress: recommend running wpscan for specific plugin/theme vulns")
+			result.AddFinding(core.Finding{
+				Title:       "WordPress detected",
+				Severity:    core.SeverityInfo,
+				Confidence:  core.ConfidenceFirm,
+				Asset:       site,
+				Evidence:    tech,
+				Remediation: "Run wpscan to enumerate vulnerable plugins and themes.",
+			})
 		}
 		if strings.Contains(strings.ToLower(tech), "drupal") {
-			vulns = append(vulns, "Detected Drupal: check for CVE-2018-7600 (Drupalgeddon 2)")
+			result.AddFinding(core.Finding{
+				Title:       "Drupal detected: possible Drupalgeddon 2",
+				Severity:    core.SeverityMedium,
+				Confidence:  core.ConfidenceTentative,
+				Asset:       site,
+				Evidence:    tech,
+				References:  []string{"CVE-2018-7600"},
+				Remediation: "Confirm the Drupal core version and upgrade to 7.58 / 8.5.1 or later.",
+			})
 		}
-		if m := regexp.MustCompile(`Apache/([0-9.]+)`).FindStringSubmatch(tech); len(m) > 1 {
-			version := m[1]
-			if version < "2.4.49" {
-				vulns = append(vulns, fmt.Sprintf("Apache version %s detected: CVE-2021-41773 path traversal/RCE!", version))
+		if match := regexp.MustCompile(`Apache/([0-9.]+)`).FindStringSubmatch(tech); len(match) > 1 {
+			version := match[1]
+			if versionLess(version, "2.4.49") {
+				result.AddFinding(core.Finding{
+					Title:       fmt.Sprintf("Outdated Apache httpd %s", version),
+					Severity:    core.SeverityHigh,
+					Confidence:  core.ConfidenceFirm,
+					Asset:       site,
+					Evidence:    tech,
+					References:  []string{"CVE-2021-41773", "CWE-22"},
+					Remediation: "Upgrade Apache httpd to the latest 2.4.x release.",
+				})
 			}
 		}
-		if m := regexp.MustCompile(`nginx/([0-9.]+)`).FindStringSubmatch(tech); len(m) > 1 {
-			version := m[1]
-			if version < "1.21.6" {
-				vulns = append(vulns, fmt.Sprintf("Nginx version %s detected: possible outdated, check for CVEs.", version))
+		if match := regexp.MustCompile(`nginx/([0-9.]+)`).FindStringSubmatch(tech); len(match) > 1 {
+			version := match[1]
+			if versionLess(version, "1.21.6") {
+				result.AddFinding(core.Finding{
+					Title:       fmt.Sprintf("Outdated nginx %s", version),
+					Severity:    core.SeverityLow,
+					Confidence:  core.ConfidenceFirm,
+					Asset:       site,
+					Evidence:    tech,
+					Remediation: "Upgrade nginx and review its changelog for security fixes.",
+				})
 			}
 		}
-		if m := regexp.MustCompile(`php/([0-9.]+)`).FindStringSubmatch(tech); len(m) > 1 {
-			version := m[1]
-			if version < "7.4" {
-				vulns = append(vulns, fmt.Sprintf("PHP version %s detected: outdated, check for known RCE/vulns.", version))
+		if match := regexp.MustCompile(`php/([0-9.]+)`).FindStringSubmatch(tech); len(match) > 1 {
+			version := match[1]
+			if versionLess(version, "7.4") {
+				result.AddFinding(core.Finding{
+					Title:       fmt.Sprintf("End-of-life PHP %s", version),
+					Severity:    core.SeverityMedium,
+					Confidence:  core.ConfidenceFirm,
+					Asset:       site,
+					Evidence:    tech,
+					Remediation: "Upgrade to a supported PHP release.",
+				})
 			}
 		}
 	}
 
 	// 3. Check HTTP banners for leaks/misconfigs
-	for _, banner := range banners {
-		if strings.Contains(banner, "Allow:") && strings.Contains(banner, "TRACE") {
-			vulns = append(vulns, "HTTP TRACE method allowed: possible Cross Site Tracing (XST) vuln")
+	for _, port := range openPorts {
+		if port.Banner == "" {
+			continue
 		}
-		if strings.Contains(strings.ToLower(banner), "public") && strings.Contains(strings.ToLower(banner), "index of") {
-			vulns = append(vulns, "Directory listing enabled: 'Index of /' found in HTTP banner")
+		asset := core.Asset{Host: host, Port: port.Port}
+		if strings.Contains(port.Banner, "Allow:") && strings.Contains(port.Banner, "TRACE") {
+			result.AddFinding(core.Finding{
+				Title:       "HTTP TRACE method allowed",
+				Severity:    core.SeverityLow,
+				Confidence:  core.ConfidenceFirm,
+				Asset:       asset,
+				Evidence:    port.Banner,
+				References:  []string{"CWE-693"},
+				Remediation: "Disable the TRACE method to prevent Cross Site Tracing (XST).",
+			})
+		}
+		if strings.Contains(strings.ToLower(port.Banner), "public") && strings.Contains(strings.ToLower(port.Banner), "index of") {
+			result.AddFinding(core.Finding{
+				Title:       "Directory listing enabled",
+				Severity:    core.SeverityMedium,
+				Confidence:  core.ConfidenceFirm,
+				Asset:       asset,
+				Evidence:    port.Banner,
+				References:  []string{"CWE-548"},
+				Remediation: "Disable automatic directory indexes on the web server.",
+			})
 		}
 	}
 
-	if len(vulns) == 0 {
-		vulns = append(vulns, "No obvious vulnerabilities found with basic fingerprinting. Consider deeper/manual assessment.")
+	if len(result.Findings) == 0 {
+		log.Infof("No obvious vulnerabilities found with basic fingerprinting. Consider deeper/manual assessment.")
 	}
 
-	return core.Result{
-		ModuleName: m.Name(),
-		Data: map[string]interface{}{
-			"vulns": vulns,
-		},
-	}, nil
+	vulns := result.Findings
+	if vulns == nil {
+		vulns = []core.Finding{}
+	}
+	if err := core.Set(rctx.Store, core.VulnsKey, vulns); err != nil {
+		return core.Result{}, err
+	}
+
+	result.Data = map[string]interface{}{
+		"vulns": vulns,
+		"count": len(vulns),
+	}
+	return result, nil
+}
+
+// versionLess compares dotted numeric versions (e.g. "2.4.9" < "2.4.49").
+func versionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
 }
 
 var Vulnscan core.Module = &VulnscanModule{}
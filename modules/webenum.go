@@ -2,12 +2,19 @@ package modules
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,12 +22,7 @@ import (
 	"github.com/r4j3sh-com/triksha/core"
 )
 
-type DirResult struct {
-	Path       string `json:"path"`
-	StatusCode int    `json:"status_code"`
-	Size       int64  `json:"size"`
-	Title      string `json:"title,omitempty"`
-}
+type DirResult = core.DirEntry
 
 type WebenumResult struct {
 	TechDetected []string    `json:"tech_detected"`
@@ -31,20 +33,84 @@ type WebenumModule struct{}
 
 func (m *WebenumModule) Name() string { return "webenum" }
 
-func (m *WebenumModule) Run(target string, ctx *core.Context) (core.Result, error) {
-	fmt.Printf("[webenum] Enumerating web for: %s\n", target)
+func (m *WebenumModule) Info() core.ModuleInfo {
+	return core.ModuleInfo{
+		Description: "Fingerprints web technologies and brute-forces directories",
+		Category:    core.CategoryActive,
+		Noise:       core.NoiseHigh,
+		Order:       40,
+		MaxRuns:     2, // may be rerun with a different wordlist
+	}
+}
+
+func (m *WebenumModule) Consumes() []string { return nil }
+
+func (m *WebenumModule) Produces() []string {
+	return []string{core.TechDetectedKey.Name(), core.DirsFoundKey.Name()}
+}
+
+func (m *WebenumModule) Params() []core.ParamSpec {
+	return []core.ParamSpec{
+		{Name: "wordlist", Type: core.ParamPath, Default: "dirs.txt", Description: "Wordlist for directory brute-force, in the wordlist directory"},
+		{Name: "timeout", Type: core.ParamDuration, Default: "10s", Description: "Per-request HTTP timeout"},
+	}
+}
+
+func (m *WebenumModule) Run(ctx context.Context, target string, params core.Params, rctx *core.Context) (core.Result, error) {
+	log := rctx.Logger(m.Name())
+	log.Infof("Enumerating web for: %s", target)
 
 	baseURL := ensureHTTP(target)
-	client := &http.Client{Timeout: 10 * time.Second} // Increased timeout for more reliable results
+	if err := rctx.Enforce(m.Name(), baseURL); err != nil {
+		return core.Result{}, err
+	}
+	// Every request, including redirects and path probes, is scope-checked
+	client := rctx.HTTPClient(m.Name(), params.Duration("timeout"))
 
 	// 1. Tech detection via headers/body and Wappalyzer
-	techs, _ := detectWebTech(client, baseURL)
+	techs, page, _ := detectWebTech(ctx, client, baseURL)
 
 	// 2. Directory brute-force (if wordlist present)
 	var dirs []DirResult
-	wordlist := "wordlists/dirs.txt"
+	wordlist := rctx.Wordlist(params.String("wordlist"))
 	if _, err := os.Stat(wordlist); err == nil {
-		dirs, _ = bruteForceDirs(client, baseURL, wordlist)
+		dirs, _ = bruteForceDirs(ctx, client, baseURL, wordlist)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return core.Result{}, err
+	}
+
+	// Share findings with downstream modules (vulnscan, report)
+	if techs == nil {
+		techs = []string{}
+	}
+	if dirs == nil {
+		dirs = []DirResult{}
+	}
+	if err := core.Set(rctx.Store, core.TechDetectedKey, techs); err != nil {
+		return core.Result{}, err
+	}
+	if err := core.Set(rctx.Store, core.DirsFoundKey, dirs); err != nil {
+		return core.Result{}, err
+	}
+	assets := rctx.AssetWriter(m.Name())
+	if page.URL != "" {
+		landing := assets.URL(page.URL, map[string]string{"status_code": strconv.Itoa(page.StatusCode)})
+		for _, tech := range techs {
+			if name, ok := graphTech(tech); ok {
+				assets.Technology(landing, name, nil)
+			}
+		}
+		if u, err := url.Parse(page.URL); err == nil {
+			recordCertificate(assets, u.Hostname(), page.TLS)
+		}
+	}
+	for _, dir := range dirs {
+		assets.URL(strings.TrimRight(baseURL, "/")+dir.Path, map[string]string{
+			"status_code": strconv.Itoa(dir.StatusCode),
+			"title":       dir.Title,
+		})
 	}
 
 	// Group technologies by category for better organization
@@ -119,8 +185,7 @@ func (m *WebenumModule) Run(target string, ctx *core.Context) (core.Result, erro
 		}
 	}
 
-	return core.Result{
-
+	result := core.Result{
 		ModuleName: m.Name(),
 		Data: map[string]interface{}{
 			"target":          target,
@@ -131,23 +196,63 @@ func (m *WebenumModule) Run(target string, ctx *core.Context) (core.Result, erro
 			"count":           len(dirs),
 			"scan_timestamp":  time.Now().Format(time.RFC3339),
 		},
-	}, nil
+	}
+	for _, dir := range dirs {
+		if title, ok := sensitivePath(dir.Path); ok {
+			result.AddFinding(core.Finding{
+				Title:       title,
+				Severity:    core.SeverityMedium,
+				Confidence:  core.ConfidenceTentative,
+				Asset:       core.Asset{Host: hostOf(target), URL: strings.TrimRight(baseURL, "/") + dir.Path},
+				Evidence:    fmt.Sprintf("HTTP %d, %d bytes, title %q", dir.StatusCode, dir.Size, dir.Title),
+				References:  []string{"CWE-538"},
+				Remediation: "Remove the resource or restrict it behind authentication.",
+			})
+		}
+	}
+	return result, nil
+}
+
+// sensitivePath reports whether a discovered path is worth raising as a finding.
+func sensitivePath(path string) (string, bool) {
+	p := strings.ToLower(path)
+	switch {
+	case strings.Contains(p, ".git") || strings.Contains(p, ".svn"):
+		return "Source control metadata exposed", true
+	case strings.Contains(p, ".env") || strings.Contains(p, "config"):
+		return "Configuration resource exposed", true
+	case strings.Contains(p, "backup") || strings.HasSuffix(p, ".sql") || strings.HasSuffix(p, ".bak"):
+		return "Backup resource exposed", true
+	case strings.Contains(p, "phpmyadmin"):
+		return "phpMyAdmin interface exposed", true
+	case strings.Contains(p, "phpinfo"):
+		return "phpinfo() page exposed", true
+	}
+	return "", false
+}
+
+// landingPage describes the response detectWebTech fingerprinted, after
+// redirects.
+type landingPage struct {
+	URL        string
+	StatusCode int
+	TLS        *tls.ConnectionState
 }
 
 // detectWebTech grabs headers/body for simple fingerprinting
-// detectWebTech grabs headers/body for simple fingerprinting
-func detectWebTech(client *http.Client, baseURL string) ([]string, error) {
+func detectWebTech(ctx context.Context, client *http.Client, baseURL string) ([]string, landingPage, error) {
 	var techs []string
-	resp, err := client.Get(baseURL)
+	resp, err := getWithContext(ctx, client, baseURL)
 	if err != nil {
-		return techs, err
+		return techs, landingPage{}, err
 	}
 	defer resp.Body.Close()
+	page := landingPage{URL: resp.Request.URL.String(), StatusCode: resp.StatusCode, TLS: resp.TLS}
 
 	// --- 1. Use Wappalyzer for detection ---
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return techs, err
+		return techs, page, err
 	}
 
 	// Initialize wappalyzer
@@ -477,7 +582,7 @@ func detectWebTech(client *http.Client, baseURL string) ([]string, error) {
 	}
 
 	// First, get the base response to compare against
-	baseResp, err := client.Get(baseURL)
+	baseResp, err := getWithContext(ctx, client, baseURL)
 	var baseBody string
 	var baseContentLength int64
 	if err == nil {
@@ -488,9 +593,11 @@ func detectWebTech(client *http.Client, baseURL string) ([]string, error) {
 	}
 
 	for _, path := range checkPaths {
+		if ctx.Err() != nil {
+			break
+		}
 		pathURL := strings.TrimRight(baseURL, "/") + path
-		req, _ := http.NewRequest("GET", pathURL, nil)
-		req.Header.Set("User-Agent", "TrikshaReconBot/1.0")
+		req, _ := http.NewRequestWithContext(ctx, "GET", pathURL, nil)
 
 		pathResp, err := client.Do(req)
 		if err == nil {
@@ -554,7 +661,43 @@ func detectWebTech(client *http.Client, baseURL string) ([]string, error) {
 
 	// De-duplicate
 	techs = uniqueStrings(techs)
-	return techs, nil
+	return techs, page, nil
+}
+
+// graphTech reduces a detected technology to a name for the asset graph,
+// e.g. "WordPress (cookie)" to "WordPress" and "Server: nginx/1.25" to
+// "nginx/1.25". Other headers and path hints are not technologies.
+func graphTech(tech string) (string, bool) {
+	if name, value, ok := strings.Cut(tech, ": "); ok {
+		switch name {
+		case "Server", "X-Powered-By", "Generator":
+			return value, true
+		}
+		return "", false
+	}
+	if strings.HasSuffix(tech, " found") || strings.HasPrefix(tech, "X-") || strings.HasPrefix(tech, "Content-") {
+		return "", false
+	}
+	if i := strings.Index(tech, " ("); i > 0 {
+		tech = tech[:i]
+	}
+	return tech, true
+}
+
+// recordCertificate adds the leaf certificate a site presented.
+func recordCertificate(assets *core.AssetWriter, host string, state *tls.ConnectionState) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+	cert := state.PeerCertificates[0]
+	sum := sha256.Sum256(cert.Raw)
+	id := assets.Add(core.NodeCertificate, hex.EncodeToString(sum[:]), map[string]string{
+		"subject":   cert.Subject.CommonName,
+		"issuer":    cert.Issuer.CommonName,
+		"not_after": cert.NotAfter.UTC().Format(time.RFC3339),
+		"dns_names": strings.Join(cert.DNSNames, ","),
+	})
+	assets.Link(id, core.EdgeSecures, assets.Host(host, nil))
 }
 
 // Helper function to extract version numbers from strings
@@ -610,7 +753,7 @@ func uniqueStrings(input []string) []string {
 }
 
 // bruteForceDirs checks for common directories (add your own wordlist)
-func bruteForceDirs(client *http.Client, baseURL, wordlist string) ([]DirResult, error) {
+func bruteForceDirs(ctx context.Context, client *http.Client, baseURL, wordlist string) ([]DirResult, error) {
 	var found []DirResult
 	file, err := os.Open(wordlist)
 	if err != nil {
@@ -620,7 +763,7 @@ func bruteForceDirs(client *http.Client, baseURL, wordlist string) ([]DirResult,
 	scanner := bufio.NewScanner(file)
 
 	// First, get the base response to compare against
-	baseResp, err := client.Get(baseURL)
+	baseResp, err := getWithContext(ctx, client, baseURL)
 	var baseBody string
 	var baseContentLength int64
 	if err == nil {
@@ -631,13 +774,15 @@ func bruteForceDirs(client *http.Client, baseURL, wordlist string) ([]DirResult,
 	}
 
 	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return found, err
+		}
 		dir := strings.TrimSpace(scanner.Text())
 		if dir == "" || strings.HasPrefix(dir, "#") {
 			continue
 		}
 		u := strings.TrimRight(baseURL, "/") + "/" + dir
-		req, _ := http.NewRequest("GET", u, nil)
-		req.Header.Set("User-Agent", "TrikshaReconBot/1.0")
+		req, _ := http.NewRequestWithContext(ctx, "GET", u, nil)
 		resp, err := client.Do(req)
 		if err == nil {
 			// Only consider it a valid finding if:
@@ -733,11 +878,23 @@ func similarity(s1, s2 string) float64 {
 	return float64(intersection) / float64(union)
 }
 
+// getWithContext issues a GET request bound to ctx.
+func getWithContext(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
 // ensureHTTP adds http:// if missing
 func ensureHTTP(target string) string {
 	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
 		return target
 	}
+	if _, port, err := net.SplitHostPort(target); err == nil && (port == "443" || port == "8443") {
+		return "https://" + target
+	}
 	return "http://" + target
 }
 
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+// diffSection is one set of added/removed values in a diff report.
+type diffSection struct {
+	Title  string
+	Change core.SetChange
+}
+
+func diffSections(d core.ScanDiff) []diffSection {
+	return []diffSection{
+		{"Subdomains", d.Subdomains},
+		{"IP Addresses", d.IPs},
+		{"Open Ports", d.Ports},
+		{"Technologies", d.Technologies},
+		{"URLs and Directories", d.URLs},
+	}
+}
+
+func diffHeader(s core.ScanSnapshot) string {
+	return fmt.Sprintf("%s (%s)", s.ID, s.Time.Format("2006-01-02 15:04"))
+}
+
+// WriteDiffJSONReport exports the change set between two scans as JSON.
+func WriteDiffJSONReport(d core.ScanDiff, path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// WriteDiffMarkdownReport exports the change set between two scans as Markdown.
+func WriteDiffMarkdownReport(d core.ScanDiff, path string) error {
+	return os.WriteFile(path, []byte(DiffMarkdown(d)), 0644)
+}
+
+// DiffMarkdown renders the change set between two scans as Markdown.
+func DiffMarkdown(d core.ScanDiff) string {
+	var sb strings.Builder
+	sb.WriteString("# Triksha Scan Diff\n\n")
+	sb.WriteString(fmt.Sprintf("- **Old scan:** `%s`\n", diffHeader(d.Old)))
+	sb.WriteString(fmt.Sprintf("- **New scan:** `%s`\n", diffHeader(d.New)))
+	sb.WriteString(fmt.Sprintf("- **Targets:** `%s`\n", strings.Join(d.New.Targets, "`, `")))
+	sb.WriteString(fmt.Sprintf("- **Changes:** %s\n\n", d.Summary()))
+	if d.Empty() {
+		return sb.String()
+	}
+
+	for _, s := range diffSections(d) {
+		if s.Change.Empty() {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("## %s\n\n", s.Title))
+		for _, v := range s.Change.Added {
+			sb.WriteString(fmt.Sprintf("- **+** `%s`\n", v))
+		}
+		for _, v := range s.Change.Removed {
+			sb.WriteString(fmt.Sprintf("- **-** `%s`\n", v))
+		}
+		sb.WriteString("\n")
+	}
+	if len(d.Services) > 0 {
+		sb.WriteString("## Changed Services\n\n| Service | Field | Old | New |\n|---|---|---|---|\n")
+		for _, c := range d.Services {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", c.Asset, c.Field, markdownCell(c.Old), markdownCell(c.New)))
+		}
+		sb.WriteString("\n")
+	}
+	if len(d.NewFindings) > 0 {
+		sb.WriteString("## New Findings\n\n")
+		for _, f := range d.NewFindings {
+			writeMarkdownFinding(&sb, f)
+		}
+	}
+	if len(d.Resolved) > 0 {
+		sb.WriteString("## Resolved Findings\n\n")
+		for _, f := range d.Resolved {
+			sb.WriteString(fmt.Sprintf("- [%s] %s (`%s`)\n", strings.ToUpper(string(f.Severity)), f.Title, f.Asset))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// markdownCell keeps a banner on one table row.
+func markdownCell(s string) string {
+	s = strings.NewReplacer("\r", "", "\n", " ", "|", "\\|").Replace(s)
+	if s == "" {
+		return "-"
+	}
+	return "`" + s + "`"
+}
+
+// WriteDiffHTMLReport exports the change set between two scans as HTML.
+func WriteDiffHTMLReport(d core.ScanDiff, path string) error {
+	var sb strings.Builder
+	sb.WriteString(htmlHead("Triksha Scan Diff"))
+	sb.WriteString("<h1>Triksha Scan Diff</h1>")
+	sb.WriteString(`<div class="summary"><h2>Summary</h2><ul>`)
+	sb.WriteString(fmt.Sprintf("<li><strong>Old scan:</strong> <code>%s</code></li>", html.EscapeString(diffHeader(d.Old))))
+	sb.WriteString(fmt.Sprintf("<li><strong>New scan:</strong> <code>%s</code></li>", html.EscapeString(diffHeader(d.New))))
+	sb.WriteString(fmt.Sprintf("<li><strong>Targets:</strong> <code>%s</code></li>", html.EscapeString(strings.Join(d.New.Targets, ", "))))
+	sb.WriteString(fmt.Sprintf("<li><strong>Changes:</strong> %s</li>", html.EscapeString(d.Summary())))
+	sb.WriteString("</ul></div>")
+
+	for _, s := range diffSections(d) {
+		if s.Change.Empty() {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(`<div class="module"><h2>%s</h2><ul>`, html.EscapeString(s.Title)))
+		for _, v := range s.Change.Added {
+			sb.WriteString(fmt.Sprintf(`<li class="added"><strong>+</strong> <code>%s</code></li>`, html.EscapeString(v)))
+		}
+		for _, v := range s.Change.Removed {
+			sb.WriteString(fmt.Sprintf(`<li class="removed"><strong>-</strong> <code>%s</code></li>`, html.EscapeString(v)))
+		}
+		sb.WriteString("</ul></div>")
+	}
+	if len(d.Services) > 0 {
+		sb.WriteString(`<div class="module"><h2>Changed Services</h2><table><tr><th>Service</th><th>Field</th><th>Old</th><th>New</th></tr>`)
+		for _, c := range d.Services {
+			sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td><code>%s</code></td><td><code>%s</code></td></tr>",
+				html.EscapeString(c.Asset), html.EscapeString(c.Field), html.EscapeString(c.Old), html.EscapeString(c.New)))
+		}
+		sb.WriteString("</table></div>")
+	}
+	if len(d.NewFindings) > 0 {
+		sb.WriteString(`<div class="module"><h2>New Findings</h2>`)
+		for _, f := range d.NewFindings {
+			writeHTMLFinding(&sb, f)
+		}
+		sb.WriteString("</div>")
+	}
+	if len(d.Resolved) > 0 {
+		sb.WriteString(`<div class="module"><h2>Resolved Findings</h2><ul>`)
+		for _, f := range d.Resolved {
+			sb.WriteString(fmt.Sprintf("<li>[%s] %s (<code>%s</code>)</li>",
+				strings.ToUpper(html.EscapeString(string(f.Severity))), html.EscapeString(f.Title), html.EscapeString(f.Asset.String())))
+		}
+		sb.WriteString("</ul></div>")
+	}
+	sb.WriteString("</body></html>")
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
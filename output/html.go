@@ -5,64 +5,116 @@ import (
 	"fmt"
 	"html"
 	"os"
-	"sort"
 	"strings"
 
 	"github.com/r4j3sh-com/triksha/core"
 )
 
-func WriteHTMLReport(cfg core.Config, results []core.Result, path string) error {
+// WriteHTMLReport exports the scan results as a HTML file.
+func WriteHTMLReport(cfg core.Config, results []core.Result, assets *core.AssetGraph, path string) error {
+	return os.WriteFile(path, []byte(HTMLReport(cfg, results, assets)), 0644)
+}
+
+// HTMLReport renders the scan results as HTML.
+func HTMLReport(cfg core.Config, results []core.Result, assets *core.AssetGraph) string {
 	var sb strings.Builder
 
 	// --- Summary Section ---
-	var summaryOpenPorts []int
-	var summaryTechs []string
-	var summaryVulns []string
+	sum := summarize(results, assets)
+	groups := groupByTarget(results)
+	targets := targetNames(cfg, groups)
 
-	for _, r := range results {
-		switch r.ModuleName {
-		case "portscan":
-			if data, ok := r.Data["open_ports"]; ok {
-				if ports, ok := data.([]interface{}); ok {
-					for _, p := range ports {
-						if portInfo, ok := p.(map[string]interface{}); ok {
-							if port, ok := portInfo["port"].(float64); ok {
-								summaryOpenPorts = append(summaryOpenPorts, int(port))
-							}
-						}
-					}
-				}
-			}
-		case "webenum":
-			if data, ok := r.Data["tech_detected"].([]string); ok {
-				summaryTechs = append(summaryTechs, data...)
-			}
-		case "vulnscan":
-			if data, ok := r.Data["vulns"].([]string); ok {
-				summaryVulns = append(summaryVulns, data...)
+	// --- HTML Structure ---
+	sb.WriteString(htmlHead("Triksha Recon Report"))
+	sb.WriteString("<h1>Triksha Recon Report</h1>")
+
+	// Summary Box
+	sb.WriteString(`<div class="summary"><h2>Summary</h2><ul>`)
+	if len(targets) == 1 {
+		sb.WriteString(fmt.Sprintf("<li><strong>Target:</strong> <code>%s</code></li>", html.EscapeString(targets[0])))
+	} else {
+		sb.WriteString(fmt.Sprintf("<li><strong>Targets:</strong> %d (<code>%s</code>)</li>", len(targets), html.EscapeString(strings.Join(targets, ", "))))
+	}
+	if len(sum.OpenPorts) > 0 {
+		sb.WriteString(fmt.Sprintf("<li><strong>Open Ports:</strong> %v</li>", sum.OpenPorts))
+	}
+	if len(sum.Techs) > 0 {
+		sb.WriteString(fmt.Sprintf("<li><strong>Tech Detected:</strong> %s</li>", html.EscapeString(fmt.Sprintf("%v", sum.Techs))))
+	}
+	sb.WriteString(fmt.Sprintf("<li><strong>Findings:</strong> %d</li>", len(sum.Findings)))
+	sb.WriteString("</ul><table><tr><th>Severity</th><th>Count</th></tr>")
+	for _, sev := range core.Severities {
+		sb.WriteString(fmt.Sprintf(`<tr class="sev-%s"><td>%s</td><td>%d</td></tr>`, sev, strings.ToUpper(string(sev)), sum.Counts[sev]))
+	}
+	sb.WriteString("</table></div>")
+
+	// Findings
+	if len(sum.Findings) > 0 {
+		sb.WriteString(`<div class="module"><h2>Findings</h2>`)
+		for _, f := range sum.Findings {
+			writeHTMLFinding(&sb, f)
+		}
+		sb.WriteString("</div>")
+	}
+
+	// Asset Inventory
+	if rows := inventory(assets); len(rows) > 0 {
+		sb.WriteString(`<div class="module"><h2>Assets</h2>`)
+		sb.WriteString(fmt.Sprintf("<p><strong>Discovered:</strong> %s</p>", html.EscapeString(assetCounts(assets))))
+		sb.WriteString("<table><tr><th>Host</th><th>IPs</th><th>Services</th><th>Technologies</th><th>Findings</th></tr>")
+		for _, row := range rows {
+			host := html.EscapeString(row.Host)
+			if row.OutOfScope {
+				host += " <em>(out of scope)</em>"
 			}
+			sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%d</td></tr>", host,
+				html.EscapeString(strings.Join(row.IPs, ", ")), html.EscapeString(strings.Join(row.Services, ", ")),
+				html.EscapeString(strings.Join(row.Techs, ", ")), row.Findings))
 		}
+		sb.WriteString("</table></div>")
 	}
 
-	// Deduplicate and sort for clean output
-	techMap := make(map[string]bool)
-	uniqueTechs := []string{}
-	for _, tech := range summaryTechs {
-		if !techMap[tech] {
-			techMap[tech] = true
-			uniqueTechs = append(uniqueTechs, tech)
+	// Per-target Results
+	for _, g := range groups {
+		name := g.Target
+		if name == "" {
+			name = cfg.Target
+		}
+		sb.WriteString(fmt.Sprintf(`<h2 class="target">Target: %s</h2>`, html.EscapeString(name)))
+		if len(groups) > 1 {
+			if line := severityLine(g.Results); line != "" {
+				sb.WriteString(fmt.Sprintf("<p><strong>Findings:</strong> %s</p>", line))
+			}
+		}
+		for _, r := range g.Results {
+			sb.WriteString(fmt.Sprintf(`<div class="module"><h2>Module: %s</h2>`, html.EscapeString(r.ModuleName)))
+			if line := retryLine(r); line != "" {
+				sb.WriteString(fmt.Sprintf("<p><strong>Retries:</strong> %s</p>", html.EscapeString(line)))
+			}
+			for _, k := range sortedKeys(r.Data) {
+				v := r.Data[k]
+				sb.WriteString(fmt.Sprintf("<h3>%s</h3>", html.EscapeString(strings.ToTitle(k))))
+				pretty, err := json.MarshalIndent(v, "", "  ")
+				if err != nil {
+					sb.WriteString(fmt.Sprintf("<pre>%s</pre>", html.EscapeString(fmt.Sprintf("%v", v))))
+				} else {
+					sb.WriteString(fmt.Sprintf("<pre>%s</pre>", html.EscapeString(string(pretty))))
+				}
+			}
+			sb.WriteString("</div>")
 		}
 	}
-	sort.Strings(uniqueTechs)
-	sort.Ints(summaryOpenPorts)
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
 
-	// --- HTML Structure ---
-	sb.WriteString(`<!DOCTYPE html>
+// htmlPage is the page header and stylesheet shared by the HTML reports.
+const htmlPage = `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Triksha Recon Report</title>
+    <title>%s</title>
     <style>
         body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; line-height: 1.6; color: #333; max-width: 1000px; margin: 20px auto; padding: 0 20px; }
         h1, h2, h3 { color: #2c3e50; }
@@ -72,43 +124,36 @@ func WriteHTMLReport(cfg core.Config, results []core.Result, path string) error
         pre { background: #2d2d2d; color: #f1f1f1; padding: 15px; border-radius: 5px; white-space: pre-wrap; word-wrap: break-word; font-family: "Fira Code", "Courier New", monospace; }
         ul { list-style-type: square; padding-left: 20px; }
 		code { background: #ecf0f1; padding: 2px 5px; border-radius: 4px; color: #c0392b; }
+        table { border-collapse: collapse; margin-top: 10px; }
+        th, td { border: 1px solid #ddd; padding: 4px 12px; text-align: left; }
+        .finding { border-left: 6px solid #95a5a6; background: #fff; padding: 10px 15px; margin-bottom: 15px; border-radius: 4px; }
+        .finding h3 { margin: 0 0 8px 0; }
+        h2.target { border-bottom: 2px solid #ecf0f1; padding-bottom: 5px; }
+        .sev-critical { border-color: #8e44ad; } .sev-high { border-color: #c0392b; } .sev-medium { border-color: #e67e22; } .sev-low { border-color: #f1c40f; } .sev-info { border-color: #3498db; }
     </style>
 </head>
-<body>`)
-	sb.WriteString("<h1>Triksha Recon Report</h1>")
+<body>`
 
-	// Summary Box
-	sb.WriteString(`<div class="summary"><h2>Summary</h2><ul>`)
-	sb.WriteString(fmt.Sprintf("<li><strong>Target:</strong> <code>%s</code></li>", html.EscapeString(cfg.Target)))
-	if len(summaryOpenPorts) > 0 {
-		sb.WriteString(fmt.Sprintf("<li><strong>Open Ports:</strong> %v</li>", summaryOpenPorts))
-	}
-	if len(uniqueTechs) > 0 {
-		sb.WriteString(fmt.Sprintf("<li><strong>Tech Detected:</strong> %v</li>", uniqueTechs))
+// htmlHead opens a report page titled title.
+func htmlHead(title string) string {
+	return fmt.Sprintf(htmlPage, html.EscapeString(title))
+}
+
+func writeHTMLFinding(sb *strings.Builder, f core.Finding) {
+	sb.WriteString(fmt.Sprintf(`<div class="finding sev-%s">`, html.EscapeString(string(f.Severity))))
+	sb.WriteString(fmt.Sprintf("<h3>[%s] %s</h3><ul>", strings.ToUpper(html.EscapeString(string(f.Severity))), html.EscapeString(f.Title)))
+	sb.WriteString(fmt.Sprintf("<li><strong>Asset:</strong> <code>%s</code></li>", html.EscapeString(f.Asset.String())))
+	sb.WriteString(fmt.Sprintf("<li><strong>Confidence:</strong> %s</li>", html.EscapeString(string(f.Confidence))))
+	sb.WriteString(fmt.Sprintf("<li><strong>Module:</strong> %s (<code>%s</code>)</li>", html.EscapeString(f.Module), html.EscapeString(f.ID)))
+	if len(f.References) > 0 {
+		sb.WriteString(fmt.Sprintf("<li><strong>References:</strong> %s</li>", html.EscapeString(strings.Join(f.References, ", "))))
 	}
-	if len(summaryVulns) > 0 {
-		sb.WriteString("<li><strong>Potential Vulnerabilities:</strong><ul>")
-		for _, v := range summaryVulns {
-			sb.WriteString(fmt.Sprintf("<li>%s</li>", html.EscapeString(v)))
-		}
-		sb.WriteString("</ul></li>")
+	if f.Remediation != "" {
+		sb.WriteString(fmt.Sprintf("<li><strong>Remediation:</strong> %s</li>", html.EscapeString(f.Remediation)))
 	}
-	sb.WriteString("</ul></div>")
-
-	// Detailed Results
-	for _, r := range results {
-		sb.WriteString(fmt.Sprintf(`<div class="module"><h2>Module: %s</h2>`, html.EscapeString(r.ModuleName)))
-		for k, v := range r.Data {
-			sb.WriteString(fmt.Sprintf("<h3>%s</h3>", html.EscapeString(strings.ToTitle(k))))
-			pretty, err := json.MarshalIndent(v, "", "  ")
-			if err != nil {
-				sb.WriteString(fmt.Sprintf("<pre>%s</pre>", html.EscapeString(fmt.Sprintf("%v", v))))
-			} else {
-				sb.WriteString(fmt.Sprintf("<pre>%s</pre>", html.EscapeString(string(pretty))))
-			}
-		}
-		sb.WriteString("</div>")
+	sb.WriteString("</ul>")
+	if f.Evidence != "" {
+		sb.WriteString(fmt.Sprintf("<pre>%s</pre>", html.EscapeString(f.Evidence)))
 	}
-	sb.WriteString("</body></html>")
-	return os.WriteFile(path, []byte(sb.String()), 0644)
+	sb.WriteString("</div>")
 }
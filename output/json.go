@@ -7,10 +7,54 @@ import (
 	"github.com/r4j3sh-com/triksha/core"
 )
 
-func WriteJSONReport(results []core.Result, path string) error {
-	data, err := json.MarshalIndent(results, "", "  ")
+// jsonReport is the layout of the exported JSON file.
+type jsonReport struct {
+	FindingCounts map[core.Severity]int `json:"finding_counts"`
+	Findings      []core.Finding        `json:"findings"`
+	Targets       []jsonTarget          `json:"targets"`
+	Assets        *core.AssetGraph      `json:"assets"`
+	Results       []core.Result         `json:"results"`
+}
+
+// jsonTarget summarizes one target of a multi-target scan.
+type jsonTarget struct {
+	Target        string                `json:"target"`
+	Modules       []string              `json:"modules"`
+	FindingCounts map[core.Severity]int `json:"finding_counts"`
+}
+
+// WriteJSONReport exports the scan results as a JSON file.
+func WriteJSONReport(results []core.Result, assets *core.AssetGraph, path string) error {
+	data, err := JSONReport(results, assets)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(path, data, 0644)
 }
+
+// JSONReport renders the scan results as indented JSON.
+func JSONReport(results []core.Result, assets *core.AssetGraph) ([]byte, error) {
+	findings := core.CollectFindings(results)
+	if findings == nil {
+		findings = []core.Finding{}
+	}
+	report := jsonReport{
+		FindingCounts: core.CountBySeverity(findings),
+		Findings:      findings,
+		Targets:       []jsonTarget{},
+		Assets:        assets,
+		Results:       results,
+	}
+	for _, g := range groupByTarget(results) {
+		t := jsonTarget{
+			Target:        g.Target,
+			Modules:       []string{},
+			FindingCounts: core.CountBySeverity(core.CollectFindings(g.Results)),
+		}
+		for _, r := range g.Results {
+			t.Modules = append(t.Modules, r.ModuleName)
+		}
+		report.Targets = append(report.Targets, t)
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
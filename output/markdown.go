@@ -4,89 +4,119 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"sort"
 	"strings"
 
 	"github.com/r4j3sh-com/triksha/core"
 )
 
-func WriteMarkdownReport(cfg core.Config, results []core.Result, path string) error {
+// WriteMarkdownReport exports the scan results as a Markdown file.
+func WriteMarkdownReport(cfg core.Config, results []core.Result, assets *core.AssetGraph, path string) error {
+	return os.WriteFile(path, []byte(MarkdownReport(cfg, results, assets)), 0644)
+}
+
+// MarkdownReport renders the scan results as Markdown.
+func MarkdownReport(cfg core.Config, results []core.Result, assets *core.AssetGraph) string {
 	var sb strings.Builder
 
 	// --- Summary Section ---
-	var summaryOpenPorts []int
-	var summaryTechs []string
-	var summaryVulns []string
+	sum := summarize(results, assets)
+	groups := groupByTarget(results)
+	targets := targetNames(cfg, groups)
 
-	for _, r := range results {
-		switch r.ModuleName {
-		case "portscan":
-			if data, ok := r.Data["open_ports"]; ok {
-				if ports, ok := data.([]interface{}); ok {
-					for _, p := range ports {
-						if portInfo, ok := p.(map[string]interface{}); ok {
-							if port, ok := portInfo["port"].(float64); ok { // JSON unmarshals numbers to float64
-								summaryOpenPorts = append(summaryOpenPorts, int(port))
-							}
-						}
-					}
-				}
-			}
-		case "webenum":
-			if data, ok := r.Data["tech_detected"].([]string); ok {
-				summaryTechs = append(summaryTechs, data...)
-			}
-		case "vulnscan":
-			if data, ok := r.Data["vulns"].([]string); ok {
-				summaryVulns = append(summaryVulns, data...)
-			}
-		}
+	sb.WriteString("# Triksha Recon Report\n\n")
+	sb.WriteString("## Summary\n\n")
+	if len(targets) == 1 {
+		sb.WriteString(fmt.Sprintf("- **Target:** `%s`\n", targets[0]))
+	} else {
+		sb.WriteString(fmt.Sprintf("- **Targets:** %d (`%s`)\n", len(targets), strings.Join(targets, "`, `")))
 	}
-
-	// Deduplicate and sort for clean output
-	techMap := make(map[string]bool)
-	uniqueTechs := []string{}
-	for _, tech := range summaryTechs {
-		if !techMap[tech] {
-			techMap[tech] = true
-			uniqueTechs = append(uniqueTechs, tech)
-		}
+	if len(sum.OpenPorts) > 0 {
+		sb.WriteString(fmt.Sprintf("- **Open Ports:** %v\n", sum.OpenPorts))
+	}
+	if len(sum.Techs) > 0 {
+		sb.WriteString(fmt.Sprintf("- **Tech Detected:** %v\n", sum.Techs))
 	}
-	sort.Strings(uniqueTechs)
-	sort.Ints(summaryOpenPorts)
+	sb.WriteString(fmt.Sprintf("- **Findings:** %d\n\n", len(sum.Findings)))
 
-	sb.WriteString("# Triksha Recon Report\n\n")
-	sb.WriteString("## Summary\n\n")
-	sb.WriteString(fmt.Sprintf("- **Target:** `%s`\n", cfg.Target))
-	if len(summaryOpenPorts) > 0 {
-		sb.WriteString(fmt.Sprintf("- **Open Ports:** %v\n", summaryOpenPorts))
+	sb.WriteString("| Severity | Count |\n|---|---|\n")
+	for _, sev := range core.Severities {
+		sb.WriteString(fmt.Sprintf("| %s | %d |\n", strings.ToUpper(string(sev)), sum.Counts[sev]))
 	}
-	if len(uniqueTechs) > 0 {
-		sb.WriteString(fmt.Sprintf("- **Tech Detected:** %v\n", uniqueTechs))
+	sb.WriteString("\n---\n\n")
+
+	// --- Findings ---
+	if len(sum.Findings) > 0 {
+		sb.WriteString("## Findings\n\n")
+		for _, f := range sum.Findings {
+			writeMarkdownFinding(&sb, f)
+		}
+		sb.WriteString("---\n\n")
 	}
-	if len(summaryVulns) > 0 {
-		sb.WriteString("- **Potential Vulnerabilities:**\n")
-		for _, v := range summaryVulns {
-			sb.WriteString(fmt.Sprintf("  - %s\n", v))
+
+	// --- Asset Inventory ---
+	if rows := inventory(assets); len(rows) > 0 {
+		sb.WriteString("## Assets\n\n")
+		sb.WriteString(fmt.Sprintf("- **Discovered:** %s\n\n", assetCounts(assets)))
+		sb.WriteString("| Host | IPs | Services | Technologies | Findings |\n|---|---|---|---|---|\n")
+		for _, row := range rows {
+			host := row.Host
+			if row.OutOfScope {
+				host += " (out of scope)"
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %d |\n", host,
+				strings.Join(row.IPs, ", "), strings.Join(row.Services, ", "), strings.Join(row.Techs, ", "), row.Findings))
 		}
+		sb.WriteString("\n---\n\n")
 	}
-	sb.WriteString("\n---\n\n")
 
-	// --- Detailed Results ---
-	for _, r := range results {
-		sb.WriteString(fmt.Sprintf("## Module: %s\n\n", r.ModuleName))
-		for k, v := range r.Data {
-			sb.WriteString(fmt.Sprintf("### %s\n\n", strings.ToTitle(k)))
-			sb.WriteString("```json\n")
-			// Pretty print JSON
-			pretty, err := json.MarshalIndent(v, "", "  ")
-			if err != nil {
-				sb.WriteString(fmt.Sprintf("%v\n", v)) // fallback to default print
-			} else {
-				sb.WriteString(string(pretty) + "\n")
+	// --- Per-target Results ---
+	for _, g := range groups {
+		name := g.Target
+		if name == "" {
+			name = cfg.Target
+		}
+		sb.WriteString(fmt.Sprintf("## Target: %s\n\n", name))
+		if len(groups) > 1 {
+			if line := severityLine(g.Results); line != "" {
+				sb.WriteString(fmt.Sprintf("- **Findings:** %s\n\n", line))
 			}
-			sb.WriteString("```\n\n")
 		}
+		for _, r := range g.Results {
+			sb.WriteString(fmt.Sprintf("### Module: %s\n\n", r.ModuleName))
+			if line := retryLine(r); line != "" {
+				sb.WriteString(fmt.Sprintf("- **Retries:** %s\n\n", line))
+			}
+			for _, k := range sortedKeys(r.Data) {
+				v := r.Data[k]
+				sb.WriteString(fmt.Sprintf("#### %s\n\n", strings.ToTitle(k)))
+				sb.WriteString("```json\n")
+				// Pretty print JSON
+				pretty, err := json.MarshalIndent(v, "", "  ")
+				if err != nil {
+					sb.WriteString(fmt.Sprintf("%v\n", v)) // fallback to default print
+				} else {
+					sb.WriteString(string(pretty) + "\n")
+				}
+				sb.WriteString("```\n\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func writeMarkdownFinding(sb *strings.Builder, f core.Finding) {
+	sb.WriteString(fmt.Sprintf("### [%s] %s\n\n", strings.ToUpper(string(f.Severity)), f.Title))
+	sb.WriteString(fmt.Sprintf("- **Asset:** `%s`\n", f.Asset))
+	sb.WriteString(fmt.Sprintf("- **Confidence:** %s\n", f.Confidence))
+	sb.WriteString(fmt.Sprintf("- **Module:** %s (`%s`)\n", f.Module, f.ID))
+	if len(f.References) > 0 {
+		sb.WriteString(fmt.Sprintf("- **References:** %s\n", strings.Join(f.References, ", ")))
+	}
+	if f.Remediation != "" {
+		sb.WriteString(fmt.Sprintf("- **Remediation:** %s\n", f.Remediation))
+	}
+	if f.Evidence != "" {
+		sb.WriteString("\n```\n" + f.Evidence + "\n```\n")
 	}
-	return os.WriteFile(path, []byte(sb.String()), 0644)
+	sb.WriteString("\n")
 }
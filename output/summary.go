@@ -0,0 +1,178 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+// summary holds the headline facts shared by the Markdown and HTML reports.
+type summary struct {
+	OpenPorts []int
+	Techs     []string
+	Findings  []core.Finding
+	Counts    map[core.Severity]int
+}
+
+// summarize reads ports and technologies from the asset graph and
+// findings from the results.
+func summarize(results []core.Result, assets *core.AssetGraph) summary {
+	var s summary
+	ports := make(map[int]bool)
+	for _, svc := range assets.Nodes(core.NodeService) {
+		if port, err := strconv.Atoi(svc.Attrs["port"]); err == nil && !ports[port] {
+			ports[port] = true
+			s.OpenPorts = append(s.OpenPorts, port)
+		}
+	}
+	for _, tech := range assets.Nodes(core.NodeTechnology) {
+		s.Techs = append(s.Techs, tech.Value)
+	}
+	sort.Ints(s.OpenPorts)
+	s.Findings = core.CollectFindings(results)
+	s.Counts = core.CountBySeverity(s.Findings)
+	return s
+}
+
+// hostRow is one host of the asset inventory.
+type hostRow struct {
+	Host       string
+	OutOfScope bool
+	IPs        []string
+	Services   []string
+	Techs      []string
+	Findings   int
+}
+
+// inventory walks the asset graph from every domain, subdomain and IP to
+// its addresses, services, the technologies its URLs run and the findings
+// raised against any of them.
+func inventory(assets *core.AssetGraph) []hostRow {
+	affected := make(map[string]int)
+	for _, f := range assets.Nodes(core.NodeFinding) {
+		for _, n := range assets.Out(f.ID, core.EdgeAffects) {
+			affected[n.ID]++
+		}
+	}
+	var rows []hostRow
+	for _, h := range assets.Nodes(core.NodeDomain, core.NodeSubdomain, core.NodeIP) {
+		row := hostRow{Host: h.Value, OutOfScope: h.Attrs["out_of_scope"] == "true", Findings: affected[h.ID]}
+		row.IPs = values(assets.Out(h.ID, core.EdgeResolvesTo))
+		techs := make(map[string]bool)
+		for _, svc := range assets.Out(h.ID, core.EdgeHosts) {
+			label := svc.Attrs["port"]
+			if name := svc.Attrs["service"]; name != "" {
+				label += "/" + name
+			}
+			row.Services = append(row.Services, label)
+			row.Findings += affected[svc.ID]
+			for _, u := range assets.Out(svc.ID, core.EdgeServes) {
+				row.Findings += affected[u.ID]
+				for _, tech := range assets.Out(u.ID, core.EdgeRuns) {
+					if !techs[tech.Value] {
+						techs[tech.Value] = true
+						row.Techs = append(row.Techs, tech.Value)
+					}
+				}
+			}
+		}
+		sort.Strings(row.Techs)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// assetCounts renders non-zero node counts, e.g. "subdomain: 12, ip: 3".
+func assetCounts(assets *core.AssetGraph) string {
+	counts := assets.Counts()
+	var parts []string
+	for _, kind := range core.NodeKinds {
+		if counts[kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", kind, counts[kind]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func values(nodes []core.Node) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.Value
+	}
+	return out
+}
+
+// targetGroup is the slice of results produced for one target.
+type targetGroup struct {
+	Target  string
+	Results []core.Result
+}
+
+// groupByTarget splits results per target, keeping first-seen order.
+func groupByTarget(results []core.Result) []targetGroup {
+	var groups []targetGroup
+	index := make(map[string]int)
+	for _, r := range results {
+		i, ok := index[r.Target]
+		if !ok {
+			i = len(groups)
+			index[r.Target] = i
+			groups = append(groups, targetGroup{Target: r.Target})
+		}
+		groups[i].Results = append(groups[i].Results, r)
+	}
+	return groups
+}
+
+// targetNames lists the scanned targets, falling back to the configured one.
+func targetNames(cfg core.Config, groups []targetGroup) []string {
+	var names []string
+	for _, g := range groups {
+		if g.Target != "" {
+			names = append(names, g.Target)
+		}
+	}
+	if len(names) == 0 && cfg.Target != "" {
+		names = []string{cfg.Target}
+	}
+	return names
+}
+
+// severityLine renders non-zero finding counts, e.g. "HIGH: 2, LOW: 1".
+func severityLine(results []core.Result) string {
+	counts := core.CountBySeverity(core.CollectFindings(results))
+	var parts []string
+	for _, sev := range core.Severities {
+		if counts[sev] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", strings.ToUpper(string(sev)), counts[sev]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortedKeys returns a result's data keys in a stable order.
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// retryLine renders a result's retry counts, e.g. "crtsh: 2, dns: 1".
+func retryLine(r core.Result) string {
+	sources := make([]string, 0, len(r.Retries))
+	for source := range r.Retries {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	parts := make([]string, len(sources))
+	for i, source := range sources {
+		parts[i] = fmt.Sprintf("%s: %d", source, r.Retries[source])
+	}
+	return strings.Join(parts, ", ")
+}
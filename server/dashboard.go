@@ -0,0 +1,218 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+// DashboardPath is where the web dashboard is served.
+const DashboardPath = "/ui/"
+
+//go:embed web
+var webFiles embed.FS
+
+// dashboard serves the embedded single-page dashboard. The page itself
+// holds no data; it asks for an API token and calls the API with it.
+func dashboard() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(DashboardPath, http.FileServer(http.FS(files)))
+}
+
+// workspaceInfo is a workspace in GET /workspaces.
+type workspaceInfo struct {
+	core.Workspace
+	Scans   int  `json:"scans"`
+	Default bool `json:"default"`
+}
+
+// timelineEntry is an agent decision in a scan's timeline.
+type timelineEntry struct {
+	Target string `json:"target"`
+	core.AgentDecision
+}
+
+// triageRequest is the body of PUT /workspaces/{ws}/findings/{fid}/triage.
+type triageRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// withDB rejects requests for workspace data when no database is open.
+func (s *Server) withDB(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.DB == nil {
+			writeError(w, http.StatusServiceUnavailable, "no workspace database; start the server with -db")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// dbError reports err from a workspace query, as 404 for unknown
+// workspaces, scans and findings.
+func dbError(w http.ResponseWriter, err error) {
+	if errors.Is(err, core.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+func (s *Server) listWorkspaces(w http.ResponseWriter, r *http.Request) {
+	list, err := s.DB.Workspaces()
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if len(list) == 0 {
+		list = []core.Workspace{{Name: core.DefaultWorkspace}}
+	}
+	out := make([]workspaceInfo, 0, len(list))
+	for _, ws := range list {
+		scans, _ := s.DB.Scans(ws.Name)
+		out = append(out, workspaceInfo{Workspace: ws, Scans: len(scans), Default: ws.Name == s.Workspace})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) workspaceScans(w http.ResponseWriter, r *http.Request) {
+	scans, err := s.DB.Scans(r.PathValue("ws"))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if scans == nil {
+		scans = []core.ScanRecord{}
+	}
+	writeJSON(w, http.StatusOK, scans)
+}
+
+// scanTimeline returns the agent decisions of a recorded scan in order.
+func (s *Server) scanTimeline(w http.ResponseWriter, r *http.Request) {
+	ws, id := r.PathValue("ws"), r.PathValue("id")
+	if _, err := s.DB.Scan(ws, id); err != nil {
+		dbError(w, err)
+		return
+	}
+	targets, err := s.DB.Targets(ws, id)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	entries := []timelineEntry{}
+	for target, tr := range targets {
+		for _, d := range tr.Decisions {
+			entries = append(entries, timelineEntry{Target: target, AgentDecision: d})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) workspaceAssets(w http.ResponseWriter, r *http.Request) {
+	q := core.AssetQuery{Scan: r.URL.Query().Get("scan"), Match: r.URL.Query().Get("q")}
+	for _, kind := range splitList(r.URL.Query().Get("kind")) {
+		if !containsKind(core.NodeKinds, core.NodeKind(kind)) {
+			writeError(w, http.StatusBadRequest, "unknown asset kind "+kind)
+			return
+		}
+		q.Kinds = append(q.Kinds, core.NodeKind(kind))
+	}
+	assets, err := s.DB.Assets(r.PathValue("ws"), q)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if assets == nil {
+		assets = []core.AssetRecord{}
+	}
+	writeJSON(w, http.StatusOK, assets)
+}
+
+func (s *Server) workspaceFindings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := core.FindingQuery{Scan: query.Get("scan"), Match: query.Get("q")}
+	for _, raw := range splitList(query.Get("status")) {
+		status, err := core.ParseTriageStatus(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.Statuses = append(q.Statuses, status)
+	}
+	if raw := query.Get("min_severity"); raw != "" {
+		var err error
+		if q.MinSeverity, err = core.ParseSeverity(raw); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	findings, err := s.DB.Findings(r.PathValue("ws"), q)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	counts := map[core.TriageStatus]int{}
+	for _, status := range core.TriageStatuses {
+		counts[status] = 0
+	}
+	for _, f := range findings {
+		counts[f.Triage.Status]++
+	}
+	if findings == nil {
+		findings = []core.FindingRecord{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"counts": counts, "findings": findings})
+}
+
+// triageFinding sets the review status and note of a finding.
+func (s *Server) triageFinding(w http.ResponseWriter, r *http.Request) {
+	var req triageRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid triage request: %v", err))
+		return
+	}
+	status, err := core.ParseTriageStatus(req.Status)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	t, err := s.DB.SetTriage(r.PathValue("ws"), r.PathValue("fid"), status, strings.TrimSpace(req.Note))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func splitList(raw string) []string {
+	var out []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsKind(kinds []core.NodeKind, kind core.NodeKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
+)
+
+// JobStatus is the lifecycle state of a submitted scan.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobFinished  JobStatus = "finished"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Done reports whether the job will not change any more.
+func (s JobStatus) Done() bool {
+	return s == JobFinished || s == JobFailed || s == JobCancelled
+}
+
+// maxJobEvents caps the events kept per job; older ones are dropped.
+const maxJobEvents = 5000
+
+// JobInfo is the public view of a job.
+type JobInfo struct {
+	ID        string      `json:"id"`
+	Status    JobStatus   `json:"status"`
+	Request   ScanRequest `json:"request"`
+	Workspace string      `json:"workspace,omitempty"`
+	Targets   []string    `json:"targets"`
+	Created   time.Time   `json:"created"`
+	Started   *time.Time  `json:"started,omitempty"`
+	Finished  *time.Time  `json:"finished,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Progress  Progress    `json:"progress"`
+}
+
+// Progress counts what a job has done so far.
+type Progress struct {
+	ModulesStarted  int                   `json:"modules_started"`
+	ModulesFinished int                   `json:"modules_finished"`
+	ModulesFailed   int                   `json:"modules_failed"`
+	Assets          int                   `json:"assets"`
+	Findings        map[core.Severity]int `json:"findings"`
+	Events          int                   `json:"events"`
+}
+
+// job is a submitted scan and everything it has produced.
+type job struct {
+	mu       sync.Mutex
+	info     JobInfo
+	prepared *Prepared
+	cancel   context.CancelFunc
+	events   []core.Event
+	dropped  int           // events discarded from the front of events
+	changed  chan struct{} // closed and replaced on every change, for streams
+	scans    []core.TargetScan
+}
+
+func newJob(id string, req ScanRequest, workspace string, p *Prepared) *job {
+	j := &job{prepared: p, changed: make(chan struct{})}
+	j.info = JobInfo{
+		ID:        id,
+		Status:    JobQueued,
+		Request:   req,
+		Workspace: workspace,
+		Created:   time.Now(),
+		Progress:  Progress{Findings: map[core.Severity]int{}},
+	}
+	for _, t := range p.Targets {
+		j.info.Targets = append(j.info.Targets, t.String())
+	}
+	return j
+}
+
+// record is the job bus subscriber: it keeps the event and updates the
+// progress counters.
+func (j *job) record(e core.Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := &j.info.Progress
+	switch e.Type {
+	case core.EventModuleStarted:
+		p.ModulesStarted++
+	case core.EventModuleFinished:
+		p.ModulesFinished++
+		if e.Error != "" {
+			p.ModulesFailed++
+		}
+	case core.EventAssetDiscovered:
+		p.Assets++
+	case core.EventFindingRaised:
+		if e.Finding != nil {
+			p.Findings[e.Finding.Severity]++
+		}
+	}
+	p.Events++
+	j.events = append(j.events, e)
+	if len(j.events) > maxJobEvents {
+		n := len(j.events) - maxJobEvents
+		j.events = append([]core.Event(nil), j.events[n:]...)
+		j.dropped += n
+	}
+	j.notify()
+}
+
+// notify wakes the streams following the job. Callers hold j.mu.
+func (j *job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// Info returns a copy of the job's public view.
+func (j *job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := j.info
+	info.Progress.Findings = make(map[core.Severity]int, len(j.info.Progress.Findings))
+	for sev, n := range j.info.Progress.Findings {
+		info.Progress.Findings[sev] = n
+	}
+	return info
+}
+
+// Events returns the kept events with sequence number since or later, and
+// the sequence number to ask for next.
+func (j *job) Events(since int) ([]core.Event, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.eventsLocked(since)
+}
+
+// Follow is Events for streams: it also reports whether the job has ended
+// and returns a channel that is closed on the next change.
+func (j *job) Follow(since int) (events []core.Event, next int, done bool, changed <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	events, next = j.eventsLocked(since)
+	return events, next, j.info.Status.Done(), j.changed
+}
+
+func (j *job) eventsLocked(since int) ([]core.Event, int) {
+	next := j.dropped + len(j.events)
+	if since < j.dropped {
+		since = j.dropped
+	}
+	if since >= next {
+		return nil, next
+	}
+	return append([]core.Event(nil), j.events[since-j.dropped:]...), next
+}
+
+// start moves a queued job to running, or reports false if it was
+// cancelled while waiting.
+func (j *job) start(cancel context.CancelFunc) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.info.Status != JobQueued {
+		return false
+	}
+	now := time.Now()
+	j.info.Status = JobRunning
+	j.info.Started = &now
+	j.cancel = cancel
+	return true
+}
+
+// finish stores the outcome of a run.
+func (j *job) finish(status JobStatus, scans []core.TargetScan, err string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.info.Status = status
+	j.info.Finished = &now
+	j.info.Error = err
+	j.scans = scans
+	j.cancel = nil
+	j.prepared.Runner = nil // release the engine and clients
+	j.notify()
+}
+
+// Cancel stops the job, and reports false if it had already ended.
+func (j *job) Cancel() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case j.info.Status == JobQueued:
+		now := time.Now()
+		j.info.Status = JobCancelled
+		j.info.Finished = &now
+		j.prepared.Runner = nil // the worker skips it; release it now
+		j.notify()
+	case j.info.Status == JobRunning && j.cancel != nil:
+		j.cancel()
+	default:
+		return false
+	}
+	return true
+}
+
+// Results returns the results and merged asset graph of a finished job.
+func (j *job) Results() ([]core.Result, *core.AssetGraph) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return core.AllResults(j.scans), core.AllAssets(j.scans)
+}
@@ -0,0 +1,692 @@
+// Package server runs scans submitted over an authenticated HTTP JSON API
+// ("triksha serve").
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"github.com/r4j3sh-com/triksha/output"
+)
+
+// APIPrefix is the path every API route lives under.
+const APIPrefix = "/api/v1"
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// Defaults for Server.Workers and Server.QueueSize.
+const (
+	DefaultWorkers   = 2
+	DefaultQueueSize = 50
+)
+
+// keepJobs caps how many finished jobs stay in memory; older ones are
+// still served from the workspace database.
+const keepJobs = 200
+
+// ScanRequest is the body of POST /api/v1/scans. Unset fields keep the
+// server's config.
+type ScanRequest struct {
+	Target     string                            `json:"target,omitempty"`
+	Targets    []string                          `json:"targets,omitempty"`
+	Modules    []string                          `json:"modules,omitempty"`
+	Profile    string                            `json:"profile,omitempty"`
+	Agent      string                            `json:"agent,omitempty"` // "simple" or "llm"
+	Concurrent bool                              `json:"concurrent,omitempty"`
+	Params     map[string]map[string]interface{} `json:"params,omitempty"`
+	Workspace  string                            `json:"workspace,omitempty"`
+}
+
+// Prepared is a validated scan ready for the queue.
+type Prepared struct {
+	Config  core.Config
+	Targets []core.Target
+	Runner  *core.Runner
+}
+
+// Server queues submitted scans, runs them on a fixed number of workers
+// and serves their status and results.
+type Server struct {
+	// Prepare validates req and sets up its scan with events going to bus.
+	// Its errors are reported to the client as bad requests.
+	Prepare func(req ScanRequest, bus *core.Bus) (*Prepared, error)
+	// Tokens are the accepted API tokens.
+	Tokens []string
+	// Workers and QueueSize bound the job queue (DefaultWorkers and
+	// DefaultQueueSize when zero).
+	Workers   int
+	QueueSize int
+	// DB, when set, records every scan in the requested workspace, or in
+	// Workspace by default.
+	DB        *core.DB
+	Workspace string
+	// Catalog describes the modules for GET /modules.
+	Catalog []core.CatalogEntry
+	// Bus receives the events of every job as well as the server's own.
+	Bus *core.Bus
+
+	mu        sync.Mutex
+	jobs      map[string]*job
+	order     []string // job IDs, oldest first
+	queue     []*job   // jobs waiting for a worker, oldest first
+	size      int      // most jobs that may wait
+	preparing int      // slots held by submissions still being prepared
+	wake      chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// Start launches the workers. They stop when ctx is done or on Shutdown.
+func (s *Server) Start(ctx context.Context) {
+	workers, size := s.Workers, s.QueueSize
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	s.jobs = make(map[string]*job)
+	s.size = size
+	s.wake = make(chan struct{}, 1)
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+}
+
+// Shutdown cancels running scans and waits for the workers to record them.
+func (s *Server) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Server) work() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		}
+		if j := s.next(); j != nil {
+			s.run(j)
+		}
+	}
+}
+
+// next pops the oldest job still waiting, or returns nil. It wakes
+// another worker when more jobs wait.
+func (s *Server) next() *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropCancelled()
+	if len(s.queue) == 0 {
+		return nil
+	}
+	j := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	if len(s.queue) > 0 {
+		s.signal()
+	}
+	return j
+}
+
+// dropCancelled forgets jobs cancelled while they waited, so they do not
+// hold queue slots. Callers hold s.mu.
+func (s *Server) dropCancelled() {
+	kept := s.queue[:0]
+	for _, j := range s.queue {
+		if j.Info().Status == JobQueued {
+			kept = append(kept, j)
+		}
+	}
+	clear(s.queue[len(kept):])
+	s.queue = kept
+}
+
+// signal wakes a worker unless one is already due to wake.
+func (s *Server) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run executes one job and records its outcome.
+func (s *Server) run(j *job) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	if !j.start(cancel) {
+		return
+	}
+	info := j.Info()
+	log := s.Bus.Logger("", "server")
+	log.Infof("Starting scan %s of %s", info.ID, strings.Join(info.Targets, ", "))
+
+	p := j.prepared
+	runner := p.Runner
+	if s.DB != nil {
+		rec, err := s.DB.StartScan(info.Workspace, info.ID, p.Targets, p.Config.Modules)
+		if err != nil {
+			log.Errorf("Scan %s will not be recorded: %v", info.ID, err)
+		} else {
+			runner.Recorder = rec
+		}
+	}
+	scans := runner.Run(ctx, p.Targets)
+
+	status, msg := JobFinished, ""
+	switch {
+	case ctx.Err() != nil:
+		status, msg = JobCancelled, "cancelled"
+		if s.ctx.Err() != nil {
+			msg = "server shut down"
+		}
+	case len(core.AllResults(scans)) == 0:
+		var errs []string
+		for _, scan := range scans {
+			if err := scan.Errors[""]; err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", scan.Target, err))
+			}
+		}
+		if len(errs) > 0 {
+			status, msg = JobFailed, strings.Join(errs, "; ")
+		}
+	}
+	recStatus := core.ScanFinished
+	if status == JobCancelled {
+		recStatus = core.ScanInterrupted
+	}
+	if err := runner.Recorder.Finish(recStatus); err != nil {
+		log.Errorf("Failed to record scan %s: %v", info.ID, err)
+	}
+	j.finish(status, scans, msg)
+	log.Infof("Scan %s %s", info.ID, status)
+	s.prune()
+}
+
+// prune forgets the oldest finished jobs beyond keepJobs.
+func (s *Server) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.order) > keepJobs {
+		dropped := false
+		for i, id := range s.order {
+			if s.jobs[id].Info().Status.Done() {
+				delete(s.jobs, id)
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			return
+		}
+	}
+}
+
+// Submit prepares req and queues it. A queue slot is held while it is
+// prepared, so a full queue turns requests away before any work is done.
+func (s *Server) Submit(req ScanRequest) (JobInfo, error) {
+	id, err := core.NewScanID()
+	if err != nil {
+		return JobInfo{}, err
+	}
+	if !s.reserve() {
+		return JobInfo{}, errQueueFull
+	}
+	workspace := req.Workspace
+	if workspace == "" {
+		workspace = s.Workspace
+	}
+	bus := core.NewBus()
+	var j *job
+	var pending []core.Event // events published while preparing
+	bus.Subscribe(func(e core.Event) {
+		if j == nil {
+			pending = append(pending, e)
+		} else {
+			j.record(e)
+		}
+		s.Bus.Publish(e)
+	})
+	p, err := s.Prepare(req, bus)
+	if err != nil {
+		s.mu.Lock()
+		s.preparing--
+		s.mu.Unlock()
+		return JobInfo{}, &requestError{err}
+	}
+	j = newJob(id, req, workspace, p)
+	for _, e := range pending {
+		j.record(e)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preparing--
+	s.queue = append(s.queue, j)
+	s.jobs[id] = j
+	s.order = append(s.order, id)
+	s.signal()
+	return j.Info(), nil
+}
+
+// reserve holds a queue slot for a submission, or reports that the queue
+// is full.
+func (s *Server) reserve() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropCancelled()
+	if len(s.queue)+s.preparing >= s.size {
+		return false
+	}
+	s.preparing++
+	return true
+}
+
+var errQueueFull = errors.New("scan queue is full, try again later")
+
+// requestError marks a problem with the submitted scan.
+type requestError struct{ err error }
+
+func (e *requestError) Error() string { return e.err.Error() }
+
+func (s *Server) job(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+// Handler returns the API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+APIPrefix+"/health", s.health)
+	mux.HandleFunc("GET "+APIPrefix+"/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPI)
+	})
+	mux.Handle("GET "+APIPrefix+"/modules", s.auth(s.modules))
+	mux.Handle("GET "+APIPrefix+"/scans", s.auth(s.listScans))
+	mux.Handle("POST "+APIPrefix+"/scans", s.auth(s.submitScan))
+	mux.Handle("GET "+APIPrefix+"/scans/{id}", s.auth(s.getScan))
+	mux.Handle("POST "+APIPrefix+"/scans/{id}/cancel", s.auth(s.cancelScan))
+	mux.Handle("GET "+APIPrefix+"/scans/{id}/events", s.auth(s.scanEvents))
+	mux.Handle("GET "+APIPrefix+"/scans/{id}/stream", s.streamAuth(s.streamSSE))
+	mux.Handle("GET "+APIPrefix+"/scans/{id}/ws", s.streamAuth(s.streamWS))
+	mux.Handle("GET "+APIPrefix+"/scans/{id}/results", s.auth(s.scanResults))
+	mux.Handle("GET "+APIPrefix+"/scans/{id}/findings", s.auth(s.scanFindings))
+	mux.Handle("GET "+APIPrefix+"/scans/{id}/report", s.auth(s.scanReport))
+	mux.Handle("GET "+APIPrefix+"/workspaces", s.auth(s.withDB(s.listWorkspaces)))
+	mux.Handle("GET "+APIPrefix+"/workspaces/{ws}/scans", s.auth(s.withDB(s.workspaceScans)))
+	mux.Handle("GET "+APIPrefix+"/workspaces/{ws}/scans/{id}/timeline", s.auth(s.withDB(s.scanTimeline)))
+	mux.Handle("GET "+APIPrefix+"/workspaces/{ws}/assets", s.auth(s.withDB(s.workspaceAssets)))
+	mux.Handle("GET "+APIPrefix+"/workspaces/{ws}/findings", s.auth(s.withDB(s.workspaceFindings)))
+	mux.Handle("PUT "+APIPrefix+"/workspaces/{ws}/findings/{fid}/triage", s.auth(s.withDB(s.triageFinding)))
+	mux.Handle("GET "+DashboardPath, dashboard())
+	mux.Handle("GET /{$}", http.RedirectHandler(DashboardPath, http.StatusFound))
+	return mux
+}
+
+// auth accepts "Authorization: Bearer <token>" or an "X-API-Token" header.
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return s.checkToken(next, false)
+}
+
+// streamAuth is auth that also accepts a "token" query parameter, since
+// browser EventSource and WebSocket clients cannot set headers.
+func (s *Server) streamAuth(next http.HandlerFunc) http.Handler {
+	return s.checkToken(next, true)
+}
+
+func (s *Server) checkToken(next http.HandlerFunc, query bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.Header.Get("X-API-Token")
+		}
+		if token == "" && query {
+			token = r.URL.Query().Get("token")
+		}
+		if !s.validToken(strings.TrimSpace(token)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="triksha"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) validToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	valid := false
+	for _, t := range s.Tokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	counts := map[JobStatus]int{}
+	for _, j := range s.jobs {
+		counts[j.Info().Status]++
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "ok",
+		"queued":  counts[JobQueued],
+		"running": counts[JobRunning],
+	})
+}
+
+func (s *Server) modules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Catalog)
+}
+
+func (s *Server) listScans(w http.ResponseWriter, r *http.Request) {
+	status := JobStatus(r.URL.Query().Get("status"))
+	s.mu.Lock()
+	list := make([]JobInfo, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		info := s.jobs[s.order[i]].Info()
+		if status == "" || info.Status == status {
+			list = append(list, info)
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) submitScan(w http.ResponseWriter, r *http.Request) {
+	var req ScanRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid scan request: %v", err))
+		return
+	}
+	info, err := s.Submit(req)
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, errQueueFull):
+		w.Header().Set("Retry-After", "30")
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Location", APIPrefix+"/scans/"+info.ID)
+	writeJSON(w, http.StatusAccepted, info)
+}
+
+func (s *Server) getScan(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if j, ok := s.job(id); ok {
+		writeJSON(w, http.StatusOK, j.Info())
+		return
+	}
+	// A scan from before a restart, or pruned from memory
+	if rec, err := s.record(r, id); err == nil {
+		writeJSON(w, http.StatusOK, recordInfo(rec, s.workspace(r)))
+		return
+	}
+	writeError(w, http.StatusNotFound, "unknown scan "+id)
+}
+
+func (s *Server) cancelScan(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	j, ok := s.job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown scan "+id)
+		return
+	}
+	if !j.Cancel() {
+		writeError(w, http.StatusConflict, "scan "+id+" has already ended")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, j.Info())
+}
+
+// scanEvents returns the events kept for a scan from sequence number
+// "since" on, for polling clients.
+func (s *Server) scanEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	j, ok := s.job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown scan "+id)
+		return
+	}
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	min := core.LevelInfo
+	if raw := r.URL.Query().Get("level"); raw != "" {
+		var err error
+		if min, err = core.ParseLevel(raw); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	events, next := j.Events(since)
+	kept := make([]core.Event, 0, len(events))
+	for _, e := range events {
+		if e.Level >= min {
+			kept = append(kept, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": j.Info().Status,
+		"next":   next,
+		"events": kept,
+	})
+}
+
+func (s *Server) scanResults(w http.ResponseWriter, r *http.Request) {
+	results, assets, ok := s.results(w, r)
+	if !ok {
+		return
+	}
+	if results == nil {
+		results = []core.Result{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": results, "assets": assets})
+}
+
+func (s *Server) scanFindings(w http.ResponseWriter, r *http.Request) {
+	min := core.SeverityInfo
+	if raw := r.URL.Query().Get("min_severity"); raw != "" {
+		var err error
+		if min, err = core.ParseSeverity(raw); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	results, _, ok := s.results(w, r)
+	if !ok {
+		return
+	}
+	findings := []core.Finding{}
+	for _, f := range core.CollectFindings(results) {
+		if f.Severity.Rank() >= min.Rank() {
+			findings = append(findings, f)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"counts":   core.CountBySeverity(findings),
+		"findings": findings,
+	})
+}
+
+// scanReport renders the report as json (default), md or html.
+func (s *Server) scanReport(w http.ResponseWriter, r *http.Request) {
+	results, assets, ok := s.results(w, r)
+	if !ok {
+		return
+	}
+	cfg := core.Config{}
+	if j, ok := s.job(r.PathValue("id")); ok {
+		cfg.Targets = j.Info().Targets
+	}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		data, err := output.JSONReport(results, assets)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	case "md", "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(output.MarkdownReport(cfg, results, assets)))
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(output.HTMLReport(cfg, results, assets)))
+	default:
+		writeError(w, http.StatusBadRequest, "unknown report format "+format+" (want json, md or html)")
+	}
+}
+
+// results finds a scan's results: from memory once a job is done, or from
+// the workspace database, which also has the partial results of a running
+// scan. It writes the error response itself.
+func (s *Server) results(w http.ResponseWriter, r *http.Request) ([]core.Result, *core.AssetGraph, bool) {
+	id := r.PathValue("id")
+	j, known := s.job(id)
+	if known && j.Info().Status.Done() {
+		results, assets := j.Results()
+		return results, assets, true
+	}
+	if s.DB != nil {
+		ws := s.workspace(r)
+		if known {
+			ws = j.Info().Workspace
+		}
+		if targets, err := s.DB.Targets(ws, id); err == nil {
+			var results []core.Result
+			assets := core.NewAssetGraph()
+			for _, name := range sortedKeys(targets) {
+				results = append(results, targets[name].Results...)
+				assets.Merge(targets[name].Assets)
+			}
+			return results, assets, true
+		} else if !errors.Is(err, core.ErrNotFound) {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return nil, nil, false
+		}
+	}
+	if known {
+		writeError(w, http.StatusConflict, "scan "+id+" has not finished yet")
+	} else {
+		writeError(w, http.StatusNotFound, "unknown scan "+id)
+	}
+	return nil, nil, false
+}
+
+// workspace is the workspace named by the request's "workspace" query
+// parameter, or the server's.
+func (s *Server) workspace(r *http.Request) string {
+	if ws := r.URL.Query().Get("workspace"); ws != "" {
+		return ws
+	}
+	return s.Workspace
+}
+
+func (s *Server) record(r *http.Request, id string) (core.ScanRecord, error) {
+	if s.DB == nil {
+		return core.ScanRecord{}, core.ErrNotFound
+	}
+	return s.DB.Scan(s.workspace(r), id)
+}
+
+// recordInfo describes a scan known only from the workspace database.
+func recordInfo(rec core.ScanRecord, workspace string) JobInfo {
+	info := JobInfo{
+		ID:        rec.ID,
+		Status:    JobFinished,
+		Workspace: workspace,
+		Targets:   rec.Targets,
+		Created:   rec.Started,
+		Started:   &rec.Started,
+		Progress:  Progress{Findings: rec.FindingCounts},
+	}
+	info.Request.Modules = rec.Modules
+	switch rec.Status {
+	case core.ScanInterrupted:
+		info.Status = JobCancelled
+	case core.ScanRunning:
+		// Left running by a server that stopped
+		info.Status = JobFailed
+		info.Error = "scan did not finish"
+	}
+	if !rec.Finished.IsZero() {
+		info.Finished = &rec.Finished
+	}
+	for _, n := range rec.Assets {
+		info.Progress.Assets += n
+	}
+	return info
+}
+
+func sortedKeys(m map[string]core.TargetRecord) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// ListenAndServe serves the API on addr until ctx is done, then shuts the
+// HTTP server and the job queue down.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		s.Shutdown()
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.Shutdown()
+	return err
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/r4j3sh-com/triksha/core"
+	"golang.org/x/net/websocket"
+)
+
+// StreamEnd is the type of the last message on a scan stream, sent once
+// the scan has ended.
+const StreamEnd = "end"
+
+// keepAlive is how often an idle stream sends something to keep proxies
+// from closing it.
+const keepAlive = 15 * time.Second
+
+// StreamEvent is one message on a scan stream: an event with its sequence
+// number, or the closing StreamEnd message with the scan's final status.
+type StreamEvent struct {
+	Seq int `json:"seq"`
+	core.Event
+	Status JobStatus `json:"status,omitempty"`
+}
+
+// streamFilter selects the events a stream sends. Scan events are always
+// sent; log events only from level up. Types, when set, limits both.
+type streamFilter struct {
+	level core.Level
+	types map[core.EventType]bool
+}
+
+func parseStreamFilter(r *http.Request) (streamFilter, error) {
+	f := streamFilter{level: core.LevelInfo}
+	q := r.URL.Query()
+	if raw := q.Get("level"); raw != "" {
+		var err error
+		if f.level, err = core.ParseLevel(raw); err != nil {
+			return f, err
+		}
+	}
+	if raw := q.Get("types"); raw != "" {
+		f.types = map[core.EventType]bool{}
+		for _, t := range strings.Split(raw, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				f.types[core.EventType(t)] = true
+			}
+		}
+	}
+	return f, nil
+}
+
+func (f streamFilter) match(e core.Event) bool {
+	if f.types != nil && !f.types[e.Type] {
+		return false
+	}
+	return e.Type != core.EventLog || e.Level >= f.level
+}
+
+// streamStart is where a stream resumes: the Last-Event-ID header sent by
+// reconnecting EventSource clients, or the "since" query parameter.
+func streamStart(r *http.Request) int {
+	if id, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		return id + 1
+	}
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	return since
+}
+
+// follow sends the job's events from since on until the job ends, ctx is
+// done or send fails. tick is called when nothing happened for keepAlive.
+func (s *Server) follow(ctx context.Context, j *job, since int, f streamFilter, send func(StreamEvent) error, tick func() error) error {
+	timer := time.NewTimer(keepAlive)
+	defer timer.Stop()
+	for {
+		events, next, done, changed := j.Follow(since)
+		for i, e := range events {
+			if !f.match(e) {
+				continue
+			}
+			if err := send(StreamEvent{Seq: next - len(events) + i, Event: e}); err != nil {
+				return err
+			}
+		}
+		since = next
+		if done {
+			info := j.Info()
+			return send(StreamEvent{
+				Seq:    next,
+				Event:  core.Event{Time: time.Now(), Type: StreamEnd, Level: core.LevelInfo, Error: info.Error},
+				Status: info.Status,
+			})
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(keepAlive)
+		select {
+		case <-changed:
+		case <-timer.C:
+			if err := tick(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+	}
+}
+
+// streamJob resolves the scan of a stream request and its filter, writing
+// the error response itself.
+func (s *Server) streamJob(w http.ResponseWriter, r *http.Request) (*job, streamFilter, bool) {
+	id := r.PathValue("id")
+	f, err := parseStreamFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, f, false
+	}
+	j, ok := s.job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown scan "+id+" (only scans in memory can be streamed)")
+		return nil, f, false
+	}
+	return j, f, true
+}
+
+// streamSSE sends a scan's events as Server-Sent Events, named by event
+// type with the sequence number as ID.
+func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request) {
+	j, f, ok := s.streamJob(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported by this connection")
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", 3000)
+	flusher.Flush()
+
+	send := func(e StreamEvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	tick := func() error {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	s.follow(r.Context(), j, streamStart(r), f, send, tick)
+}
+
+// streamWS sends a scan's events over a WebSocket as JSON text messages.
+// Messages from the client are ignored; closing the socket ends the stream.
+func (s *Server) streamWS(w http.ResponseWriter, r *http.Request) {
+	j, f, ok := s.streamJob(w, r)
+	if !ok {
+		return
+	}
+	since := streamStart(r)
+	ws := websocket.Server{
+		// Browsers on any origin may connect; the API token is the guard.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			go func() {
+				var discard string
+				for websocket.Message.Receive(conn, &discard) == nil {
+				}
+				cancel()
+			}()
+			send := func(e StreamEvent) error { return websocket.JSON.Send(conn, e) }
+			tick := func() error {
+				ping, err := conn.NewFrameWriter(websocket.PingFrame)
+				if err != nil {
+					return err
+				}
+				if _, err := ping.Write(nil); err != nil {
+					return err
+				}
+				return ping.Close()
+			}
+			s.follow(ctx, j, since, f, send, tick)
+		},
+	}
+	ws.ServeHTTP(w, r)
+}
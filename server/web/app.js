@@ -0,0 +1,449 @@
+// Triksha dashboard: a small single-page client of the /api/v1 routes.
+// Views are addressed by the URL hash, e.g. #/ws/default/findings?scan=ID.
+"use strict";
+
+const API = "/api/v1";
+const app = document.getElementById("app");
+const state = {
+    token: localStorage.getItem("triksha-token") || "",
+    workspace: null,
+    stream: null, // EventSource of the scan being watched
+    timer: null,  // live indicator poll
+};
+
+const SEVERITIES = ["critical", "high", "medium", "low", "info"];
+const STATUSES = [["new", "New"], ["confirmed", "Confirmed"], ["false_positive", "False positive"], ["fixed", "Fixed"]];
+const KINDS = ["domain", "subdomain", "netblock", "ip", "service", "url", "technology", "certificate", "finding"];
+const MAX_ROWS = 1000;
+
+class Unauthorized extends Error {}
+
+// --- helpers ---
+
+function esc(v) {
+    return String(v ?? "").replace(/[&<>"']/g, c => ({"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;"}[c]));
+}
+
+function enc(v) { return encodeURIComponent(v); }
+
+function fmtTime(t) {
+    if (!t || t.startsWith("0001")) return "-";
+    return new Date(t).toLocaleString();
+}
+
+function badge(cls, text) { return `<span class="badge ${esc(cls)}">${esc(text)}</span>`; }
+
+function severityBadges(counts) {
+    const parts = SEVERITIES.filter(s => counts && counts[s] > 0).map(s => badge("sev-" + s, `${counts[s]} ${s}`));
+    return parts.length ? parts.join(" ") : `<span class="muted">none</span>`;
+}
+
+function debounce(fn, ms) {
+    let t;
+    return (...args) => { clearTimeout(t); t = setTimeout(() => fn(...args), ms); };
+}
+
+async function api(path, opts = {}) {
+    const headers = {"Authorization": "Bearer " + state.token};
+    if (opts.body) headers["Content-Type"] = "application/json";
+    const res = await fetch(API + path, {...opts, headers});
+    if (res.status === 401) throw new Unauthorized();
+    if (!res.ok) {
+        let msg = res.statusText;
+        try { msg = (await res.json()).error || msg; } catch (e) { /* not JSON */ }
+        throw new Error(msg);
+    }
+    return res;
+}
+
+async function getJSON(path) { return (await api(path)).json(); }
+
+function fail(err) {
+    if (err instanceof Unauthorized) {
+        showLogin(state.token ? "That token was not accepted." : "");
+        return;
+    }
+    app.innerHTML = `<p class="error">${esc(err.message)}</p>`;
+}
+
+function closeStream() {
+    if (state.stream) {
+        state.stream.close();
+        state.stream = null;
+    }
+}
+
+// --- routing ---
+
+function parseHash() {
+    const [path, query] = location.hash.replace(/^#\/?/, "").split("?");
+    return {parts: path.split("/").filter(Boolean).map(decodeURIComponent), params: new URLSearchParams(query || "")};
+}
+
+function go(ws, view, params) {
+    const query = params && params.toString();
+    location.hash = `#/ws/${enc(ws)}/${view}` + (query ? "?" + query : "");
+}
+
+async function route() {
+    closeStream();
+    const {parts, params} = parseHash();
+    if (parts[0] !== "ws" || !parts[1]) {
+        go(state.workspace, "scans");
+        return;
+    }
+    const ws = parts[1];
+    const view = parts[2] || "scans";
+    state.workspace = ws;
+    document.getElementById("workspace").value = ws;
+    document.querySelectorAll("header nav a").forEach(a => a.classList.toggle("active", a.dataset.view === view));
+    app.innerHTML = `<p class="muted">Loading...</p>`;
+    try {
+        if (view === "scans" && parts[3]) await scanView(ws, parts[3]);
+        else if (view === "assets") await assetsView(ws, params);
+        else if (view === "findings") await findingsView(ws, params);
+        else await scansView(ws);
+    } catch (err) {
+        fail(err);
+    }
+}
+
+// --- sign-in ---
+
+function showLogin(message) {
+    closeStream();
+    clearInterval(state.timer);
+    document.getElementById("logout").hidden = true;
+    app.replaceChildren(document.getElementById("login").content.cloneNode(true));
+    const form = app.querySelector("form");
+    form.querySelector(".error").textContent = message;
+    form.addEventListener("submit", ev => {
+        ev.preventDefault();
+        state.token = form.token.value.trim();
+        localStorage.setItem("triksha-token", state.token);
+        start();
+    });
+}
+
+async function start() {
+    if (!state.token) {
+        showLogin("");
+        return;
+    }
+    try {
+        const list = await getJSON("/workspaces");
+        const select = document.getElementById("workspace");
+        select.innerHTML = list.map(w => `<option value="${esc(w.name)}">${esc(w.name)} (${w.scans} scans)</option>`).join("");
+        if (!state.workspace || !list.some(w => w.name === state.workspace)) {
+            state.workspace = (list.find(w => w.default) || list[0]).name;
+        }
+        document.getElementById("logout").hidden = false;
+        pollLive();
+        clearInterval(state.timer);
+        state.timer = setInterval(pollLive, 5000);
+        route();
+    } catch (err) {
+        fail(err);
+    }
+}
+
+async function pollLive() {
+    try {
+        const health = await (await fetch(API + "/health")).json();
+        const busy = health.running + health.queued;
+        document.getElementById("live").textContent = busy ? `${health.running} running, ${health.queued} queued` : "idle";
+    } catch (e) {
+        document.getElementById("live").textContent = "server unreachable";
+    }
+}
+
+// --- scans ---
+
+async function scansView(ws) {
+    const scans = await getJSON(`/workspaces/${enc(ws)}/scans`);
+    if (!scans.length) {
+        app.innerHTML = `<h1>Scans</h1><div class="empty">No scans in workspace ${esc(ws)} yet. Submit one with POST ${API}/scans.</div>`;
+        return;
+    }
+    const rows = scans.map(s => {
+        const assets = Object.entries(s.assets || {}).filter(([k]) => k !== "finding").map(([k, n]) => `${n} ${k}`).join(", ");
+        return `<tr class="clickable" data-id="${esc(s.id)}">
+            <td class="mono">${esc(s.id)}</td>
+            <td>${badge("st-" + s.status, s.status)}</td>
+            <td>${esc(fmtTime(s.started))}</td>
+            <td>${esc(fmtTime(s.finished))}</td>
+            <td>${esc((s.targets || []).join(", "))}</td>
+            <td>${s.results}</td>
+            <td>${esc(assets || "-")}</td>
+            <td>${severityBadges(s.finding_counts)}</td>
+        </tr>`;
+    }).join("");
+    app.innerHTML = `<h1>Scans</h1>
+        <table>
+            <thead><tr><th>ID</th><th>Status</th><th>Started</th><th>Finished</th><th>Targets</th><th>Results</th><th>Assets</th><th>Findings</th></tr></thead>
+            <tbody>${rows}</tbody>
+        </table>`;
+    app.querySelectorAll("tr[data-id]").forEach(tr => tr.addEventListener("click", () => go(ws, "scans/" + enc(tr.dataset.id))));
+}
+
+async function scanView(ws, id) {
+    const info = await getJSON(`/scans/${enc(id)}?workspace=${enc(ws)}`);
+    const live = info.status === "queued" || info.status === "running";
+    let timeline = [];
+    if (!live) {
+        try {
+            timeline = await getJSON(`/workspaces/${enc(ws)}/scans/${enc(id)}/timeline`);
+        } catch (err) {
+            if (err instanceof Unauthorized) throw err;
+        }
+    }
+    const scanParams = new URLSearchParams({scan: id});
+    app.innerHTML = `<h1>Scan <span class="mono">${esc(id)}</span></h1>
+        <p>${esc((info.targets || []).join(", "))} <span class="muted">&middot; started ${esc(fmtTime(info.started || info.created))}</span></p>
+        <div class="cards" id="cards"></div>
+        <div class="filters">
+            <a href="#/ws/${enc(ws)}/findings?${scanParams}">Findings of this scan</a>
+            <a href="#/ws/${enc(ws)}/assets?${scanParams}">Assets of this scan</a>
+            <button class="secondary" data-report="html">HTML report</button>
+            <button class="secondary" data-report="md">Markdown report</button>
+            <button class="secondary" data-report="json">JSON report</button>
+            ${live ? `<button id="cancel">Cancel scan</button>` : ""}
+        </div>
+        <h2>Agent decisions</h2>
+        <ol class="timeline" id="timeline"></ol>`;
+    renderCards(info);
+    const list = document.getElementById("timeline");
+    timeline.forEach(d => list.append(decisionItem(d, false)));
+    if (!timeline.length && !live) {
+        list.outerHTML = `<div class="empty">No agent decisions were recorded (modules were listed explicitly or run as a graph).</div>`;
+    }
+
+    app.querySelectorAll("[data-report]").forEach(b => b.addEventListener("click", () => openReport(ws, id, b.dataset.report).catch(fail)));
+    const cancel = document.getElementById("cancel");
+    if (cancel) {
+        cancel.addEventListener("click", async () => {
+            if (!confirm(`Cancel scan ${id}?`)) return;
+            try { await api(`/scans/${enc(id)}/cancel`, {method: "POST"}); } catch (err) { fail(err); }
+        });
+    }
+    if (live) follow(ws, id, list);
+}
+
+function renderCards(info) {
+    const p = info.progress || {};
+    const card = (n, l) => `<div class="card"><div class="n">${n}</div><div class="l">${esc(l)}</div></div>`;
+    document.getElementById("cards").innerHTML =
+        `<div class="card"><div class="n">${badge("st-" + info.status, info.status)}</div><div class="l">${esc(info.error || "status")}</div></div>` +
+        card(p.modules_finished ?? "-", `modules finished` + (p.modules_failed ? ` (${p.modules_failed} failed)` : "")) +
+        card(p.assets ?? "-", "assets") +
+        `<div class="card"><div>${severityBadges(p.findings)}</div><div class="l">findings</div></div>`;
+}
+
+function decisionItem(d, live) {
+    const li = document.createElement("li");
+    if (live) li.className = "live";
+    const params = d.params && Object.keys(d.params).length ? `<pre>${esc(JSON.stringify(d.params, null, 2))}</pre>` : "";
+    li.innerHTML = `<div class="when">${esc(fmtTime(d.time))} &middot; ${esc(d.target)}</div>
+        <div><strong>${esc(d.module)}</strong> &mdash; ${esc(d.reason || "no reason given")}</div>${params}`;
+    return li;
+}
+
+// follow streams a running scan's decisions into list and keeps the
+// progress cards current until the scan ends.
+function follow(ws, id, list) {
+    const types = "agent_decision,module_finished,asset_discovered,finding_raised,scan_finished";
+    const source = new EventSource(`${API}/scans/${enc(id)}/stream?types=${types}&token=${enc(state.token)}`);
+    state.stream = source;
+    const refresh = debounce(async () => {
+        try { renderCards(await getJSON(`/scans/${enc(id)}?workspace=${enc(ws)}`)); } catch (e) { /* next event retries */ }
+    }, 1000);
+    source.addEventListener("agent_decision", ev => {
+        const e = JSON.parse(ev.data);
+        const f = e.fields || {};
+        list.append(decisionItem({time: e.time, target: e.target, module: f.action, reason: f.reason, params: f.params}, true));
+    });
+    ["module_finished", "asset_discovered", "finding_raised", "scan_finished"].forEach(t => source.addEventListener(t, refresh));
+    source.addEventListener("end", () => {
+        closeStream();
+        route(); // reload from the workspace database
+    });
+}
+
+async function openReport(ws, id, format) {
+    const res = await api(`/scans/${enc(id)}/report?format=${format}&workspace=${enc(ws)}`);
+    const type = format === "html" ? "text/html" : format === "json" ? "application/json" : "text/plain";
+    const blob = new Blob([await res.blob()], {type});
+    window.open(URL.createObjectURL(blob), "_blank");
+}
+
+// --- assets ---
+
+async function assetsView(ws, params) {
+    app.innerHTML = `<h1>Assets</h1>
+        <form class="filters">
+            <select name="kind"><option value="">All kinds</option>${KINDS.filter(k => k !== "finding").map(k => `<option>${k}</option>`).join("")}</select>
+            <input type="search" name="q" placeholder="Filter by value">
+            <input type="search" name="scan" placeholder="Scan ID">
+            <span class="muted" id="count"></span>
+        </form>
+        <div id="table"></div>`;
+    const filters = app.querySelector("form.filters");
+    filters.addEventListener("submit", ev => ev.preventDefault());
+    filters.kind.value = params.get("kind") || "";
+    filters.q.value = params.get("q") || "";
+    filters.scan.value = params.get("scan") || "";
+
+    const load = async () => {
+        const q = new URLSearchParams();
+        for (const name of ["kind", "q", "scan"]) {
+            if (filters[name].value) q.set(name, filters[name].value.trim());
+        }
+        history.replaceState(null, "", `#/ws/${enc(ws)}/assets` + (q.toString() ? "?" + q : ""));
+        if (!q.has("kind")) q.set("kind", KINDS.filter(k => k !== "finding").join(","));
+        try {
+            renderAssets(await getJSON(`/workspaces/${enc(ws)}/assets?${q}`));
+        } catch (err) {
+            fail(err);
+        }
+    };
+    filters.kind.addEventListener("change", load);
+    filters.q.addEventListener("input", debounce(load, 250));
+    filters.scan.addEventListener("input", debounce(load, 250));
+    await load();
+}
+
+function renderAssets(assets) {
+    document.getElementById("count").textContent = `${assets.length} assets` + (assets.length > MAX_ROWS ? `, showing the first ${MAX_ROWS}` : "");
+    if (!assets.length) {
+        document.getElementById("table").innerHTML = `<div class="empty">No assets match.</div>`;
+        return;
+    }
+    const rows = assets.slice(0, MAX_ROWS).map(a => {
+        const attrs = Object.keys(a.attrs || {}).sort().map(k => `${k}=${a.attrs[k]}`).join(" ");
+        return `<tr>
+            <td>${esc(a.kind)}</td>
+            <td class="mono">${esc(a.value)}</td>
+            <td class="mono">${esc(attrs)}</td>
+            <td title="${esc(a.scans.join("\n"))}">${a.scans.length}</td>
+            <td>${esc(fmtTime(a.first_seen))}</td>
+            <td>${esc(fmtTime(a.last_seen))}</td>
+        </tr>`;
+    }).join("");
+    document.getElementById("table").innerHTML = `<table>
+        <thead><tr><th>Kind</th><th>Value</th><th>Details</th><th>Scans</th><th>First seen</th><th>Last seen</th></tr></thead>
+        <tbody>${rows}</tbody>
+    </table>`;
+}
+
+// --- findings ---
+
+async function findingsView(ws, params) {
+    app.innerHTML = `<h1>Findings</h1>
+        <form class="filters">
+            <span id="statuses"></span>
+            <select name="min_severity"><option value="">Any severity</option>${SEVERITIES.map(s => `<option value="${s}">${s} and above</option>`).join("")}</select>
+            <input type="search" name="q" placeholder="Filter by title, asset or module">
+            <input type="search" name="scan" placeholder="Scan ID">
+        </form>
+        <div id="table"></div>`;
+    const filters = app.querySelector("form.filters");
+    filters.addEventListener("submit", ev => ev.preventDefault());
+    filters.min_severity.value = params.get("min_severity") || "";
+    filters.q.value = params.get("q") || "";
+    filters.scan.value = params.get("scan") || "";
+    // Statuses are filtered here so the chips can count every status
+    const shown = new Set((params.get("status") || "new,confirmed").split(",").filter(Boolean));
+    let findings = [];
+
+    const query = () => {
+        const q = new URLSearchParams();
+        for (const name of ["min_severity", "q", "scan"]) {
+            if (filters[name].value) q.set(name, filters[name].value.trim());
+        }
+        return q;
+    };
+    const render = () => {
+        const counts = Object.fromEntries(STATUSES.map(([s]) => [s, 0]));
+        findings.forEach(f => counts[f.triage.status]++);
+        const chips = document.getElementById("statuses");
+        chips.innerHTML = STATUSES.map(([s, label]) => `<span class="chip ${shown.has(s) ? "on" : ""}" data-status="${s}">${label} ${counts[s]}</span>`).join(" ");
+        chips.querySelectorAll(".chip").forEach(c => c.addEventListener("click", () => {
+            shown.has(c.dataset.status) ? shown.delete(c.dataset.status) : shown.add(c.dataset.status);
+            render();
+        }));
+        const q = query();
+        q.set("status", [...shown].join(","));
+        history.replaceState(null, "", `#/ws/${enc(ws)}/findings?${q}`);
+        renderFindings(ws, findings.filter(f => shown.has(f.triage.status)), render);
+    };
+    const load = async () => {
+        try {
+            findings = (await getJSON(`/workspaces/${enc(ws)}/findings?${query()}`)).findings;
+            render();
+        } catch (err) {
+            fail(err);
+        }
+    };
+    filters.min_severity.addEventListener("change", load);
+    filters.q.addEventListener("input", debounce(load, 250));
+    filters.scan.addEventListener("input", debounce(load, 250));
+    await load();
+}
+
+function renderFindings(ws, findings, rerender) {
+    const table = document.getElementById("table");
+    if (!findings.length) {
+        table.innerHTML = `<div class="empty">No findings match.</div>`;
+        return;
+    }
+    const rows = findings.slice(0, MAX_ROWS).map((f, i) => {
+        const refs = (f.references || []).map(r => /^https?:\/\//.test(r) ? `<a href="${esc(r)}" target="_blank" rel="noopener">${esc(r)}</a>` : esc(r)).join(", ");
+        const asset = f.asset.url || (f.asset.port ? `${f.asset.host}:${f.asset.port}` : f.asset.host);
+        return `<tr data-i="${i}">
+            <td>${badge("sev-" + f.severity, f.severity)}</td>
+            <td><details><summary>${esc(f.title)}</summary>
+                <p class="muted mono">${esc(f.id)} &middot; ${esc(f.module)} &middot; ${esc(f.confidence)}</p>
+                ${f.evidence ? `<pre>${esc(f.evidence)}</pre>` : ""}
+                ${f.remediation ? `<p><strong>Remediation:</strong> ${esc(f.remediation)}</p>` : ""}
+                ${refs ? `<p><strong>References:</strong> ${refs}</p>` : ""}
+            </details></td>
+            <td class="mono">${esc(asset)}</td>
+            <td title="${esc(f.scans.join("\n"))}">${f.scans.length}</td>
+            <td>${esc(fmtTime(f.last_seen))}</td>
+            <td><select name="status">${STATUSES.map(([s, label]) => `<option value="${s}" ${s === f.triage.status ? "selected" : ""}>${label}</option>`).join("")}</select></td>
+            <td><input class="note" name="note" value="${esc(f.triage.note)}" placeholder="Note"></td>
+        </tr>`;
+    }).join("");
+    table.innerHTML = `<table>
+        <thead><tr><th>Severity</th><th>Finding</th><th>Asset</th><th>Scans</th><th>Last seen</th><th>Status</th><th>Note</th></tr></thead>
+        <tbody>${rows}</tbody>
+    </table>`;
+    table.querySelectorAll("tr[data-i]").forEach(tr => {
+        const f = findings[tr.dataset.i];
+        const save = async () => {
+            const body = JSON.stringify({status: tr.querySelector("[name=status]").value, note: tr.querySelector("[name=note]").value});
+            try {
+                f.triage = await (await api(`/workspaces/${enc(ws)}/findings/${enc(f.id)}/triage`, {method: "PUT", body})).json();
+                rerender();
+            } catch (err) {
+                fail(err);
+            }
+        };
+        tr.querySelector("[name=status]").addEventListener("change", save);
+        tr.querySelector("[name=note]").addEventListener("change", save);
+    });
+}
+
+// --- wiring ---
+
+document.getElementById("workspace").addEventListener("change", ev => {
+    go(ev.target.value, parseHash().parts[2] || "scans");
+});
+document.querySelectorAll("header nav a").forEach(a => a.addEventListener("click", () => go(state.workspace, a.dataset.view)));
+document.getElementById("logout").addEventListener("click", () => {
+    localStorage.removeItem("triksha-token");
+    state.token = "";
+    showLogin("");
+});
+window.addEventListener("hashchange", () => { if (state.token) route(); });
+start();